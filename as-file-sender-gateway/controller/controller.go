@@ -35,6 +35,12 @@ import (
 	"aicsd/pkg/types"
 )
 
+const (
+	// maxUpdateAttempts is the number of times the archived files of a job are written to the job repository
+	// before giving up on the concurrent updates of the job
+	maxUpdateAttempts = 5
+)
+
 type Controller struct {
 	lc                 logger.LoggingClient
 	fileHostname       string
@@ -97,7 +103,8 @@ func (c *Controller) RegisterRoutes(service interfaces.ApplicationService) error
 
 // RejectFile is called when the user rejects a job that is not rejected (POST)
 // or when the user unrejects a job that is rejected (DELETE).
-// The function copies an image from the archive folder to the rejected folder.
+// The function copies an image from the archive folder to the rejected folder of the pipeline,
+// or to the rejected folder of each pipeline run for a fanned out job.
 func (c *Controller) RejectFile(writer http.ResponseWriter, request *http.Request) {
 	jobId, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
 	if err != nil {
@@ -111,50 +118,79 @@ func (c *Controller) RejectFile(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
-	taskId := job.PipelineDetails.TaskId
-	task, err := c.taskLauncherClient.RetrieveById(taskId)
-	if err != nil {
-		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for TaskId: %s, Error: %s", taskPkg.ErrTaskInvalid, taskId, err), http.StatusInternalServerError)
+	taskIds := rejectTaskIds(job)
+	if len(taskIds) == 0 {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for JobId: %s, Error: no task ran for the job", taskPkg.ErrTaskInvalid, jobId), http.StatusInternalServerError)
 		return
 	}
 
-	modelName := task.PipelineId
-	if strings.Contains(modelName, "/") {
-		split := strings.Split(modelName, "/")
-		modelName = split[len(split)-1]
-	}
-
-	src := job.InputFile.ArchiveName
-	dstDir := path.Join(c.rejectFolder, modelName)
-	dst := path.Join(dstDir, job.InputFile.Name)
+	dstDirs := make(map[string]bool)
+	for _, taskId := range taskIds {
+		task, err := c.taskLauncherClient.RetrieveById(taskId)
+		if err != nil {
+			helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for TaskId: %s, Error: %s", taskPkg.ErrTaskInvalid, taskId, err), http.StatusInternalServerError)
+			return
+		}
 
-	dirExists := true
-	if _, err := os.Stat(dstDir); os.IsNotExist(err) {
-		dirExists = false
-	}
+		modelName := task.PipelineId
+		if strings.Contains(modelName, "/") {
+			split := strings.Split(modelName, "/")
+			modelName = split[len(split)-1]
+		}
 
-	if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) && request.Method == http.MethodPost {
-		if !dirExists {
-			os.Mkdir(dstDir, os.FileMode(0777))
-			os.Chmod(dstDir, os.FileMode(0777))
+		dstDir := path.Join(c.rejectFolder, modelName)
+		// the pipeline runs of the same pipeline share its reject folder
+		if dstDirs[dstDir] {
+			continue
 		}
-		helpers.CopyFile(c.lc, src, dst)
-		os.Chmod(dst, os.FileMode(0777))
-	} else if request.Method == http.MethodDelete {
-		err = os.Remove(dst)
-		if err != nil {
-			helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for JobId: %s", pkg.ErrFileDeletingReject, jobId), http.StatusInternalServerError)
+		dstDirs[dstDir] = true
+
+		src := job.InputFile.ArchiveName
+		dst := path.Join(dstDir, job.InputFile.Name)
+
+		dirExists := true
+		if _, err := os.Stat(dstDir); os.IsNotExist(err) {
+			dirExists = false
 		}
 
-		if ok, err := helpers.DirectoryIsEmpty(dstDir); ok && err == nil {
-			err := os.RemoveAll(dstDir)
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) && request.Method == http.MethodPost {
+			if !dirExists {
+				os.Mkdir(dstDir, os.FileMode(0777))
+				os.Chmod(dstDir, os.FileMode(0777))
+			}
+			helpers.CopyFile(c.lc, src, dst)
+			os.Chmod(dst, os.FileMode(0777))
+		} else if request.Method == http.MethodDelete {
+			err = os.Remove(dst)
 			if err != nil {
-				helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("failed to remove empty directory %s", dstDir), http.StatusInternalServerError)
+				helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for JobId: %s", pkg.ErrFileDeletingReject, jobId), http.StatusInternalServerError)
+				return
+			}
+
+			if ok, err := helpers.DirectoryIsEmpty(dstDir); ok && err == nil {
+				err := os.RemoveAll(dstDir)
+				if err != nil {
+					helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("failed to remove empty directory %s", dstDir), http.StatusInternalServerError)
+					return
+				}
 			}
 		}
 	}
 }
 
+// rejectTaskIds returns the ids of the tasks whose pipelines the input file of the job is rejected for.
+// A fanned out job has no task id in its pipeline details, so it is rejected for the task of each pipeline run.
+func rejectTaskIds(job types.Job) []string {
+	if job.PipelineDetails.TaskId != "" {
+		return []string{job.PipelineDetails.TaskId}
+	}
+	taskIds := make([]string, 0, len(job.PipelineRuns))
+	for _, run := range job.PipelineRuns {
+		taskIds = append(taskIds, run.TaskId)
+	}
+	return taskIds
+}
+
 // RetryOnStartup will be called on startup to look at what job objects the file sender gateway owns and attempts to
 // process them. The function checks that the job host name matches a pipeline and will send the data onto
 // the message bus for the file receiver oem.
@@ -349,11 +385,19 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
-	job, ok := c.jobMap[jobId]
+	_, ok := c.jobMap[jobId]
 	if !ok {
 		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("%s for JobId %s:", pkg.ErrJobInvalid, jobId), http.StatusInternalServerError)
 		return
 	}
+	// the output files are updated from the current job, and the updates are applied to the version of the job they
+	// were made on, or again to the current version if the job has changed since
+	job, err := c.jobRepoClient.RetrieveById(jobId)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "%s for JobId %s", pkg.ErrRetrieving, jobId), http.StatusInternalServerError)
+		return
+	}
+	version := job.Version
 
 	// archive input file
 	timestamp := time.Now().UTC().UnixNano()
@@ -365,10 +409,10 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 		// if err when trying to archive input file, then update job repo
 		jobErr := pkg.CreateUserFacingError(pkg.OwnerFileSenderGateway, pkg.ErrFileArchiving)
 		// update to owner none as we don't want to reprocess files with issues
-		_, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, pkg.StatusFileError, "", jobErr, "", "", nil)
+		_, updateErr := c.updateArchivedJob(job.Id, version, pkg.StatusFileError, jobErr, "", "", nil)
 		if updateErr != nil {
 			helpers.HandleErrorMessage(c.lc, writer,
-				fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+				fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 				http.StatusInternalServerError)
 			return
 		}
@@ -379,13 +423,14 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 	err = job.ValidateFiles()
 	if err != nil {
 		jobErr := pkg.CreateUserFacingError(pkg.OwnerFileSenderGateway, pkg.ErrFileInvalid)
-		_, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, pkg.StatusFileError, "", jobErr, inputArchiveName, "", job.PipelineDetails.OutputFiles)
+		updated, updateErr := c.updateArchivedJob(job.Id, version, pkg.StatusFileError, jobErr, inputArchiveName, "", job.PipelineDetails.OutputFiles)
 		if updateErr != nil {
 			helpers.HandleErrorMessage(c.lc, writer,
-				fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+				fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 				http.StatusInternalServerError)
 			return
 		}
+		version = updated.Version
 		c.lc.Warnf("files %s not found for job id %s: %v but still continuing to archive valid job output files", job.PipelineDetails.OutputFiles, job.Id, err)
 	}
 
@@ -406,13 +451,14 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 			// update file owner for job
 			job.UpdateOutputFile(fileId, "", "", "", "", "", pkg.ErrFileArchiving, pkg.FileStatusArchiveFailed, pkg.OwnerFileSenderGateway)
 			c.lc.Debugf("failure archiving file named %s for job %s", job.PipelineDetails.OutputFiles[fileId].Name, jobId)
-			_, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, pkg.StatusFileError, "", jobErr, inputArchiveName, "", job.PipelineDetails.OutputFiles)
+			updated, updateErr := c.updateArchivedJob(job.Id, version, pkg.StatusFileError, jobErr, inputArchiveName, "", job.PipelineDetails.OutputFiles)
 			if updateErr != nil {
 				helpers.HandleErrorMessage(c.lc, writer,
-					fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+					fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 					http.StatusInternalServerError)
 				return
 			}
+			version = updated.Version
 			continue
 		} else {
 			// if this point is reached for an output file, then it had no error and can be marked complete
@@ -424,10 +470,10 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 	if job.Status != pkg.StatusFileError {
 		job.Status = pkg.StatusComplete
 	}
-	updatedJob, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, job.Status, "", job.ErrorDetails, inputArchiveName, inputArchiveName, job.PipelineDetails.OutputFiles)
+	updatedJob, updateErr := c.updateArchivedJob(job.Id, version, job.Status, job.ErrorDetails, inputArchiveName, inputArchiveName, job.PipelineDetails.OutputFiles)
 	if updateErr != nil {
 		helpers.HandleErrorMessage(c.lc, writer,
-			fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+			fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 			http.StatusInternalServerError)
 		return
 	}
@@ -445,9 +491,13 @@ func (c *Controller) ArchiveFile(writer http.ResponseWriter, request *http.Reque
 	delete(c.jobMap, jobId)
 }
 
+// Convert creates the viewable jpeg files of the archived input and output files of the job. The job should be current,
+// since the updated output files are applied to the version of the job, or again to the current version if the job
+// has changed since.
 func (c *Controller) Convert(writer http.ResponseWriter, job types.Job, inputArchiveDir string) {
 
 	jobId := job.Id
+	version := job.Version
 	inputViewableDir := inputArchiveDir
 	//open input file in order to convert and save
 	filebytes, err := os.ReadFile(inputArchiveDir)
@@ -470,11 +520,12 @@ func (c *Controller) Convert(writer http.ResponseWriter, job types.Job, inputArc
 		err = imgconv.Save(inputViewableDir, srcImg, &imgconv.FormatOption{Format: imgconv.JPEG})
 		if err != nil {
 			jobErr := pkg.CreateUserFacingError(pkg.OwnerFileSenderGateway, pkg.ErrFileInvalid)
-			_, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, pkg.StatusFileError, "", jobErr, inputArchiveDir, "", job.PipelineDetails.OutputFiles)
+			updated, updateErr := c.updateArchivedJob(job.Id, version, pkg.StatusFileError, jobErr, inputArchiveDir, "", job.PipelineDetails.OutputFiles)
 			if updateErr != nil {
-				helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "failed to write archival jpeg for input file for JobId %s", jobId), http.StatusInternalServerError)
+				helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(updateErr, "failed to write archival jpeg for input file for JobId %s", jobId), http.StatusInternalServerError)
 				return
 			}
+			version = updated.Version
 		}
 		c.lc.Debugf("visualization input file created for job %s", jobId)
 	}
@@ -509,23 +560,24 @@ func (c *Controller) Convert(writer http.ResponseWriter, job types.Job, inputArc
 				// update file owner for job
 				job.UpdateOutputFile(fileId, "", "", "", "", "", pkg.ErrFileArchiving, pkg.FileStatusArchiveFailed, pkg.OwnerFileSenderGateway)
 				c.lc.Errorf("failed to create visualized output file named %s for job %s", job.PipelineDetails.OutputFiles[fileId].Name, jobId)
-				_, updateErr := helpers.UpdateJobFields(c.jobRepoClient, job.Id, pkg.OwnerNone, pkg.StatusFileError, "", jobErr, inputArchiveDir, "", job.PipelineDetails.OutputFiles)
+				updated, updateErr := c.updateArchivedJob(job.Id, version, pkg.StatusFileError, jobErr, inputArchiveDir, "", job.PipelineDetails.OutputFiles)
 				if updateErr != nil {
 					helpers.HandleErrorMessage(c.lc, writer,
-						fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+						fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 						http.StatusInternalServerError)
 					return
 				}
+				version = updated.Version
 				continue
 			}
 			c.lc.Debugf("visualization file created for output file %d on job %s", fileId, jobId)
 			job.UpdateOutputFile(fileId, "", "", "", outputArchiveName, outputViewableName, err, pkg.FileStatusComplete, pkg.OwnerNone)
 		}
 	}
-	updatedJob, updateErr := helpers.UpdateJobFields(c.jobRepoClient, jobId, pkg.OwnerNone, job.Status, "", job.ErrorDetails, inputArchiveDir, inputViewableDir, job.PipelineDetails.OutputFiles)
+	updatedJob, updateErr := c.updateArchivedJob(jobId, version, job.Status, job.ErrorDetails, inputArchiveDir, inputViewableDir, job.PipelineDetails.OutputFiles)
 	if updateErr != nil {
 		helpers.HandleErrorMessage(c.lc, writer,
-			fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), err.Error()),
+			fmt.Errorf("%s for job %s: %s", pkg.ErrUpdating, job.FullInputFileLocation(), updateErr.Error()),
 			http.StatusInternalServerError)
 		return
 	}
@@ -533,6 +585,23 @@ func (c *Controller) Convert(writer http.ResponseWriter, job types.Job, inputArc
 
 }
 
+// updateArchivedJob updates the job with the archive names, status and output files of the job copy at the version.
+// The files are already moved when the job is updated, so if the job has changed since, the update is applied again
+// to the current version of the job.
+func (c *Controller) updateArchivedJob(jobId string, version int64, status string, errDetails *pkg.UserFacingError, inputArchiveName string, inputViewableName string, outputFiles []types.OutputFile) (types.Job, error) {
+	for attempt := 1; ; attempt++ {
+		updated, err := helpers.UpdateJobFieldsVersion(c.jobRepoClient, jobId, version, pkg.OwnerNone, status, "", errDetails, inputArchiveName, inputViewableName, outputFiles)
+		if err != pkg.ErrJobVersionMismatch || attempt == maxUpdateAttempts {
+			return updated, err
+		}
+		current, err := c.jobRepoClient.RetrieveById(jobId)
+		if err != nil {
+			return current, err
+		}
+		version = current.Version
+	}
+}
+
 // initJobMap is a helper function to initialize the job map with the jobs it will need to send to the File Receiver OEM
 func (c *Controller) initJobMap() error {
 	jobs, err := c.jobRepoClient.RetrieveAllByOwner(pkg.OwnerFileRecvOem)
@@ -323,7 +323,8 @@ func TestFileSender_ArchiveFilesPositive(t *testing.T) {
 			// Add job to testController.JobMap if needed to test all err conditions
 			testController.jobMap[test.Expected.Id] = test.Expected
 			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.Expected.Id})
-			repoMock.On("Update", test.Expected.Id, mock.Anything).Return(test.Expected, nil)
+			repoMock.On("RetrieveById", test.Expected.Id).Return(test.Expected, nil)
+			repoMock.On("UpdateVersion", test.Expected.Id, mock.Anything, test.Expected.Version).Return(test.Expected, nil)
 
 			testController.ArchiveFile(w, req)
 			resp := w.Result()
@@ -356,6 +357,50 @@ func TestFileSender_ArchiveFilesPositive(t *testing.T) {
 	}
 }
 
+func TestFileSender_ArchiveFileJobChanged(t *testing.T) {
+	tearDownTestResources := helpers.SetupTestFiles(t)
+	defer tearDownTestResources(t)
+
+	job := helpers.CreateTestJob(pkg.OwnerFileSenderGateway, fileHostname)
+	changed := job
+	changed.Version = job.Version + 1
+
+	repoMock := jobRepoMocks.Client{}
+	repoMock.On("RetrieveAllByOwner", pkg.OwnerFileRecvOem).Return(make([]types.Job, 0), nil)
+	launcherMock := taskLauncherMocks.Client{}
+	testController, err := New(logger.NewMockClient(), &repoMock, &launcherMock, mockBackgroundPublisher, mockAppService, fileHostname, archiveFolder, rejectFolder)
+	require.NoError(t, err)
+
+	// the job changes after it was retrieved, so the update is applied again to the changed job
+	testController.jobMap[job.Id] = job
+	repoMock.On("RetrieveById", job.Id).Return(job, nil).Once()
+	repoMock.On("RetrieveById", job.Id).Return(changed, nil)
+	repoMock.On("UpdateVersion", job.Id, mock.Anything, job.Version).Return(job, pkg.ErrJobVersionMismatch)
+	repoMock.On("UpdateVersion", job.Id, mock.Anything, changed.Version).Return(changed, nil)
+
+	req := httptest.NewRequest("POST", "http://localhost", nil)
+	req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: job.Id})
+	w := httptest.NewRecorder()
+	testController.ArchiveFile(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	// clean up the directory and rename the files back
+	files, err := os.ReadDir(archiveFolder)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+	for _, f := range files {
+		if !f.IsDir() {
+			oldFile := strings.Join([]string{strings.Split(f.Name(), "_archive")[0], filepath.Ext(f.Name())}, "")
+			err = os.Rename(filepath.Join(archiveFolder, f.Name()), filepath.Join(".", "test", oldFile))
+			require.NoError(t, err)
+		}
+	}
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "invalid status code")
+	repoMock.AssertCalled(t, "UpdateVersion", job.Id, mock.Anything, changed.Version)
+}
+
 func TestFileSender_ArchiveFilesNegative(t *testing.T) {
 	tearDownTestResources := helpers.SetupTestFiles(t)
 	defer tearDownTestResources(t)
@@ -371,18 +416,23 @@ func TestFileSender_ArchiveFilesNegative(t *testing.T) {
 	badOutputFileJob.Status = pkg.StatusFileError
 	badOutputFileJob.ErrorDetails = pkg.CreateUserFacingError(pkg.OwnerFileSenderGateway, pkg.ErrFileInvalid)
 
+	changedJob := helpers.CreateTestJob(pkg.OwnerFileSenderGateway, fileHostname)
+	changedJob.Version = 3
+
 	tests := []struct {
 		Name               string
 		Expected           *types.Job
+		UpdateErr          error
 		ExpectedErr        error
 		ExpectedStatusCode int
 		ExpectedFiles      int
 	}{
-		{"invalid job id", nil, errors.New("missing jobid in url"), http.StatusBadRequest, 0},
-		{"bad input file", &badInputFileJob, errors.New("failed to archive input file"), http.StatusInternalServerError, 0},
+		{"invalid job id", nil, nil, errors.New("missing jobid in url"), http.StatusBadRequest, 0},
+		{"bad input file", &badInputFileJob, nil, errors.New("failed to archive input file"), http.StatusInternalServerError, 0},
 		// Note: the below test case responds with a 200 as it tries to archive the output files that it can.
 		// The error feedback for bad output files are captured in the job/file err details fields.
-		{"bad output file", &badOutputFileJob, pkg.ErrFileInvalid, http.StatusOK, 1},
+		{"bad output file", &badOutputFileJob, nil, pkg.ErrFileInvalid, http.StatusOK, 1},
+		{"job changed on every attempt", &changedJob, pkg.ErrJobVersionMismatch, pkg.ErrJobVersionMismatch, http.StatusInternalServerError, 2},
 	}
 
 	for _, test := range tests {
@@ -405,7 +455,8 @@ func TestFileSender_ArchiveFilesNegative(t *testing.T) {
 				if test.Expected.PipelineDetails.OutputFiles[0].DirName == "" {
 					jobFields[types.JobPipelineOutputFiles] = test.Expected.PipelineDetails.OutputFiles
 				}
-				repoMock.On("Update", test.Expected.Id, mock.Anything).Return(*test.Expected, nil)
+				repoMock.On("RetrieveById", test.Expected.Id).Return(*test.Expected, nil)
+				repoMock.On("UpdateVersion", test.Expected.Id, mock.Anything, test.Expected.Version).Return(*test.Expected, test.UpdateErr)
 			}
 
 			testController.ArchiveFile(w, req)
@@ -490,7 +541,7 @@ func TestFileSender_ImageConversionPositive(t *testing.T) {
 
 			w := httptest.NewRecorder()
 
-			repoMock.On("Update", test.Expected.Id, mock.Anything).Return(test.Expected, nil)
+			repoMock.On("UpdateVersion", test.Expected.Id, mock.Anything, test.Expected.Version).Return(test.Expected, nil)
 
 			testController.Convert(w, test.Expected, test.Expected.InputFile.ArchiveName)
 			resp := w.Result()
@@ -574,7 +625,7 @@ func TestFileSender_ImageConversionNegative(t *testing.T) {
 
 			w := httptest.NewRecorder()
 
-			repoMock.On("Update", test.Expected.Id, mock.Anything).Return(test.Expected, nil)
+			repoMock.On("UpdateVersion", test.Expected.Id, mock.Anything, test.Expected.Version).Return(test.Expected, nil)
 
 			testController.Convert(w, test.Expected, test.Expected.InputFile.ArchiveName)
 			resp := w.Result()
@@ -602,3 +653,74 @@ func TestFileSender_ImageConversionNegative(t *testing.T) {
 	require.NoError(t, os.RemoveAll(filepath.Join(".", testDir)))
 	require.NoError(t, os.RemoveAll(archiveFolder))
 }
+
+func TestFileSender_RejectFile(t *testing.T) {
+	archived := filepath.Join(t.TempDir(), file)
+	require.NoError(t, os.WriteFile(archived, []byte{}, pkg.FilePermissions))
+	testRejectFolder := t.TempDir()
+
+	single := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	single.InputFile.ArchiveName = archived
+	fannedOut := single
+	fannedOut.PipelineDetails.TaskId = ""
+	fannedOut.PipelineRuns = []types.PipelineInfo{{TaskId: "2"}, {TaskId: "3"}, {TaskId: "4"}}
+	noTask := fannedOut
+	noTask.PipelineRuns = nil
+	tasks := map[string]types.Task{
+		"1": {Id: "1", PipelineId: "models/model1"},
+		"2": {Id: "2", PipelineId: "models/model2"},
+		"3": {Id: "3", PipelineId: "model3"},
+		"4": {Id: "4", PipelineId: "other/model3"},
+	}
+
+	tests := []struct {
+		Name               string
+		Job                types.Job
+		Method             string
+		ExpectedFolders    []string
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path - reject", single, http.MethodPost, []string{"model1"}, http.StatusOK, ""},
+		{"happy path - unreject", single, http.MethodDelete, []string{}, http.StatusOK, ""},
+		{"happy path - reject fanned out", fannedOut, http.MethodPost, []string{"model2", "model3"}, http.StatusOK, ""},
+		{"happy path - unreject fanned out", fannedOut, http.MethodDelete, []string{}, http.StatusOK, ""},
+		{"no task", noTask, http.MethodPost, []string{}, http.StatusInternalServerError, "no task ran for the job"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			repoMock := jobRepoMocks.Client{}
+			repoMock.On("RetrieveAllByOwner", pkg.OwnerFileRecvOem).Return(make([]types.Job, 0), nil)
+			repoMock.On("RetrieveById", test.Job.Id).Return(test.Job, nil)
+			launcherMock := taskLauncherMocks.Client{}
+			for id, task := range tasks {
+				launcherMock.On("RetrieveById", id).Return(task, nil)
+			}
+			testController, err := New(logger.NewMockClient(), &repoMock, &launcherMock, mockBackgroundPublisher, mockAppService, fileHostname, archiveFolder, testRejectFolder)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(test.Method, "http://localhost", nil)
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.Job.Id})
+			w := httptest.NewRecorder()
+			testController.RejectFile(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), test.ExpectedErrorMsg)
+			}
+			entries, err := os.ReadDir(testRejectFolder)
+			require.NoError(t, err)
+			folders := []string{}
+			for _, entry := range entries {
+				folders = append(folders, entry.Name())
+				assert.FileExists(t, filepath.Join(testRejectFolder, entry.Name(), test.Job.InputFile.Name))
+			}
+			assert.Equal(t, test.ExpectedFolders, folders)
+		})
+	}
+}
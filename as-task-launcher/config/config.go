@@ -7,11 +7,14 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
 
 	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
 )
 
 // TODO: Define your structured custom configuration types. Must be wrapped with an outer struct with
@@ -49,6 +52,33 @@ type Configuration struct {
 	FileHostname          string
 	RedisHost             string
 	RedisPort             string
+	PersistenceType       string
+	PersistenceFile       string
+	// FanOut launches a job for every task that matches it rather than only the first one
+	FanOut bool
+	// PipelineServiceUrls are the base urls of the pipeline services whose advertised pipelines
+	// the PipelineId of a task is checked against, the check is skipped when there are none
+	PipelineServiceUrls []string
+	// WatchdogInterval is how often the jobs stuck processing on a pipeline are checked for, zero disables the watchdog
+	WatchdogInterval time.Duration
+	// PipelineTimeout is how long a pipeline may process a job before it is published again,
+	// for the tasks that do not set their own timeout
+	PipelineTimeout time.Duration
+	// WatchdogMaxAttempts is the number of times a stuck job is published again before the pipeline timed out
+	WatchdogMaxAttempts int
+	// MaxInFlight is the number of jobs that may be in flight on a pipeline at once, further jobs wait in the
+	// dispatch queue for a free slot. Zero publishes every job right away.
+	MaxInFlight int
+	// PipelineMaxInFlight overrides the MaxInFlight for the given PipelineIds
+	PipelineMaxInFlight map[string]int
+	// ResultsTopic is the topic the results of the pipelines are published to, for the tasks that do not set their own
+	ResultsTopic string
+	// LegacyResultsFormat publishes the results as the "filename:...; results:..." string
+	// rather than the JSON ResultsEnvelope
+	LegacyResultsFormat bool
+	// FilenameDecoder parses the attributes of the input file name of the jobs explained by the task matching,
+	// loaded from the same AttributeParser configuration as the data organizer
+	FilenameDecoder types.FilenameDecoder
 }
 
 func New(service interfaces.ApplicationService) (*Configuration, error) {
@@ -103,11 +133,132 @@ func New(service interfaces.ApplicationService) (*Configuration, error) {
 	if err != nil {
 		return nil, err
 	}
+	config.PersistenceType, config.PersistenceFile, err = helpers.GetPersistenceFromAppSetting(service)
+	if err != nil {
+		return nil, err
+	}
 
 	config.FileHostname, err = helpers.GetAppSetting(service, "FileHostname", false)
 	if err != nil {
 		return nil, err
 	}
 
+	// fan out is opt-in, an empty setting leaves it disabled
+	fanOut, err := helpers.GetAppSetting(service, "FanOut", true)
+	if err != nil {
+		return nil, err
+	}
+	if fanOut != "" {
+		config.FanOut, err = strconv.ParseBool(fanOut)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse bool for fan out, got %s: %s", fanOut, err.Error())
+		}
+	}
+
+	config.ResultsTopic, err = helpers.GetAppSetting(service, "ResultsTopic", true)
+	if err != nil {
+		return nil, err
+	}
+
+	legacyResultsFormat, err := helpers.GetAppSetting(service, "LegacyResultsFormat", true)
+	if err != nil {
+		return nil, err
+	}
+	if legacyResultsFormat != "" {
+		config.LegacyResultsFormat, err = strconv.ParseBool(legacyResultsFormat)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse bool for legacy results format, got %s: %s", legacyResultsFormat, err.Error())
+		}
+	}
+
+	pipelineServiceUrls, err := helpers.GetAppSetting(service, "PipelineServiceUrls", true)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range strings.Split(pipelineServiceUrls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			config.PipelineServiceUrls = append(config.PipelineServiceUrls, url)
+		}
+	}
+
+	maxInFlight, err := helpers.GetAppSetting(service, "MaxInFlight", true)
+	if err != nil {
+		return nil, err
+	}
+	if maxInFlight != "" {
+		config.MaxInFlight, err = strconv.Atoi(maxInFlight)
+		if err != nil || config.MaxInFlight < 0 {
+			return nil, fmt.Errorf("could not parse non-negative integer for max in flight, got %s", maxInFlight)
+		}
+	}
+
+	pipelineMaxInFlight, err := helpers.GetAppSetting(service, "PipelineMaxInFlight", true)
+	if err != nil {
+		return nil, err
+	}
+	config.PipelineMaxInFlight, err = parsePipelineMaxInFlight(pipelineMaxInFlight)
+	if err != nil {
+		return nil, err
+	}
+
+	// the watchdog is opt-in, an empty interval leaves it disabled
+	watchdogInterval, err := helpers.GetAppSetting(service, "WatchdogInterval", true)
+	if err != nil {
+		return nil, err
+	}
+	if watchdogInterval != "" {
+		config.WatchdogInterval, err = time.ParseDuration(watchdogInterval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse duration for watchdog interval, got %s: %s", watchdogInterval, err.Error())
+		}
+
+		pipelineTimeout, err := helpers.GetAppSetting(service, "PipelineTimeout", false)
+		if err != nil {
+			return nil, err
+		}
+		config.PipelineTimeout, err = time.ParseDuration(pipelineTimeout)
+		if err != nil || config.PipelineTimeout <= 0 {
+			return nil, fmt.Errorf("could not parse positive duration for pipeline timeout, got %s", pipelineTimeout)
+		}
+
+		maxAttempts, err := helpers.GetAppSetting(service, "WatchdogMaxAttempts", false)
+		if err != nil {
+			return nil, err
+		}
+		config.WatchdogMaxAttempts, err = strconv.Atoi(maxAttempts)
+		if err != nil || config.WatchdogMaxAttempts < 0 {
+			return nil, fmt.Errorf("could not parse non-negative integer for watchdog max attempts, got %s", maxAttempts)
+		}
+	}
+
 	return &config, nil
 }
+
+// MaxInFlightFor returns the number of jobs that may be in flight on the pipeline at once, zero if it is not limited
+func (c *Configuration) MaxInFlightFor(pipelineId string) int {
+	if maxInFlight, ok := c.PipelineMaxInFlight[pipelineId]; ok {
+		return maxInFlight
+	}
+	return c.MaxInFlight
+}
+
+// parsePipelineMaxInFlight parses a comma separated list of PipelineId and max in flight pairs, e.g. "only-file:2,geti/count-cells:1"
+func parsePipelineMaxInFlight(setting string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(setting, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		separator := strings.LastIndex(pair, ":")
+		if separator <= 0 {
+			return nil, fmt.Errorf("could not parse pipeline max in flight %s, expected PipelineId:count", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(pair[separator+1:]))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("could not parse non-negative integer for the max in flight of pipeline %s", pair)
+		}
+		limits[strings.TrimSpace(pair[:separator])] = limit
+	}
+	return limits, nil
+}
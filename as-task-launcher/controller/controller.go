@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
@@ -25,6 +26,7 @@ import (
 	"aicsd/pkg"
 	"aicsd/pkg/clients/job_handler"
 	"aicsd/pkg/clients/job_repo"
+	"aicsd/pkg/clients/pipeline"
 	"aicsd/pkg/helpers"
 	"aicsd/pkg/types"
 	"aicsd/pkg/werrors"
@@ -35,6 +37,9 @@ import (
 const (
 	MqttResultsTopic = "pipeline/inferenceResults"
 	CustomTopic      = "pipeline-inference-results"
+	// maxSummarizeAttempts is the number of times the summary of the pipeline runs of a fanned out job is written
+	// before giving up on the concurrent updates of the job
+	maxSummarizeAttempts = 5
 )
 
 type Controller struct {
@@ -42,20 +47,24 @@ type Controller struct {
 	persist           persist.Persistence
 	jobRepoClient     job_repo.Client
 	fileSenderClient  job_handler.Client
+	pipelineClients   []pipeline.Client
 	publisher         interfaces.BackgroundPublisher
 	service           interfaces.ApplicationService
 	config            *config.Configuration
 	DependentServices wait.Services
+	// dispatchMutex serializes the reads and updates of the dispatch queue
+	dispatchMutex sync.Mutex
 }
 
 func New(lc logger.LoggingClient, persist persist.Persistence, jobRepoClient job_repo.Client,
-	fileSenderClient job_handler.Client, publisher interfaces.BackgroundPublisher,
+	fileSenderClient job_handler.Client, pipelineClients []pipeline.Client, publisher interfaces.BackgroundPublisher,
 	service interfaces.ApplicationService, config *config.Configuration) *Controller {
 	return &Controller{
 		lc:                lc,
 		persist:           persist,
 		jobRepoClient:     jobRepoClient,
 		fileSenderClient:  fileSenderClient,
+		pipelineClients:   pipelineClients,
 		publisher:         publisher,
 		service:           service,
 		config:            config,
@@ -86,6 +95,16 @@ func (c *Controller) RegisterRoutes(service interfaces.ApplicationService) error
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "delete")
 	}
 
+	err = service.AddRoute(pkg.EndpointTaskVersions, c.GetVersions, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointTaskVersions)
+	}
+
+	err = service.AddRoute(pkg.EndpointTaskRollback, c.Rollback, http.MethodPost)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointTaskRollback)
+	}
+
 	err = service.AddRoute(pkg.EndpointTask, c.Update, http.MethodPut)
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "update")
@@ -96,6 +115,11 @@ func (c *Controller) RegisterRoutes(service interfaces.ApplicationService) error
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointMatchTask)
 	}
 
+	err = service.AddRoute(pkg.EndpointMatchTaskExplain, c.MatchTaskExplain, http.MethodPost)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointMatchTaskExplain)
+	}
+
 	err = service.AddRoute(pkg.EndpointDataToHandle, c.HandleNewJob, http.MethodPost)
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointDataToHandle)
@@ -109,6 +133,11 @@ func (c *Controller) RegisterRoutes(service interfaces.ApplicationService) error
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointRetry)
 	}
+
+	err = service.AddRoute(pkg.EndpointQueue, c.GetQueue, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, pkg.EndpointQueue)
+	}
 	return nil
 }
 
@@ -126,6 +155,12 @@ func (c *Controller) Create(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	err = c.validateTask(task, false)
+	if err != nil {
+		c.handleInvalidTask(writer, err)
+		return
+	}
+
 	currentTaskId, err := c.persist.Create(task)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapErr(err, taskPkg.ErrTaskCreation), http.StatusInternalServerError)
@@ -204,6 +239,12 @@ func (c *Controller) Update(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	err = c.validateTask(task, true)
+	if err != nil {
+		c.handleInvalidTask(writer, err)
+		return
+	}
+
 	err = c.persist.Update(task)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("failed to update task for Id (%s): %s",
@@ -241,9 +282,44 @@ func (c *Controller) RetryOnStartup(retryTimeout int64) error {
 		err = fmt.Errorf("could not retrieve %s data: %s", pkg.OwnerTaskLauncher, err.Error())
 		return err
 	}
+	queued, err := c.queuedJobIds()
+	if err != nil {
+		return fmt.Errorf("could not retrieve the dispatch queue: %s", err.Error())
+	}
 	for _, job := range jobs {
+		// a queued job is published once its pipeline has a free slot
+		if queued[job.Id] {
+			c.lc.Debugf("Job with input file %s is queued for its pipeline", job.FullInputFileLocation())
+			continue
+		}
+		if len(job.PipelineRuns) > 0 && job.PipelineDetails.Status == pkg.TaskStatusProcessing {
+			var summarized bool
+			job, summarized, err = c.summarizePipelineRuns(job)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			if !summarized {
+				if job.PipelineDetails.Status == pkg.TaskStatusProcessing && job.LastUpdated+retryTimeout < now {
+					c.lc.Debugf("Resending job to the pipelines of its processing runs for Job with input file %s", job.FullInputFileLocation())
+					err = c.resendPipelineRuns(job)
+					if err != nil {
+						errs = multierror.Append(errs, err)
+					}
+				}
+				continue
+			}
+		}
 		if job.PipelineDetails.Status == pkg.TaskStatusComplete || job.PipelineDetails.Status == pkg.TaskStatusFailed {
 			c.lc.Debugf("Task status is complete or failed for Job with input file %s", job.FullInputFileLocation())
+			launched, err := c.launchNextStage(job)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			if launched {
+				continue
+			}
 			// update job object with its status if all tasks complete and no output file
 			if len(job.PipelineDetails.OutputFiles) == 0 {
 				jobFields := make(map[string]interface{})
@@ -268,6 +344,18 @@ func (c *Controller) RetryOnStartup(retryTimeout int64) error {
 				}
 				continue
 			}
+		} else if job.PipelineDetails.Status == pkg.TaskStatusProcessing && job.LastUpdated+retryTimeout < now && len(job.PipelineStages) > 0 {
+			// a follow-up stage is resent to the task of the stage rather than matched to the tasks again
+			c.lc.Debugf("Resending job to the pipeline of stage %d for Job with input file %s", len(job.PipelineStages)+1, job.FullInputFileLocation())
+			task, err := c.persist.GetVersion(job.PipelineDetails.TaskId, job.PipelineDetails.TaskVersion)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("could not retrieve task %s for job id %s: %s", job.PipelineDetails.TaskId, job.Id, err.Error()))
+				continue
+			}
+			err = c.publishEventForTask(job, &task)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+			}
 		} else if job.PipelineDetails.Status == pkg.TaskStatusProcessing && job.LastUpdated+retryTimeout < now {
 			c.lc.Debugf("Resending job to its pipeline for Job with input file %s", job.FullInputFileLocation())
 			matchedTask, err := c.matchJobToTasks(job)
@@ -293,13 +381,14 @@ func (c *Controller) RetryOnStartup(retryTimeout int64) error {
 			jobFields := make(map[string]interface{})
 			jobFields[types.JobOwner] = pkg.OwnerTaskLauncher
 			jobFields[types.JobPipelineTaskId] = matchedTask.Id
+			jobFields[types.JobPipelineTaskVersion] = matchedTask.Version
 			jobFields[types.JobPipelineOutputHost] = c.config.FileHostname
 			job, err = c.jobRepoClient.Update(job.Id, jobFields)
 			if err != nil {
 				errs = multierror.Append(errs, fmt.Errorf("could not update job repo to status no pipeline for job with input file %s: %s", job.FullInputFileLocation(), err.Error()))
 				continue
 			}
-			err = helpers.PublishEventForPipeline(c.publisher, c.service, c.lc, job, matchedTask, c.config.JobRepoBaseUrl, c.config.PipelineStatusBaseUrl, c.config.DeviceProfileName, c.config.DeviceName, c.config.ResourceName)
+			err = c.publishEventForTask(job, matchedTask)
 			if err != nil {
 				errs = multierror.Append(errs, err)
 				continue
@@ -387,13 +476,13 @@ func (c *Controller) HandleNewJob(writer http.ResponseWriter, request *http.Requ
 	}
 
 	// call match task and set info to metadata object
-	matchedTask, err := c.matchJobToTasks(job)
+	matchedTasks, err := c.tasksForJob(job)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusInternalServerError)
 		return
 	}
 
-	if matchedTask == nil {
+	if len(matchedTasks) == 0 {
 		jobFields := make(map[string]interface{})
 		jobFields[types.JobOwner] = pkg.OwnerNone
 		jobFields[types.JobStatus] = pkg.StatusNoPipeline
@@ -414,9 +503,19 @@ func (c *Controller) HandleNewJob(writer http.ResponseWriter, request *http.Requ
 	// take ownership, add task info to pipeline details, update the data repo and ack back
 	jobFields := make(map[string]interface{})
 	jobFields[types.JobOwner] = pkg.OwnerTaskLauncher
-	jobFields[types.JobPipelineTaskId] = matchedTask.Id
 	jobFields[types.JobPipelineOutputHost] = c.config.FileHostname
 	jobFields[types.JobPipelineStatus] = pkg.TaskStatusProcessing
+	if len(matchedTasks) == 1 {
+		jobFields[types.JobPipelineTaskId] = matchedTasks[0].Id
+		jobFields[types.JobPipelineTaskVersion] = matchedTasks[0].Version
+	} else {
+		// a fanned out job keeps the details of each task run apart from the pipeline details summarizing them
+		runs := make([]types.PipelineInfo, len(matchedTasks))
+		for k, task := range matchedTasks {
+			runs[k] = types.PipelineInfo{TaskId: task.Id, TaskVersion: task.Version, Status: pkg.TaskStatusProcessing, OutputFileHost: c.config.FileHostname}
+		}
+		jobFields[types.JobPipelineRuns] = runs
+	}
 	job, err = c.jobRepoClient.Update(job.Id, jobFields)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer,
@@ -429,15 +528,16 @@ func (c *Controller) HandleNewJob(writer http.ResponseWriter, request *http.Requ
 
 	writer.WriteHeader(http.StatusOK)
 
-	err = helpers.PublishEventForPipeline(c.publisher, c.service, c.lc, job, matchedTask, c.config.JobRepoBaseUrl, c.config.PipelineStatusBaseUrl, c.config.DeviceProfileName, c.config.DeviceName, c.config.ResourceName)
-	if err != nil {
-		c.lc.Error(err.Error())
+	for k := range matchedTasks {
+		err = c.dispatchJob(job, &matchedTasks[k])
+		if err != nil {
+			c.lc.Error(err.Error())
+		}
 	}
-
 }
 
 // PipelineStatus takes the task status and handles the job after the pipeline has been completed
-// It will send a file if needed
+// It will launch the follow-up task of a completed stage, or send a file if needed
 func (c *Controller) PipelineStatus(writer http.ResponseWriter, request *http.Request) {
 	// unpack the job id, task id, task status
 	jobId, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
@@ -465,21 +565,59 @@ func (c *Controller) PipelineStatus(writer http.ResponseWriter, request *http.Re
 		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
 		return
 	}
+	// the reported details are those of the task run when the job is fanned out
+	reported := job.PipelineDetails
 	if job.PipelineDetails.TaskId != taskId {
-		helpers.HandleErrorMessage(c.lc, writer,
-			fmt.Errorf("task id mismatch: got %s, expected %s", taskId, job.PipelineDetails.TaskId),
-			http.StatusBadRequest)
-		return
+		runIndex := job.PipelineRunIndex(taskId)
+		if runIndex < 0 {
+			helpers.HandleErrorMessage(c.lc, writer,
+				fmt.Errorf("task id mismatch: got %s, expected %s", taskId, strings.Join(job.TaskIds(), ",")),
+				http.StatusBadRequest)
+			return
+		}
+		reported = job.PipelineRuns[runIndex]
 	}
 	// verify that the current pipeline status matches what is passed in
-	if job.PipelineDetails.Status != taskStatus {
+	if reported.Status != taskStatus {
 		helpers.HandleErrorMessage(c.lc, writer,
-			fmt.Errorf("task status mismatch for job id %s, got %s, expected %s", jobId, taskStatus, job.PipelineDetails.Status),
+			fmt.Errorf("task status mismatch for job id %s, got %s, expected %s", jobId, taskStatus, reported.Status),
 			http.StatusBadRequest)
 		return
 	}
 
-	c.lc.Debugf("Received pipeline status of '%s' for Job with input file %s", taskStatus, job.FullInputFileLocation())
+	c.lc.Debugf("Received pipeline status of '%s' for task %s of Job with input file %s", taskStatus, taskId, job.FullInputFileLocation())
+
+	// the pipeline is free for the next queued job
+	err = c.releaseDispatch(jobId, taskId)
+	if err != nil {
+		c.lc.Errorf("could not release the pipeline of task %s for job id %s: %s", taskId, jobId, err.Error())
+	}
+
+	if len(job.PipelineRuns) > 0 {
+		var summarized bool
+		job, summarized, err = c.summarizePipelineRuns(job)
+		if err != nil {
+			helpers.HandleErrorMessage(c.lc, writer, err, http.StatusInternalServerError)
+			return
+		}
+		if !summarized {
+			// the job is handled once the last of its task runs reports its status
+			writer.WriteHeader(http.StatusOK)
+			c.publishResultsForRun(writer, job, reported)
+			return
+		}
+	}
+
+	// the results are only passed on after the final stage of a chain of tasks
+	launched, err := c.launchNextStage(job)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusInternalServerError)
+		return
+	}
+	if launched {
+		writer.WriteHeader(http.StatusOK)
+		return
+	}
 
 	// update job object with its status if all tasks complete
 	if len(job.PipelineDetails.OutputFiles) == 0 {
@@ -511,15 +649,20 @@ func (c *Controller) PipelineStatus(writer http.ResponseWriter, request *http.Re
 	}
 
 	writer.WriteHeader(http.StatusOK)
+	c.publishResultsForRun(writer, job, reported)
+}
 
-	if job.PipelineDetails.Results != "" {
-		err := c.publishResultsForJob(job)
-		if err != nil {
-			helpers.HandleErrorMessage(c.lc, writer,
-				fmt.Errorf("error publishing results for job"),
-				http.StatusBadRequest)
-			return
-		}
+// publishResultsForRun publishes the results reported by the pipeline run of the task, if there are any
+func (c *Controller) publishResultsForRun(writer http.ResponseWriter, job types.Job, reported types.PipelineInfo) {
+	if reported.Results == "" {
+		return
+	}
+	job.PipelineDetails = reported
+	err := c.publishResultsForJob(job)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			fmt.Errorf("error publishing results for job"),
+			http.StatusBadRequest)
 	}
 }
 
@@ -545,25 +688,177 @@ func (c *Controller) getStatusFromRequestBody(request *http.Request) (string, er
 	return "", taskPkg.ErrReqBodyTaskStatusMismatch
 }
 
-// matchJobToTasks will match a Job to its corresponding Task and return the first task that matches
+// matchJobToTasks will match a Job to its corresponding Task and return the first task that matches,
+// evaluating the enabled tasks by priority and then in creation order
 func (c *Controller) matchJobToTasks(job types.Job) (*types.Task, error) {
+	tasks, err := c.matchingTasks(job, false)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// tasksForJob returns the tasks to run for the job, which are all the matching tasks when fan out is enabled
+// and the first matching task otherwise
+func (c *Controller) tasksForJob(job types.Job) ([]types.Task, error) {
+	return c.matchingTasks(job, c.config.FanOut)
+}
+
+// matchingTasks returns the enabled tasks that match the job in matching order, stopping at the first one unless all is set
+func (c *Controller) matchingTasks(job types.Job, all bool) ([]types.Task, error) {
 	c.lc.Debugf("Matching to Tasks for Job with input file %s", job.FullInputFileLocation())
 	tasks, err := c.persist.GetAll()
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve tasks: %s", err.Error())
 	}
 
-	// iterate over tasks
+	// iterate over tasks in matching order
+	types.SortTasks(tasks)
+	var matched []types.Task
 	for _, task := range tasks {
+		if !task.IsEnabled() {
+			continue
+		}
 		isMatch, err := helpers.ApplyJsonLogicToJob(job, task.JobSelector)
 		if err != nil {
 			return nil, werrors.WrapMsgf(err, "could not apply json logic for job id: %s, task id %s", job.Id, task.Id)
 		}
 		if isMatch {
-			return &task, nil
+			matched = append(matched, task)
+			if !all {
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// publishEventForTask publishes the event launching the pipeline of the task for the job
+func (c *Controller) publishEventForTask(job types.Job, task *types.Task) error {
+	// the pipeline urls are built from the task id of the pipeline details, which is not set for a fanned out job
+	job.PipelineDetails.TaskId = task.Id
+	return helpers.PublishEventForPipeline(c.publisher, c.service, c.lc, job, task, c.config.JobRepoBaseUrl, c.config.PipelineStatusBaseUrl, c.config.DeviceProfileName, c.config.DeviceName, c.config.ResourceName)
+}
+
+// summarizePipelineRuns sets the pipeline details of a fanned out job once all of its task runs reported their status.
+// The pipeline status is complete if every run completed and failed otherwise, and the output files of all the runs
+// are collected so the job is handled like a job run for a single task.
+// It returns true with the updated job when the details were summarized, and false while runs are still processing
+// or if the details were already summarized for the status of another run.
+func (c *Controller) summarizePipelineRuns(job types.Job) (types.Job, bool, error) {
+	for attempt := 1; ; attempt++ {
+		if job.PipelineDetails.Status != pkg.TaskStatusProcessing {
+			return job, false, nil
+		}
+		status := pkg.TaskStatusComplete
+		outputFiles := []types.OutputFile{}
+		for _, run := range job.PipelineRuns {
+			switch run.Status {
+			case pkg.TaskStatusComplete:
+			case pkg.TaskStatusFailed:
+				status = pkg.TaskStatusFailed
+			default:
+				return job, false, nil
+			}
+			outputFiles = append(outputFiles, run.OutputFiles...)
+		}
+
+		jobFields := make(map[string]interface{})
+		jobFields[types.JobPipelineStatus] = status
+		jobFields[types.JobPipelineOutputFiles] = outputFiles
+		// the version makes sure only one of the concurrent status reports of the runs summarizes the job
+		updated, err := c.jobRepoClient.UpdateVersion(job.Id, jobFields, job.Version)
+		if err == nil {
+			return updated, true, nil
+		}
+		if err != pkg.ErrJobVersionMismatch || attempt == maxSummarizeAttempts {
+			return job, false, fmt.Errorf("could not summarize the pipeline runs for job id %s: %s", job.Id, err.Error())
+		}
+		job, err = c.jobRepoClient.RetrieveById(job.Id)
+		if err != nil {
+			return job, false, err
+		}
+	}
+}
+
+// resendPipelineRuns publishes the events launching the pipelines again for the runs of a fanned out job that are
+// still processing, with the versions of the tasks the runs were launched with
+func (c *Controller) resendPipelineRuns(job types.Job) error {
+	var errs error
+	for _, run := range job.PipelineRuns {
+		if run.Status != pkg.TaskStatusProcessing {
+			continue
+		}
+		task, err := c.persist.GetVersion(run.TaskId, run.TaskVersion)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("could not retrieve task %s for job id %s: %s", run.TaskId, job.Id, err.Error()))
+			continue
 		}
+		err = c.publishEventForTask(job, &task)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// launchNextStage launches the follow-up task of the task that completed the current stage of the job,
+// with the output files of the stage as its input. The completed stage is recorded in the pipeline stages
+// and the pipeline details are reset for the follow-up task.
+// It returns true if the job was handed to the follow-up task, or failed because it could not be launched,
+// and false if the stage is the final one: the task has no follow-up task, the stage failed or it had no output files.
+func (c *Controller) launchNextStage(job types.Job) (bool, error) {
+	// the runs of a fanned out job are not chained
+	if len(job.PipelineRuns) > 0 || job.PipelineDetails.Status != pkg.TaskStatusComplete || len(job.PipelineDetails.OutputFiles) == 0 {
+		return false, nil
+	}
+	// the follow-up task is the one of the version of the task the stage was launched with
+	task, err := c.persist.GetVersion(job.PipelineDetails.TaskId, job.PipelineDetails.TaskVersion)
+	if err != nil {
+		c.lc.Warnf("could not retrieve task %s to check for a follow-up task for job id %s: %s", job.PipelineDetails.TaskId, job.Id, err.Error())
+		return false, nil
+	}
+	if task.NextTaskId == "" {
+		return false, nil
+	}
+
+	nextTask, err := c.persist.GetById(task.NextTaskId)
+	if err == nil && !nextTask.IsEnabled() {
+		err = fmt.Errorf("task is disabled")
+	}
+	if err == nil {
+		for _, taskId := range job.TaskIds() {
+			if taskId == nextTask.Id {
+				err = fmt.Errorf("task already ran for the job")
+			}
+		}
+	}
+	if err != nil {
+		c.lc.Warnf("could not launch follow-up task %s of task %s for job id %s: %s", task.NextTaskId, task.Id, job.Id, err.Error())
+		jobFields := make(map[string]interface{})
+		jobFields[types.JobOwner] = pkg.OwnerNone
+		jobFields[types.JobStatus] = pkg.StatusPipelineError
+		jobFields[types.JobErrorDetailsOwner] = pkg.OwnerTaskLauncher
+		jobFields[types.JobErrorDetailsErrorMsg] = pkg.ErrNextTaskInvalid.Error()
+		_, err = c.jobRepoClient.Update(job.Id, jobFields)
+		if err != nil {
+			return true, fmt.Errorf("could not update job repo to status %s for job id %s: %s", pkg.StatusPipelineError, job.Id, err.Error())
+		}
+		return true, nil
+	}
+
+	stages := make([]types.PipelineInfo, 0, len(job.PipelineStages)+1)
+	stages = append(stages, job.PipelineStages...)
+	stages = append(stages, job.PipelineDetails)
+	jobFields := make(map[string]interface{})
+	jobFields[types.JobPipelineStages] = stages
+	jobFields[types.JobPipelineDetails] = types.PipelineInfo{TaskId: nextTask.Id, TaskVersion: nextTask.Version, Status: pkg.TaskStatusProcessing, OutputFileHost: c.config.FileHostname}
+	job, err = c.jobRepoClient.Update(job.Id, jobFields)
+	if err != nil {
+		return true, fmt.Errorf("could not update job repo for stage %d of job id %s: %s", len(stages)+1, job.Id, err.Error())
 	}
-	return nil, nil
+	c.lc.Debugf("Launching follow-up task %s of task %s for Job with input file %s", nextTask.Id, task.Id, job.FullInputFileLocation())
+	return true, c.dispatchJob(job, &nextTask)
 }
 
 // passJobToSender will take the job object, validate its output file location and determine if handle job should be called
@@ -604,17 +899,45 @@ func (c *Controller) passJobToSender(job types.Job) error {
 	return nil
 }
 
-// publishResultsForJob publishes the pipeline results to the EdgeX Message Bus using to the topic InferenceResults
+// publishResultsForJob publishes the pipeline results to the EdgeX Message Bus on the ResultsTopic of the version
+// of the task the job was launched with, or the default ResultsTopic, as a ResultsEnvelope or as the legacy results
+// string when configured
 func (c *Controller) publishResultsForJob(job types.Job) error {
+	topic := c.config.ResultsTopic
+	if topic == "" {
+		topic = MqttResultsTopic
+	}
+	var pipelineId string
+	task, err := c.persist.GetVersion(job.PipelineDetails.TaskId, job.PipelineDetails.TaskVersion)
+	if err != nil {
+		c.lc.Warnf("could not retrieve task %s for the results of job id %s: %s", job.PipelineDetails.TaskId, job.Id, err.Error())
+	} else {
+		pipelineId = task.PipelineId
+		if task.ResultsTopic != "" {
+			topic = task.ResultsTopic
+		}
+	}
+
+	contentType := common.ContentTypeJSON
+	var msg []byte
+	if c.config.LegacyResultsFormat {
+		contentType = common.ContentTypeText
+		msg = []byte(fmt.Sprintf("filename:%s; results:%s", strings.ReplaceAll(job.FullInputFileLocation(), ":", "="), job.PipelineDetails.Results))
+	} else {
+		msg, err = json.Marshal(types.NewResultsEnvelope(job, pipelineId, time.Now().UTC().UnixNano()))
+		if err != nil {
+			return fmt.Errorf("could not marshal results for job id %s: %s", job.Id, err.Error())
+		}
+	}
+
 	// publish event
-	ctx := c.service.BuildContext(uuid.NewString(), common.ContentTypeText)
-	ctx.AddValue(pkg.PublishTopicKey, MqttResultsTopic)
+	ctx := c.service.BuildContext(uuid.NewString(), contentType)
+	ctx.AddValue(pkg.PublishTopicKey, topic)
 	ctx.AddValue(pkg.CustomTopicKey, CustomTopic)
-	msg := fmt.Sprintf("filename:%s; results:%s", strings.ReplaceAll(job.FullInputFileLocation(), ":", "="), job.PipelineDetails.Results)
-	err := c.publisher.Publish([]byte(msg), ctx)
+	err = c.publisher.Publish(msg, ctx)
 	if err != nil {
 		return fmt.Errorf("could not publish results for job id %s, job input file %s: %s", job.Id, job.FullInputFileLocation(), err.Error())
 	}
-	c.lc.Debugf("Publish Results for job id=%s, input file=%s", job.Id, job.FullInputFileLocation())
+	c.lc.Debugf("Publish Results for job id=%s, input file=%s to %s", job.Id, job.FullInputFileLocation(), topic)
 	return nil
 }
@@ -23,6 +23,8 @@ import (
 	"aicsd/pkg"
 	jobHandlerMocks "aicsd/pkg/clients/job_handler/mocks"
 	jobRepoMocks "aicsd/pkg/clients/job_repo/mocks"
+	"aicsd/pkg/clients/pipeline"
+	pipelineMocks "aicsd/pkg/clients/pipeline/mocks"
 	"aicsd/pkg/helpers"
 	"aicsd/pkg/types"
 
@@ -57,6 +59,10 @@ func TestController_Create(t *testing.T) {
 	invalidWithId := valid
 	invalidWithId.Id = "123"
 
+	invalidSelector := valid
+	invalidSelector.JobSelector = `{ "bogus" : [ { "var" : "Id" }, "1" ] }`
+	invalidSelector.PipelineId = ""
+
 	expectedId := "1"
 
 	tests := []struct {
@@ -69,15 +75,16 @@ func TestController_Create(t *testing.T) {
 	}{
 		{"happy path: new", &valid, expectedId, nil, http.StatusCreated, ""},
 		{"bad request body", &invalidWithId, expectedId, nil, http.StatusBadRequest, taskPkg.ErrDuplicateTaskId.Error()},
+		{"invalid task", &invalidSelector, expectedId, nil, http.StatusBadRequest, `"Fields":{"JobSelector":`},
 		{"unmarshal failed", nil, "", nil, http.StatusBadRequest, pkg.ErrJSONMarshalErr.Error()},
-		{"persist create failed", &types.Task{}, "", taskPkg.ErrTaskCreation, http.StatusInternalServerError, taskPkg.ErrTaskCreation.Error()},
+		{"persist create failed", &valid, "", taskPkg.ErrTaskCreation, http.StatusInternalServerError, taskPkg.ErrTaskCreation.Error()},
 	}
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			var requestBody []byte
 			persistMock := persistMocks.Persistence{}
-			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, &config.Configuration{})
+			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
 
 			if test.Task != nil {
 				requestBody, _ = json.Marshal(test.Task)
@@ -146,7 +153,7 @@ func TestController_Get(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			persistMock := persistMocks.Persistence{}
-			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, &config.Configuration{})
+			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
 
 			persistMock.On("GetAll", mock.Anything).Return(test.ExpectedTasks, test.PersistMockErr)
 
@@ -176,6 +183,87 @@ func TestController_Get(t *testing.T) {
 
 }
 
+func TestController_validateTask(t *testing.T) {
+	valid := types.Task{
+		Description: "Count Cells",
+		JobSelector: `{ "==" : [ { "var" : "InputFile.Extension" }, ".tiff" ] }`,
+		PipelineId:  "only-file",
+	}
+	withPipelineId := func(pipelineId string) types.Task {
+		task := valid
+		task.PipelineId = pipelineId
+		return task
+	}
+	withSelector := func(selector string) types.Task {
+		task := valid
+		task.JobSelector = selector
+		return task
+	}
+	pipelines := []types.Pipeline{{Name: "OnlyFile", SubscriptionTopic: "only-file"}, {Name: "GetiPipeline", SubscriptionTopic: "geti/#"}}
+
+	tests := []struct {
+		Name            string
+		Task            types.Task
+		Update          bool
+		Pipelines       []types.Pipeline
+		PipelinesErr    error
+		ExpectedInvalid map[string]string
+	}{
+		{"happy path", valid, false, pipelines, nil, nil},
+		{"happy path - no pipeline services", withPipelineId("bogus"), false, nil, nil, nil},
+		{"happy path - wildcard topic", withPipelineId("geti/count-cells"), false, pipelines, nil, nil},
+		{"happy path - pipeline services down", withPipelineId("bogus"), false, pipelines, errors.New("connection refused"), nil},
+		{"happy path - partial update", types.Task{Id: "1", Description: "Count Cells"}, true, pipelines, nil, nil},
+		{"missing fields", types.Task{}, false, pipelines, nil,
+			map[string]string{"Description": "required", "JobSelector": "required", "PipelineId": "required"}},
+		{"unknown operator", withSelector(`{ "bogus" : [ 1, 2 ] }`), false, pipelines, nil, map[string]string{"JobSelector": `operator "bogus" is not supported`}},
+		{"malformed selector", withSelector(`{ "==" : [ 1`), true, pipelines, nil, map[string]string{"JobSelector": "unexpected EOF"}},
+		{"selector not a boolean", withSelector(`{ "var" : "Id" }`), false, pipelines, nil, map[string]string{"JobSelector": "could not parse bool"}},
+		{"unknown pipeline", withPipelineId("bogus"), false, pipelines, nil, map[string]string{"PipelineId": `expected one of the pipeline topics ["only-file" "geti/#"]`}},
+		{"wildcard topic mismatch", withPipelineId("getis/count-cells"), false, pipelines, nil, map[string]string{"PipelineId": `got "getis/count-cells"`}},
+		{"happy path - pipeline timeout", types.Task{Id: "1", PipelineTimeout: "30m"}, true, pipelines, nil, nil},
+		{"invalid pipeline timeout", types.Task{Id: "1", PipelineTimeout: "30"}, true, pipelines, nil, map[string]string{"PipelineTimeout": "expected a positive duration"}},
+		{"negative pipeline timeout", types.Task{Id: "1", PipelineTimeout: "-5m"}, true, pipelines, nil, map[string]string{"PipelineTimeout": `got "-5m"`}},
+		{"happy path - results topic", types.Task{Id: "1", ResultsTopic: "lab-a/results"}, true, pipelines, nil, nil},
+		{"happy path - clear fields", types.Task{Id: "1", ClearFields: []string{"NextTaskId", "Priority"}}, true, pipelines, nil, nil},
+		{"unknown clear field", types.Task{Id: "1", ClearFields: []string{"Description"}}, true, pipelines, nil, map[string]string{"ClearFields": `got "Description"`}},
+		{"clear fields on create", types.Task{Description: valid.Description, JobSelector: valid.JobSelector, PipelineId: valid.PipelineId,
+			ClearFields: []string{"NextTaskId"}}, false, pipelines, nil, map[string]string{"ClearFields": "only used by updates"}},
+		{"wildcard results topic", types.Task{Id: "1", ResultsTopic: "lab-a/#"}, true, pipelines, nil, map[string]string{"ResultsTopic": "expected a topic without wildcards"}},
+		{"happy path - templates", types.Task{Id: "1", ResultFileFolder: `/tmp/files/{{.InputFile.Attributes.LabName}}/{{date "2006-01-02"}}`,
+			ModelParameters: map[string]string{"Threshold": "{{.InputFile.Attributes.Threshold}}"}}, true, pipelines, nil, nil},
+		{"invalid templates", types.Task{Id: "1", ResultFileFolder: "/tmp/files/{{.InputFile.Nmae}}",
+			ModelParameters: map[string]string{"Threshold": "{{.InputFile.Attributes.Threshold"}}, true, pipelines, nil,
+			map[string]string{"ResultFileFolder": "can't evaluate field Nmae", "ModelParameters.Threshold": "invalid template"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			var pipelineClients []pipeline.Client
+			if test.Pipelines != nil {
+				pipelineMock := pipelineMocks.Client{}
+				pipelineMock.On("GetPipelines").Return(test.Pipelines, test.PipelinesErr)
+				pipelineClients = append(pipelineClients, &pipelineMock)
+			}
+			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, pipelineClients, nil, nil, &config.Configuration{})
+
+			err := taskRepoController.validateTask(test.Task, test.Update)
+			if test.ExpectedInvalid == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			invalidTaskErr, ok := err.(*taskPkg.InvalidTaskError)
+			require.True(t, ok, "expected an InvalidTaskError")
+			require.Len(t, invalidTaskErr.Fields, len(test.ExpectedInvalid))
+			for name, reason := range test.ExpectedInvalid {
+				require.Contains(t, invalidTaskErr.Fields[name], reason)
+			}
+		})
+	}
+}
+
 func TestTaskRepoController_Update(t *testing.T) {
 	valid := types.Task{
 		Id:               "1",
@@ -186,6 +274,12 @@ func TestTaskRepoController_Update(t *testing.T) {
 		ModelParameters:  map[string]string{},
 		LastUpdated:      time.Now().UTC().UnixNano(),
 	}
+	chained := valid
+	chained.NextTaskId = "2"
+	loop := valid
+	loop.NextTaskId = "3"
+	// task 3 is followed by task 1 so chaining task 1 to it would loop
+	tasks := []types.Task{valid, {Id: "2"}, {Id: "3", NextTaskId: "1"}}
 
 	tests := []struct {
 		Name               string
@@ -196,6 +290,8 @@ func TestTaskRepoController_Update(t *testing.T) {
 		ExpectedErrorMsg   string
 	}{
 		{"happy path", &valid, valid.Id, nil, http.StatusOK, ""},
+		{"happy path - follow-up task", &chained, valid.Id, nil, http.StatusOK, ""},
+		{"follow-up task loop", &loop, valid.Id, nil, http.StatusBadRequest, fmt.Sprintf(taskPkg.ErrFmtTaskChainLoop, valid.Id)},
 		{"update error", &valid, valid.Id, errors.New("update failed"), http.StatusNotFound, "failed to update task for Id"},
 		{"bad json object", nil, valid.Id, nil, http.StatusBadRequest, "failed to unmarshal request (http://localhost) to task: invalid character 'b' looking for beginning of object key string"},
 	}
@@ -204,13 +300,14 @@ func TestTaskRepoController_Update(t *testing.T) {
 		t.Run(test.Name, func(t *testing.T) {
 			var requestBody []byte
 			persistMock := persistMocks.Persistence{}
-			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, &config.Configuration{})
+			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
 			if test.Task != nil {
 				requestBody, _ = json.Marshal(test.Task)
 			} else {
 				requestBody = []byte("{ badJSON }")
 			}
 			persistMock.On("Update", mock.Anything, mock.Anything).Return(test.PersistMockErr)
+			persistMock.On("GetAll").Return(tasks, nil)
 			req := httptest.NewRequest("PUT", "http://localhost", bytes.NewReader(requestBody))
 			w := httptest.NewRecorder()
 			taskRepoController.Update(w, req)
@@ -247,7 +344,7 @@ func TestTaskRepoController_Delete(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			persistMock := persistMocks.Persistence{}
-			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, &config.Configuration{})
+			taskRepoController := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
 
 			persistMock.On("Delete", mock.Anything).Return(test.PersistMockErr)
 
@@ -390,11 +487,13 @@ func TestController_RetryOnStartup(t *testing.T) {
 				ResourceName:      "PipelineParameters",
 				FileHostname:      fileHostname,
 			}
-			taskHandler := New(mockLogger, &persistMock, &repoMock, &senderMock, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+			taskHandler := New(mockLogger, &persistMock, &repoMock, &senderMock, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
 			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
 
 			// set mocks
 			repoMock.On("RetrieveAllByOwner", pkg.OwnerTaskLauncher).Return(test.Jobs, test.RepoRetrieveErr)
+			// the tasks of the jobs have no follow-up task
+			persistMock.On("GetVersion", mock.Anything, mock.Anything).Return(types.Task{Id: "task1"}, nil).Maybe()
 			if taskCompleteOrFailed {
 				if noOutputFile {
 					jobFields := make(map[string]interface{})
@@ -426,6 +525,7 @@ func TestController_RetryOnStartup(t *testing.T) {
 					jobFields := make(map[string]interface{})
 					jobFields[types.JobOwner] = pkg.OwnerTaskLauncher
 					jobFields[types.JobPipelineTaskId] = test.PersistTasks[0].Id
+					jobFields[types.JobPipelineTaskVersion] = test.PersistTasks[0].Version
 					jobFields[types.JobPipelineOutputHost] = fileHostname
 					repoMock.On("Update", mock.Anything, jobFields).Return(types.Job{}, test.RepoUpdateErr2)
 					backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(test.PublisherErr)
@@ -501,8 +601,9 @@ func TestController_retry(t *testing.T) {
 				ResourceName:      "PipelineParameters",
 				FileHostname:      fileHostname,
 			}
-			taskHandler := New(&mockLogger, &persistMock, &repoMock, &senderMock, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, &senderMock, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
 			repoMock.On("RetrieveAllByOwner", pkg.OwnerTaskLauncher).Return(test.Jobs, nil)
+			persistMock.On("GetVersion", mock.Anything, mock.Anything).Return(types.Task{Id: "task1"}, nil).Maybe()
 			if test.Jobs[0].Status == pkg.TaskStatusComplete {
 				senderMock.On("HandleJob", test.Jobs[0]).Return(nil)
 			}
@@ -576,7 +677,7 @@ func TestController_MatchTask(t *testing.T) {
 			// build task handler
 			persistMock := persistMocks.Persistence{}
 			repoMock := jobRepoMocks.Client{}
-			taskHandler := New(logger.MockLogger{}, &persistMock, &repoMock, nil, nil, nil, &config.Configuration{})
+			taskHandler := New(logger.MockLogger{}, &persistMock, &repoMock, nil, nil, nil, nil, &config.Configuration{})
 
 			// build up the request
 			if test.Expected != nil {
@@ -610,6 +711,51 @@ func TestController_MatchTask(t *testing.T) {
 	}
 }
 
+func TestController_matchJobToTasks(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
+	selector := `{ "==" : [ { "var" : "Id" }, "1" ] }`
+	disabled := false
+
+	tests := []struct {
+		Name       string
+		Tasks      []types.Task
+		ExpectedId string
+	}{
+		{"creation order", []types.Task{
+			{Id: "newer", JobSelector: selector, Created: 2},
+			{Id: "older", JobSelector: selector, Created: 1},
+		}, "older"},
+		{"priority before creation order", []types.Task{
+			{Id: "older", JobSelector: selector, Created: 1},
+			{Id: "priority", JobSelector: selector, Created: 2, Priority: 1},
+		}, "priority"},
+		{"disabled task skipped", []types.Task{
+			{Id: "disabled", JobSelector: selector, Created: 1, Priority: 1, Enabled: &disabled},
+			{Id: "enabled", JobSelector: selector, Created: 2},
+		}, "enabled"},
+		{"no enabled task", []types.Task{
+			{Id: "disabled", JobSelector: selector, Enabled: &disabled},
+		}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			taskHandler := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
+			persistMock.On("GetAll").Return(test.Tasks, nil)
+
+			matchedTask, err := taskHandler.matchJobToTasks(job)
+			require.NoError(t, err)
+			if test.ExpectedId == "" {
+				require.Nil(t, matchedTask)
+				return
+			}
+			require.NotNil(t, matchedTask)
+			require.Equal(t, test.ExpectedId, matchedTask.Id)
+		})
+	}
+}
+
 func TestController_HandleNewJob(t *testing.T) {
 	expected := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
 	tasks := []types.Task{
@@ -663,7 +809,7 @@ func TestController_HandleNewJob(t *testing.T) {
 				DeviceName:        "device1",
 				ResourceName:      "PipelineParameters",
 			}
-			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, &backgroundPublisherMock,
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock,
 				&appServiceMock, &taskConfig)
 
 			// build up the request
@@ -710,6 +856,55 @@ func TestController_HandleNewJob(t *testing.T) {
 	}
 }
 
+func TestController_HandleNewJobTemplateError(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	escapingJob := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	escapingJob.InputFile.Attributes = map[string]string{"LabName": "../../etc"}
+
+	tests := []struct {
+		Name             string
+		Job              types.Job
+		ResultFileFolder string
+	}{
+		{"missing attribute", job, "/tmp/files/{{.InputFile.Attributes.Plate}}"},
+		{"result folder outside of its folder", escapingJob, "/tmp/files/output/{{.InputFile.Attributes.LabName}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock, &appServiceMock, &config.Configuration{})
+
+			task := types.Task{Id: "task1", PipelineId: "pipeline1", JobSelector: `{ "==" : [ { "var" : "Id" }, "1" ] }`,
+				ResultFileFolder: test.ResultFileFolder}
+			persistMock.On("GetAll").Return([]types.Task{task}, nil)
+			repoMock.On("Update", test.Job.Id, mock.Anything).Return(test.Job, nil)
+
+			requestBody, err := json.Marshal(test.Job)
+			require.NoError(t, err)
+			req := httptest.NewRequest("POST", "http://localhost", bytes.NewReader(requestBody))
+			w := httptest.NewRecorder()
+			taskHandler.HandleNewJob(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode, "invalid status code")
+			// the job is not published and fails with the template error
+			backgroundPublisherMock.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+			repoMock.AssertCalled(t, "Update", test.Job.Id, map[string]interface{}{
+				types.JobOwner:                pkg.OwnerNone,
+				types.JobStatus:               pkg.StatusPipelineError,
+				types.JobErrorDetailsOwner:    pkg.OwnerTaskLauncher,
+				types.JobErrorDetailsErrorMsg: pkg.ErrTaskTemplate.Error(),
+			})
+		})
+	}
+}
+
 func TestController_PipelineStatus(t *testing.T) {
 	completeJob := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
 
@@ -783,11 +978,12 @@ func TestController_PipelineStatus(t *testing.T) {
 
 			// build task handler
 			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
 			repoMock := jobRepoMocks.Client{}
 			senderMock := jobHandlerMocks.Client{}
 			backgroundPublisherMock := mocks.BackgroundPublisher{}
 			appServiceMock := mocks.ApplicationService{}
-			taskHandler := New(&mockLogger, nil, &repoMock, &senderMock, &backgroundPublisherMock, &appServiceMock, &config.Configuration{FileHostname: fileHostname})
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, &senderMock, nil, &backgroundPublisherMock, &appServiceMock, &config.Configuration{FileHostname: fileHostname})
 
 			// build up the request
 			if test.TaskStatus != "" {
@@ -808,8 +1004,10 @@ func TestController_PipelineStatus(t *testing.T) {
 
 			// set mocks
 			repoMock.On("RetrieveById", mock.Anything).Return(test.Job, test.JobRetrieveErr)
+			// the task of the job has no follow-up task
+			persistMock.On("GetVersion", test.Job.PipelineDetails.TaskId, test.Job.PipelineDetails.TaskVersion).Return(types.Task{Id: test.Job.PipelineDetails.TaskId}, nil).Maybe()
 			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
-			appServiceMock.On("BuildContext", mock.Anything, common.ContentTypeText).Return(ctx)
+			appServiceMock.On("BuildContext", mock.Anything, common.ContentTypeJSON).Return(ctx)
 			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(test.PublisherErr)
 			if len(test.Job.PipelineDetails.OutputFiles) == 0 {
 				jobFields := make(map[string]interface{})
@@ -861,3 +1059,329 @@ func TestController_PipelineStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestController_HandleNewJobFanOut(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
+	tasks := []types.Task{
+		{Id: "task1", PipelineId: "pipeline1", JobSelector: `{ "==" : [ { "var" : "Id" }, "1" ] }`, Version: 2},
+		{Id: "task2", PipelineId: "nomatch", JobSelector: `{ "==" : [ { "var" : "Id" }, "2" ] }`, Version: 1},
+		{Id: "task3", PipelineId: "pipeline3", JobSelector: `{ "in" : [ "test", { "var" : "InputFile.Name" } ] }`, Version: 5},
+	}
+	expectedRuns := []types.PipelineInfo{
+		{TaskId: "task1", TaskVersion: 2, Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+		{TaskId: "task3", TaskVersion: 5, Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+	}
+
+	tests := []struct {
+		Name              string
+		FanOut            bool
+		ExpectedFields    map[string]interface{}
+		ExpectedPublishes int
+	}{
+		{"fan out", true, map[string]interface{}{
+			types.JobOwner:              pkg.OwnerTaskLauncher,
+			types.JobPipelineOutputHost: fileHostname,
+			types.JobPipelineStatus:     pkg.TaskStatusProcessing,
+			types.JobPipelineRuns:       expectedRuns,
+		}, 2},
+		{"fan out disabled", false, map[string]interface{}{
+			types.JobOwner:               pkg.OwnerTaskLauncher,
+			types.JobPipelineOutputHost:  fileHostname,
+			types.JobPipelineStatus:      pkg.TaskStatusProcessing,
+			types.JobPipelineTaskId:      "task1",
+			types.JobPipelineTaskVersion: int64(2),
+		}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskConfig := config.Configuration{
+				DeviceProfileName: "my-profile",
+				DeviceName:        "device1",
+				ResourceName:      "PipelineParameters",
+				FileHostname:      fileHostname,
+				FanOut:            test.FanOut,
+			}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock,
+				&appServiceMock, &taskConfig)
+
+			updated := job
+			updated.Owner = pkg.OwnerTaskLauncher
+			persistMock.On("GetAll").Return(tasks, nil)
+			repoMock.On("Update", job.Id, test.ExpectedFields).Return(updated, nil)
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+			appServiceMock.On("BuildContext", mock.Anything, common.ContentTypeJSON).Return(ctx)
+
+			requestBody, err := json.Marshal(job)
+			require.NoError(t, err)
+			req := httptest.NewRequest("POST", "http://localhost", bytes.NewReader(requestBody))
+			w := httptest.NewRecorder()
+
+			taskHandler.HandleNewJob(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode, "invalid status code")
+			repoMock.AssertExpectations(t)
+			backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", test.ExpectedPublishes)
+		})
+	}
+}
+
+func TestController_PipelineStatusFanOut(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.Version = 5
+	job.PipelineDetails = types.PipelineInfo{Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname}
+	job.PipelineRuns = []types.PipelineInfo{
+		{TaskId: "task1", Status: pkg.TaskStatusComplete, OutputFileHost: fileHostname, Results: "count,5"},
+		{TaskId: "task2", Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+	}
+	allComplete := job
+	allComplete.PipelineRuns = []types.PipelineInfo{job.PipelineRuns[0], {TaskId: "task2", Status: pkg.TaskStatusComplete, OutputFileHost: fileHostname}}
+	oneFailed := job
+	oneFailed.PipelineRuns = []types.PipelineInfo{job.PipelineRuns[0], {TaskId: "task2", Status: pkg.TaskStatusFailed, OutputFileHost: fileHostname}}
+	summarized := allComplete
+	summarized.PipelineDetails.Status = pkg.TaskStatusComplete
+
+	tests := []struct {
+		Name               string
+		TaskId             string
+		TaskStatus         string
+		Job                types.Job
+		SummaryStatus      string
+		SummaryErr         error
+		ExpectedJobStatus  string
+		ExpectedPublishes  int
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"runs still processing", "task1", pkg.TaskStatusComplete, job, "", nil, "", 1, http.StatusOK, ""},
+		{"last run complete", "task2", pkg.TaskStatusComplete, allComplete, pkg.TaskStatusComplete, nil, pkg.StatusComplete, 0, http.StatusOK, ""},
+		{"last run failed", "task2", pkg.TaskStatusFailed, oneFailed, pkg.TaskStatusFailed, nil, pkg.StatusPipelineError, 0, http.StatusOK, ""},
+		{"summarized concurrently", "task2", pkg.TaskStatusComplete, allComplete, pkg.TaskStatusComplete, pkg.ErrJobVersionMismatch, "", 0, http.StatusOK, ""},
+		{"summary update failed", "task2", pkg.TaskStatusComplete, allComplete, pkg.TaskStatusComplete, errors.New("update failed"), "", 0, http.StatusInternalServerError, "could not summarize the pipeline runs"},
+		{"unknown task", "task3", pkg.TaskStatusComplete, job, "", nil, "", 0, http.StatusBadRequest, "task id mismatch"},
+		{"run status mismatch", "task2", pkg.TaskStatusComplete, job, "", nil, "", 0, http.StatusBadRequest, "task status mismatch"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			repoMock := jobRepoMocks.Client{}
+			senderMock := jobHandlerMocks.Client{}
+			persistMock := persistMocks.Persistence{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, &senderMock, nil, &backgroundPublisherMock, &appServiceMock, &config.Configuration{FileHostname: fileHostname})
+
+			persistMock.On("GetVersion", "task1", mock.Anything).Return(types.Task{Id: "task1", PipelineId: "count-cells"}, nil)
+
+			repoMock.On("RetrieveById", job.Id).Return(test.Job, nil).Once()
+			// a concurrent summary is seen when the job is retrieved again
+			repoMock.On("RetrieveById", job.Id).Return(summarized, nil)
+			summaryJob := test.Job
+			summaryJob.PipelineDetails.Status = test.SummaryStatus
+			summaryFields := map[string]interface{}{
+				types.JobPipelineStatus:      test.SummaryStatus,
+				types.JobPipelineOutputFiles: []types.OutputFile{},
+			}
+			repoMock.On("UpdateVersion", job.Id, summaryFields, job.Version).Return(summaryJob, test.SummaryErr)
+			repoMock.On("Update", job.Id, mock.Anything).Return(summaryJob, nil)
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, common.ContentTypeJSON).Return(ctx)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+
+			req := httptest.NewRequest("POST", "http://localhost", bytes.NewReader([]byte(test.TaskStatus)))
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: job.Id, pkg.TaskIdKey: test.TaskId})
+			w := httptest.NewRecorder()
+
+			taskHandler.PipelineStatus(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", test.ExpectedPublishes)
+			if test.ExpectedJobStatus == "" {
+				repoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				return
+			}
+			repoMock.AssertCalled(t, "UpdateVersion", job.Id, summaryFields, job.Version)
+			repoMock.AssertCalled(t, "Update", job.Id, mock.MatchedBy(func(jobFields map[string]interface{}) bool {
+				return jobFields[types.JobStatus] == test.ExpectedJobStatus
+			}))
+		})
+	}
+}
+
+func TestController_PipelineStatusChained(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.PipelineDetails.TaskId = "segment"
+	noOutputJob := job
+	noOutputJob.PipelineDetails.OutputFiles = []types.OutputFile{}
+	finalStageJob := job
+	finalStageJob.PipelineDetails.TaskId = "report"
+	finalStageJob.PipelineStages = []types.PipelineInfo{{TaskId: "classify", Status: pkg.TaskStatusComplete}}
+
+	disabled := false
+	tasks := map[string]types.Task{
+		"segment":  {Id: "segment", PipelineId: "segmentation", NextTaskId: "classify"},
+		"classify": {Id: "classify", PipelineId: "classification", NextTaskId: "report"},
+		"report":   {Id: "report", PipelineId: "report", Enabled: &disabled},
+	}
+
+	tests := []struct {
+		Name               string
+		Job                types.Job
+		NextTasks          map[string]types.Task
+		UpdateErr          error
+		ExpectedStage      bool
+		ExpectedJobStatus  string
+		ExpectedSend       bool
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"launch follow-up task", job, tasks, nil, true, "", false, http.StatusOK, ""},
+		{"final stage", finalStageJob, tasks, nil, false, "", true, http.StatusOK, ""},
+		{"no output files", noOutputJob, tasks, nil, false, pkg.StatusComplete, false, http.StatusOK, ""},
+		{"follow-up task not found", job, map[string]types.Task{"segment": tasks["segment"]}, nil, false, pkg.StatusPipelineError, false, http.StatusOK, ""},
+		{"follow-up task disabled", job, map[string]types.Task{"segment": {Id: "segment", NextTaskId: "report"}, "report": tasks["report"]},
+			nil, false, pkg.StatusPipelineError, false, http.StatusOK, ""},
+		{"follow-up task already ran", finalStageJob, map[string]types.Task{"report": {Id: "report", NextTaskId: "classify"}, "classify": tasks["classify"]},
+			nil, false, pkg.StatusPipelineError, false, http.StatusOK, ""},
+		{"launch update failed", job, tasks, pkg.ErrUpdating, false, "", false, http.StatusInternalServerError, "could not update job repo for stage 2"},
+		{"follow-up task templates not rendered", job, map[string]types.Task{"segment": tasks["segment"],
+			"classify": {Id: "classify", PipelineId: "classification", ResultFileFolder: "/tmp/files/{{.InputFile.Attributes.Plate}}"}},
+			nil, false, pkg.StatusPipelineError, false, http.StatusInternalServerError, pkg.ErrTaskTemplate.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			senderMock := jobHandlerMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, &senderMock, nil, &backgroundPublisherMock, &appServiceMock, &config.Configuration{FileHostname: fileHostname})
+
+			repoMock.On("RetrieveById", job.Id).Return(test.Job, nil)
+			// the follow-up task of the stage is the one of the version the stage was launched with
+			for id, task := range test.NextTasks {
+				persistMock.On("GetById", id).Return(task, nil)
+				persistMock.On("GetVersion", id, test.Job.PipelineDetails.TaskVersion).Return(task, nil)
+			}
+			persistMock.On("GetById", mock.Anything).Return(types.Task{}, errors.New("not found"))
+			persistMock.On("GetVersion", mock.Anything, mock.Anything).Return(types.Task{}, errors.New("not found"))
+			repoMock.On("Update", job.Id, mock.Anything).Return(test.Job, test.UpdateErr)
+			senderMock.On("HandleJob", mock.Anything).Return(nil)
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, mock.Anything).Return(ctx)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+
+			req := httptest.NewRequest("POST", "http://localhost", bytes.NewReader([]byte(pkg.TaskStatusComplete)))
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: job.Id, pkg.TaskIdKey: test.Job.PipelineDetails.TaskId})
+			w := httptest.NewRecorder()
+
+			taskHandler.PipelineStatus(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			if test.ExpectedJobStatus != "" {
+				repoMock.AssertCalled(t, "Update", job.Id, mock.MatchedBy(func(jobFields map[string]interface{}) bool {
+					return jobFields[types.JobStatus] == test.ExpectedJobStatus
+				}))
+			}
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			if test.ExpectedStage {
+				repoMock.AssertCalled(t, "Update", job.Id, map[string]interface{}{
+					types.JobPipelineStages:  []types.PipelineInfo{test.Job.PipelineDetails},
+					types.JobPipelineDetails: types.PipelineInfo{TaskId: "classify", Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+				})
+				// the event launching the follow-up task is published, but not the results of the stage
+				backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", 1)
+				appServiceMock.AssertCalled(t, "BuildContext", mock.Anything, common.ContentTypeJSON)
+			}
+			if test.ExpectedSend {
+				senderMock.AssertCalled(t, "HandleJob", mock.Anything)
+			} else {
+				senderMock.AssertNotCalled(t, "HandleJob", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestController_publishResultsForJob(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.PipelineDetails.TaskId = "count"
+	job.PipelineDetails.TaskVersion = 2
+
+	tests := []struct {
+		Name                string
+		Task                types.Task
+		TaskErr             error
+		LegacyResultsFormat bool
+		ExpectedTopic       string
+		ExpectedContentType string
+		ExpectedPipelineId  string
+	}{
+		{"default topic", types.Task{Id: "count", PipelineId: "count-cells"}, nil, false, "results/default", common.ContentTypeJSON, "count-cells"},
+		{"task topic", types.Task{Id: "count", PipelineId: "count-cells", ResultsTopic: "lab-a/results"}, nil, false, "lab-a/results", common.ContentTypeJSON, "count-cells"},
+		{"task not found", types.Task{}, errors.New("not found"), false, "results/default", common.ContentTypeJSON, ""},
+		{"legacy format", types.Task{Id: "count", PipelineId: "count-cells", ResultsTopic: "lab-a/results"}, nil, true, "lab-a/results", common.ContentTypeText, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskConfig := config.Configuration{ResultsTopic: "results/default", LegacyResultsFormat: test.LegacyResultsFormat}
+			taskHandler := New(&mockLogger, &persistMock, nil, nil, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+
+			persistMock.On("GetVersion", "count", int64(2)).Return(test.Task, test.TaskErr)
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, test.ExpectedContentType).Return(ctx)
+			var published []byte
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Run(func(args mock.Arguments) {
+				published = args.Get(0).([]byte)
+			}).Return(nil)
+
+			err := taskHandler.publishResultsForJob(job)
+			require.NoError(t, err)
+			topic, _ := ctx.GetValue(pkg.PublishTopicKey)
+			require.Equal(t, test.ExpectedTopic, topic)
+			if test.LegacyResultsFormat {
+				require.Equal(t, "filename:"+fileHostname+"=test/test-image.tiff; results:count,3", string(published))
+				return
+			}
+			var envelope types.ResultsEnvelope
+			require.NoError(t, json.Unmarshal(published, &envelope))
+			require.Equal(t, types.ResultsEnvelopeVersion, envelope.Version)
+			require.Equal(t, job.Id, envelope.JobId)
+			require.Equal(t, "count", envelope.TaskId)
+			require.Equal(t, test.ExpectedPipelineId, envelope.PipelineId)
+			require.Equal(t, job.InputFile, envelope.InputFile)
+			require.Equal(t, map[string]interface{}{"count": "3"}, envelope.ParsedResults)
+			require.Equal(t, []string{"outfile1.tiff"}, envelope.OutputFiles)
+		})
+	}
+}
@@ -0,0 +1,306 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// QueueDepth is the number of jobs in flight on a pipeline and waiting in the dispatch queue for it
+type QueueDepth struct {
+	PipelineId string
+	// MaxInFlight is the number of jobs that may be in flight on the pipeline at once, zero if it is not limited
+	MaxInFlight int
+	InFlight    int
+	Queued      int
+}
+
+// dispatchLimited reports whether the number of jobs in flight is limited for any pipeline
+func (c *Controller) dispatchLimited() bool {
+	return c.config.MaxInFlight > 0 || len(c.config.PipelineMaxInFlight) > 0
+}
+
+// dispatchJob publishes the job to the pipeline of the task when the pipeline has a free slot,
+// and queues it otherwise until a job in flight on the pipeline reports its status
+func (c *Controller) dispatchJob(job types.Job, task *types.Task) error {
+	maxInFlight := c.config.MaxInFlightFor(task.PipelineId)
+	if maxInFlight == 0 {
+		err := c.publishEventForTask(job, task)
+		if err != nil {
+			return c.failLaunch(job, err)
+		}
+		return nil
+	}
+
+	c.dispatchMutex.Lock()
+	defer c.dispatchMutex.Unlock()
+
+	dispatches, err := c.persist.GetDispatches()
+	if err != nil {
+		return err
+	}
+	dispatch := types.Dispatch{JobId: job.Id, TaskId: task.Id, PipelineId: task.PipelineId, Queued: time.Now().UTC().UnixNano()}
+	inFlight, queued := countDispatches(dispatches, task.PipelineId)
+	// the jobs already waiting go first
+	if inFlight >= maxInFlight || queued > 0 {
+		err = c.persist.PutDispatch(dispatch)
+		if err != nil {
+			return err
+		}
+		c.lc.Debugf("Queued Job with input file %s for pipeline %s with %d jobs in flight and %d queued",
+			job.FullInputFileLocation(), task.PipelineId, inFlight, queued)
+		return nil
+	}
+
+	dispatch.InFlight = true
+	err = c.persist.PutDispatch(dispatch)
+	if err != nil {
+		return err
+	}
+	err = c.publishEventForTask(job, task)
+	if err != nil {
+		// the job never reached the pipeline, so it does not hold the slot
+		deleteErr := c.persist.DeleteDispatch(job.Id, task.Id)
+		err = c.failLaunch(job, err)
+		if deleteErr != nil {
+			err = multierror.Append(err, deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// failLaunch sets the job to a pipeline error after the event launching its pipeline could not be published,
+// and returns the launch error. The error details tell whether the templates of the task could not be rendered.
+func (c *Controller) failLaunch(job types.Job, launchErr error) error {
+	jobFields := make(map[string]interface{})
+	jobFields[types.JobOwner] = pkg.OwnerNone
+	jobFields[types.JobStatus] = pkg.StatusPipelineError
+	jobFields[types.JobErrorDetailsOwner] = pkg.OwnerTaskLauncher
+	jobFields[types.JobErrorDetailsErrorMsg] = pkg.ErrPipelineLaunch.Error()
+	if werrors.Context(launchErr) == pkg.ErrTaskTemplate {
+		jobFields[types.JobErrorDetailsErrorMsg] = pkg.ErrTaskTemplate.Error()
+	}
+	_, err := c.jobRepoClient.Update(job.Id, jobFields)
+	if err != nil {
+		return multierror.Append(launchErr, fmt.Errorf("could not update job repo to status %s for job id %s: %s", pkg.StatusPipelineError, job.Id, err.Error()))
+	}
+	return launchErr
+}
+
+// releaseDispatch frees the slot of the job in flight on the pipeline of the task and publishes the next
+// queued jobs of the pipeline
+func (c *Controller) releaseDispatch(jobId string, taskId string) error {
+	if !c.dispatchLimited() {
+		return nil
+	}
+
+	c.dispatchMutex.Lock()
+	defer c.dispatchMutex.Unlock()
+
+	dispatches, err := c.persist.GetDispatches()
+	if err != nil {
+		return err
+	}
+	key := types.DispatchKey(jobId, taskId)
+	for k, dispatch := range dispatches {
+		if dispatch.Key() != key {
+			continue
+		}
+		err = c.persist.DeleteDispatch(jobId, taskId)
+		if err != nil {
+			return err
+		}
+		dispatches = append(dispatches[:k], dispatches[k+1:]...)
+		return c.releaseQueued(dispatch.PipelineId, dispatches)
+	}
+	return nil
+}
+
+// DispatchQueued publishes the queued jobs of every pipeline that has a free slot,
+// e.g. after the limits were raised or the service was restarted
+func (c *Controller) DispatchQueued() error {
+	c.dispatchMutex.Lock()
+	defer c.dispatchMutex.Unlock()
+
+	dispatches, err := c.persist.GetDispatches()
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, pipelineId := range dispatchPipelineIds(dispatches) {
+		err = c.releaseQueued(pipelineId, dispatches)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// releaseQueued publishes the queued jobs of the pipeline, in the order they were queued, while it has a free slot.
+// It must be called with the dispatch mutex held.
+func (c *Controller) releaseQueued(pipelineId string, dispatches []types.Dispatch) error {
+	maxInFlight := c.config.MaxInFlightFor(pipelineId)
+	inFlight, _ := countDispatches(dispatches, pipelineId)
+	var queued []types.Dispatch
+	for _, dispatch := range dispatches {
+		if dispatch.PipelineId == pipelineId && !dispatch.InFlight {
+			queued = append(queued, dispatch)
+		}
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		return queued[i].Queued < queued[j].Queued
+	})
+
+	var errs error
+	for _, dispatch := range queued {
+		if maxInFlight > 0 && inFlight >= maxInFlight {
+			break
+		}
+		released, err := c.publishQueued(dispatch)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		if released {
+			inFlight++
+		}
+	}
+	return errs
+}
+
+// publishQueued publishes a queued job to the pipeline of the version of its task it was launched with, and returns
+// true if the job is in flight.
+// The job is dropped from the queue if it is no longer processed by the task launcher or the task was deleted,
+// and fails if its pipeline could not be launched.
+func (c *Controller) publishQueued(dispatch types.Dispatch) (bool, error) {
+	job, err := c.jobRepoClient.RetrieveById(dispatch.JobId)
+	if err == nil && job.Owner != pkg.OwnerTaskLauncher {
+		err = fmt.Errorf("job is owned by %s", job.Owner)
+	}
+	var task types.Task
+	if err == nil {
+		task, err = c.persist.GetVersion(dispatch.TaskId, job.TaskVersion(dispatch.TaskId))
+	}
+	if err != nil {
+		c.lc.Warnf("dropping queued job id %s for task %s: %s", dispatch.JobId, dispatch.TaskId, err.Error())
+		return false, c.persist.DeleteDispatch(dispatch.JobId, dispatch.TaskId)
+	}
+
+	dispatch.InFlight = true
+	err = c.persist.PutDispatch(dispatch)
+	if err != nil {
+		return false, err
+	}
+	// the update restarts the pipeline timeout of the job, which was waiting in the queue
+	jobFields := make(map[string]interface{})
+	jobFields[types.JobPipelineStatus] = pkg.TaskStatusProcessing
+	job, err = c.jobRepoClient.Update(job.Id, jobFields)
+	if err != nil {
+		return true, fmt.Errorf("could not update job repo for queued job id %s: %s", dispatch.JobId, err.Error())
+	}
+	c.lc.Debugf("Releasing queued Job with input file %s to pipeline %s", job.FullInputFileLocation(), dispatch.PipelineId)
+	err = c.publishEventForTask(job, &task)
+	if err != nil {
+		// the slot is freed for the next queued job
+		deleteErr := c.persist.DeleteDispatch(dispatch.JobId, dispatch.TaskId)
+		if deleteErr != nil {
+			return true, multierror.Append(err, deleteErr)
+		}
+		return false, c.failLaunch(job, err)
+	}
+	return true, nil
+}
+
+// queuedJobIds returns the ids of the jobs waiting in the dispatch queue, which are not resent to their pipeline
+func (c *Controller) queuedJobIds() (map[string]bool, error) {
+	queued := make(map[string]bool)
+	if !c.dispatchLimited() {
+		return queued, nil
+	}
+	dispatches, err := c.persist.GetDispatches()
+	if err != nil {
+		return nil, err
+	}
+	for _, dispatch := range dispatches {
+		if !dispatch.InFlight {
+			queued[dispatch.JobId] = true
+		}
+	}
+	return queued, nil
+}
+
+// GetQueue is a request to retrieve the number of jobs in flight and queued for each pipeline
+func (c *Controller) GetQueue(writer http.ResponseWriter, request *http.Request) {
+	dispatches, err := c.persist.GetDispatches()
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, taskPkg.ErrDispatchRetrieval), http.StatusInternalServerError)
+		return
+	}
+
+	pipelineIds := dispatchPipelineIds(dispatches)
+	for pipelineId := range c.config.PipelineMaxInFlight {
+		pipelineIds = append(pipelineIds, pipelineId)
+	}
+	sort.Strings(pipelineIds)
+
+	depths := []QueueDepth{}
+	for k, pipelineId := range pipelineIds {
+		if k > 0 && pipelineIds[k-1] == pipelineId {
+			continue
+		}
+		inFlight, queued := countDispatches(dispatches, pipelineId)
+		depths = append(depths, QueueDepth{
+			PipelineId:  pipelineId,
+			MaxInFlight: c.config.MaxInFlightFor(pipelineId),
+			InFlight:    inFlight,
+			Queued:      queued,
+		})
+	}
+
+	c.writeTaskJson(writer, depths)
+}
+
+// countDispatches returns the number of jobs in flight and queued for the pipeline
+func countDispatches(dispatches []types.Dispatch, pipelineId string) (int, int) {
+	inFlight, queued := 0, 0
+	for _, dispatch := range dispatches {
+		if dispatch.PipelineId != pipelineId {
+			continue
+		}
+		if dispatch.InFlight {
+			inFlight++
+		} else {
+			queued++
+		}
+	}
+	return inFlight, queued
+}
+
+// dispatchPipelineIds returns the sorted ids of the pipelines of the dispatches
+func dispatchPipelineIds(dispatches []types.Dispatch) []string {
+	unique := make(map[string]bool)
+	for _, dispatch := range dispatches {
+		unique[dispatch.PipelineId] = true
+	}
+	pipelineIds := make([]string, 0, len(unique))
+	for pipelineId := range unique {
+		pipelineIds = append(pipelineIds, pipelineId)
+	}
+	sort.Strings(pipelineIds)
+	return pipelineIds
+}
@@ -0,0 +1,276 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aicsd/as-task-launcher/config"
+	persistMocks "aicsd/as-task-launcher/persist/mocks"
+	"aicsd/pkg"
+	jobRepoMocks "aicsd/pkg/clients/job_repo/mocks"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	appsdk "github.com/edgexfoundry/app-functions-sdk-go/v2/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// launchFailedFields are the job fields of a job whose pipeline could not be launched
+var launchFailedFields = map[string]interface{}{
+	types.JobOwner:                pkg.OwnerNone,
+	types.JobStatus:               pkg.StatusPipelineError,
+	types.JobErrorDetailsOwner:    pkg.OwnerTaskLauncher,
+	types.JobErrorDetailsErrorMsg: pkg.ErrPipelineLaunch.Error(),
+}
+
+func TestController_DispatchJob(t *testing.T) {
+	inFlight := types.Dispatch{JobId: "2", TaskId: "count", PipelineId: "count-cells", Queued: 1, InFlight: true}
+	queued := types.Dispatch{JobId: "3", TaskId: "count", PipelineId: "count-cells", Queued: 2}
+	otherPipeline := types.Dispatch{JobId: "4", TaskId: "measure", PipelineId: "measure", Queued: 1, InFlight: true}
+
+	tests := []struct {
+		Name              string
+		MaxInFlight       int
+		Dispatches        []types.Dispatch
+		GetErr            error
+		PublishErr        error
+		ExpectedInFlight  bool
+		ExpectedPut       bool
+		ExpectedPublishes int
+		ExpectedFailed    bool
+		ExpectedErrorMsg  string
+	}{
+		{"not limited", 0, nil, nil, nil, false, false, 1, false, ""},
+		{"free slot", 2, []types.Dispatch{inFlight, otherPipeline}, nil, nil, true, true, 1, false, ""},
+		{"pipeline full", 1, []types.Dispatch{inFlight, otherPipeline}, nil, nil, false, true, 0, false, ""},
+		{"jobs already queued", 2, []types.Dispatch{inFlight, queued}, nil, nil, false, true, 0, false, ""},
+		{"get dispatches failed", 1, nil, errors.New("retrieval failed"), nil, false, false, 0, false, "retrieval failed"},
+		{"publish failed", 2, []types.Dispatch{inFlight, otherPipeline}, nil, errors.New("publish failed"), true, true, 1, true, "publish failed"},
+		{"publish failed not limited", 0, nil, nil, errors.New("publish failed"), false, false, 1, true, "publish failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskConfig := config.Configuration{FileHostname: fileHostname, PipelineMaxInFlight: map[string]int{"count-cells": test.MaxInFlight}}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+
+			job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+			persistMock.On("GetDispatches").Return(test.Dispatches, test.GetErr)
+			persistMock.On("PutDispatch", mock.Anything).Return(nil)
+			persistMock.On("DeleteDispatch", job.Id, "count").Return(nil)
+			repoMock.On("Update", job.Id, mock.Anything).Return(job, nil)
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, mock.Anything).Return(ctx)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(test.PublishErr)
+
+			err := taskHandler.dispatchJob(job, &types.Task{Id: "count", PipelineId: "count-cells"})
+			if test.ExpectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			if test.ExpectedPut {
+				persistMock.AssertCalled(t, "PutDispatch", mock.MatchedBy(func(dispatch types.Dispatch) bool {
+					return dispatch.Key() == types.DispatchKey(job.Id, "count") && dispatch.PipelineId == "count-cells" &&
+						dispatch.InFlight == test.ExpectedInFlight
+				}))
+			} else {
+				persistMock.AssertNotCalled(t, "PutDispatch", mock.Anything)
+			}
+			backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", test.ExpectedPublishes)
+			if test.ExpectedFailed {
+				// the slot of the job is freed and the job fails
+				if test.ExpectedPut {
+					persistMock.AssertCalled(t, "DeleteDispatch", job.Id, "count")
+				}
+				repoMock.AssertCalled(t, "Update", job.Id, launchFailedFields)
+			} else {
+				persistMock.AssertNotCalled(t, "DeleteDispatch", mock.Anything, mock.Anything)
+				repoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestController_ReleaseDispatch(t *testing.T) {
+	released := types.Dispatch{JobId: "1", TaskId: "count", PipelineId: "count-cells", Queued: 1, InFlight: true}
+	first := types.Dispatch{JobId: "2", TaskId: "count", PipelineId: "count-cells", Queued: 2}
+	second := types.Dispatch{JobId: "3", TaskId: "count", PipelineId: "count-cells", Queued: 3}
+	dropped := types.Dispatch{JobId: "4", TaskId: "count", PipelineId: "count-cells", Queued: 2}
+
+	tests := []struct {
+		Name             string
+		MaxInFlight      int
+		Dispatches       []types.Dispatch
+		ExpectedReleased []string
+		ExpectedDropped  []string
+	}{
+		{"next queued job released", 1, []types.Dispatch{second, released, first}, []string{"2"}, nil},
+		{"queued jobs released up to the limit", 2, []types.Dispatch{second, released, first}, []string{"2", "3"}, nil},
+		{"job no longer processed dropped", 1, []types.Dispatch{second, released, dropped}, []string{"3"}, []string{"4"}},
+		{"nothing queued", 1, []types.Dispatch{released}, nil, nil},
+		{"not in flight", 1, []types.Dispatch{first}, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskConfig := config.Configuration{FileHostname: fileHostname, MaxInFlight: test.MaxInFlight}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+
+			persistMock.On("GetDispatches").Return(test.Dispatches, nil)
+			persistMock.On("PutDispatch", mock.Anything).Return(nil)
+			persistMock.On("DeleteDispatch", mock.Anything, mock.Anything).Return(nil)
+			// the queued jobs are released with the version of the task they were launched with
+			persistMock.On("GetVersion", "count", int64(3)).Return(types.Task{Id: "count", PipelineId: "count-cells", Version: 3}, nil)
+			for _, jobId := range []string{"2", "3", "4"} {
+				job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+				job.Id = jobId
+				job.PipelineDetails.TaskId = "count"
+				job.PipelineDetails.TaskVersion = 3
+				if jobId == "4" {
+					job.Owner = pkg.OwnerNone
+				}
+				repoMock.On("RetrieveById", jobId).Return(job, nil)
+				repoMock.On("Update", jobId, mock.Anything).Return(job, nil)
+			}
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, mock.Anything).Return(ctx)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+
+			err := taskHandler.releaseDispatch("1", "count")
+			require.NoError(t, err)
+
+			for _, jobId := range test.ExpectedReleased {
+				persistMock.AssertCalled(t, "PutDispatch", mock.MatchedBy(func(dispatch types.Dispatch) bool {
+					return dispatch.JobId == jobId && dispatch.InFlight
+				}))
+				repoMock.AssertCalled(t, "Update", jobId, map[string]interface{}{types.JobPipelineStatus: pkg.TaskStatusProcessing})
+			}
+			for _, jobId := range test.ExpectedDropped {
+				persistMock.AssertCalled(t, "DeleteDispatch", jobId, "count")
+			}
+			backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", len(test.ExpectedReleased))
+			if len(test.ExpectedReleased) < 2 {
+				repoMock.AssertNumberOfCalls(t, "Update", len(test.ExpectedReleased))
+			}
+		})
+	}
+}
+
+func TestController_ReleaseDispatchPublishFailed(t *testing.T) {
+	released := types.Dispatch{JobId: "1", TaskId: "count", PipelineId: "count-cells", Queued: 1, InFlight: true}
+	first := types.Dispatch{JobId: "2", TaskId: "count", PipelineId: "count-cells", Queued: 2}
+	second := types.Dispatch{JobId: "3", TaskId: "count", PipelineId: "count-cells", Queued: 3}
+
+	mockLogger := logger.MockLogger{}
+	persistMock := persistMocks.Persistence{}
+	repoMock := jobRepoMocks.Client{}
+	backgroundPublisherMock := mocks.BackgroundPublisher{}
+	appServiceMock := mocks.ApplicationService{}
+	taskConfig := config.Configuration{FileHostname: fileHostname, MaxInFlight: 1}
+	taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+
+	persistMock.On("GetDispatches").Return([]types.Dispatch{released, first, second}, nil)
+	persistMock.On("PutDispatch", mock.Anything).Return(nil)
+	persistMock.On("DeleteDispatch", mock.Anything, mock.Anything).Return(nil)
+	persistMock.On("GetVersion", "count", int64(3)).Return(types.Task{Id: "count", PipelineId: "count-cells", Version: 3}, nil)
+	for _, jobId := range []string{"2", "3"} {
+		job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+		job.Id = jobId
+		job.PipelineDetails.TaskId = "count"
+		job.PipelineDetails.TaskVersion = 3
+		repoMock.On("RetrieveById", jobId).Return(job, nil)
+		repoMock.On("Update", jobId, mock.Anything).Return(job, nil)
+	}
+	ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+	appServiceMock.On("BuildContext", mock.Anything, mock.Anything).Return(ctx)
+	// the first queued job cannot be published
+	backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(errors.New("publish failed")).Once()
+	backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+
+	err := taskHandler.releaseDispatch("1", "count")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "publish failed")
+
+	// the failed job gives its slot to the next queued job
+	persistMock.AssertCalled(t, "DeleteDispatch", "2", "count")
+	repoMock.AssertCalled(t, "Update", "2", launchFailedFields)
+	persistMock.AssertCalled(t, "PutDispatch", mock.MatchedBy(func(dispatch types.Dispatch) bool {
+		return dispatch.JobId == "3" && dispatch.InFlight
+	}))
+	persistMock.AssertNotCalled(t, "DeleteDispatch", "3", "count")
+	backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", 2)
+}
+
+func TestController_GetQueue(t *testing.T) {
+	dispatches := []types.Dispatch{
+		{JobId: "1", TaskId: "count", PipelineId: "count-cells", InFlight: true},
+		{JobId: "2", TaskId: "count", PipelineId: "count-cells"},
+		{JobId: "3", TaskId: "count", PipelineId: "count-cells"},
+		{JobId: "1", TaskId: "measure", PipelineId: "measure", InFlight: true},
+	}
+
+	tests := []struct {
+		Name               string
+		PersistErr         error
+		ExpectedStatusCode int
+		ExpectedDepths     []QueueDepth
+	}{
+		{"happy path", nil, http.StatusOK, []QueueDepth{
+			{PipelineId: "count-cells", MaxInFlight: 1, InFlight: 1, Queued: 2},
+			{PipelineId: "idle", MaxInFlight: 4},
+			{PipelineId: "measure", MaxInFlight: 2, InFlight: 1},
+		}},
+		{"retrieval failed", errors.New("retrieval failed"), http.StatusInternalServerError, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("GetDispatches").Return(dispatches, test.PersistErr)
+			taskConfig := config.Configuration{MaxInFlight: 2, PipelineMaxInFlight: map[string]int{"count-cells": 1, "idle": 4}}
+			taskHandler := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &taskConfig)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			w := httptest.NewRecorder()
+			taskHandler.GetQueue(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			var depths []QueueDepth
+			require.NoError(t, json.Unmarshal(body, &depths))
+			assert.Equal(t, test.ExpectedDepths, depths)
+		})
+	}
+}
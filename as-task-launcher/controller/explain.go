@@ -0,0 +1,157 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// MatchExplanation reports how a job is matched to the tasks
+type MatchExplanation struct {
+	// Attributes are the attributes of the input file of the job the selectors were evaluated with
+	Attributes map[string]string
+	// Tasks are the results of the tasks in matching order
+	Tasks []TaskMatch
+	// MatchedTaskId is the id of the winning task, the first enabled task that matched, or empty if there is none
+	MatchedTaskId string
+	// LaunchedTaskIds are the ids of the tasks the job would be launched for, all the matching tasks with fan out
+	LaunchedTaskIds []string
+	// MatchError is the error the task matching fails with when a selector that is evaluated for the job
+	// can not be evaluated, in which case the job is not launched
+	MatchError string `json:",omitempty"`
+}
+
+// TaskMatch is the result of evaluating the JobSelector of a task against the job
+type TaskMatch struct {
+	TaskId      string
+	Description string
+	PipelineId  string
+	Priority    int
+	Enabled     bool
+	// Matched is true if the task is enabled and its JobSelector evaluated to true
+	Matched bool
+	// Error is the reason the JobSelector could not be evaluated
+	Error string `json:",omitempty"`
+}
+
+// matchExplainRequest is either a job or the name of an input file
+type matchExplainRequest struct {
+	types.Job
+	// Filename is the path of an input file to build the job from instead of passing the job
+	Filename string
+}
+
+// MatchTaskExplain is a dry run of the task matching for debugging the task selectors. It takes a job or the name of
+// an input file, parses the attributes of the input file name like the data organizer and reports for every task
+// whether its JobSelector matched, along with the evaluated attributes and the winning task.
+func (c *Controller) MatchTaskExplain(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, pkg.ErrFmtProcessingReq, request.URL.String()), http.StatusInternalServerError)
+		return
+	}
+	var explainRequest matchExplainRequest
+	err = json.Unmarshal(body, &explainRequest)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("failed to unmarshal request (%s): %s", request.URL.String(), err.Error()),
+			http.StatusBadRequest)
+		return
+	}
+	job := explainRequest.Job
+	if explainRequest.Filename != "" {
+		// a new file is notified by the file watcher to the data organizer, which owns the job while it is matched
+		job = types.Job{
+			Owner: pkg.OwnerDataOrg,
+			InputFile: types.FileInfo{
+				DirName:   filepath.Dir(explainRequest.Filename),
+				Name:      filepath.Base(explainRequest.Filename),
+				Extension: filepath.Ext(explainRequest.Filename),
+			},
+			Status: pkg.StatusIncomplete,
+		}
+	}
+	if job.InputFile.Name == "" {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("either a job with an input file name or a filename is required"),
+			http.StatusBadRequest)
+		return
+	}
+	if job.InputFile.Attributes == nil {
+		job.InputFile.Attributes = make(map[string]string)
+	}
+	err = job.InputFile.ParseFilenameForAttributes(c.config.FilenameDecoder.AttributeParser)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "could not parse the attributes of %s", job.InputFile.Name),
+			http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := c.explainMatch(job)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusInternalServerError)
+		return
+	}
+	jsonRsp, err := json.Marshal(explanation)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapErr(err, pkg.ErrJSONMarshalErr), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// explainMatch evaluates the JobSelector of every enabled task against the job in matching order, like matchingTasks,
+// but carries on past the winning task and the tasks whose selector can not be evaluated so that every task is reported
+func (c *Controller) explainMatch(job types.Job) (MatchExplanation, error) {
+	tasks, err := c.persist.GetAll()
+	if err != nil {
+		return MatchExplanation{}, fmt.Errorf("could not retrieve tasks: %s", err.Error())
+	}
+
+	types.SortTasks(tasks)
+	explanation := MatchExplanation{Attributes: job.InputFile.Attributes, Tasks: make([]TaskMatch, 0, len(tasks))}
+	for _, task := range tasks {
+		taskMatch := TaskMatch{
+			TaskId:      task.Id,
+			Description: task.Description,
+			PipelineId:  task.PipelineId,
+			Priority:    task.Priority,
+			Enabled:     task.IsEnabled(),
+		}
+		// the matching evaluates the tasks until the winning task is found, or all of them with fan out
+		evaluated := explanation.MatchError == "" && (explanation.MatchedTaskId == "" || c.config.FanOut)
+		if taskMatch.Enabled {
+			taskMatch.Matched, err = helpers.ApplyJsonLogicToJob(job, task.JobSelector)
+			if err != nil {
+				taskMatch.Error = err.Error()
+				if evaluated {
+					explanation.MatchError = fmt.Sprintf("could not apply json logic for task id %s: %s", task.Id, err.Error())
+					explanation.MatchedTaskId = ""
+					explanation.LaunchedTaskIds = nil
+				}
+			}
+		}
+		if taskMatch.Matched && evaluated {
+			if explanation.MatchedTaskId == "" {
+				explanation.MatchedTaskId = task.Id
+			}
+			explanation.LaunchedTaskIds = append(explanation.LaunchedTaskIds, task.Id)
+		}
+		explanation.Tasks = append(explanation.Tasks, taskMatch)
+	}
+	return explanation, nil
+}
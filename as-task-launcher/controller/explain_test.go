@@ -0,0 +1,112 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aicsd/as-task-launcher/config"
+	persistMocks "aicsd/as-task-launcher/persist/mocks"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_MatchTaskExplain(t *testing.T) {
+	disabled := false
+	plateTask := types.Task{Id: "plate", JobSelector: `{ "==" : [ { "var" : "InputFile.Attributes.Plate" }, "12" ] }`, PipelineId: "count", Priority: 1}
+	tiffTask := types.Task{Id: "tiff", JobSelector: `{ "==" : [ { "var" : "InputFile.Extension" }, ".tiff" ] }`, PipelineId: "measure"}
+	disabledTask := types.Task{Id: "disabled", JobSelector: `true`, PipelineId: "measure", Priority: 2, Enabled: &disabled}
+	badTask := types.Task{Id: "bad", JobSelector: `{ "bogus" : [ 1, 2 ] }`, PipelineId: "measure", Priority: -1}
+	tasks := []types.Task{tiffTask, plateTask, disabledTask}
+	parser := map[string]types.AttributeInfo{"Plate": {Id: "p", DataType: "int"}}
+
+	job := types.Job{Id: "1", InputFile: types.FileInfo{DirName: "/tmp/files/input", Name: "scan-p7.tiff", Extension: ".tiff",
+		Attributes: map[string]string{"LabName": "lab"}}}
+	jobBody, err := json.Marshal(job)
+	require.NoError(t, err)
+	filenameBody := `{"Filename": "/tmp/files/input/scan-p12.tiff"}`
+
+	tests := []struct {
+		Name               string
+		Body               string
+		Tasks              []types.Task
+		GetAllErr          error
+		FanOut             bool
+		ExpectedAttributes map[string]string
+		ExpectedMatched    []string
+		ExpectedWinner     string
+		ExpectedLaunched   []string
+		ExpectedMatchError string
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path - filename", filenameBody, tasks, nil, false, map[string]string{"Plate": "12"},
+			[]string{"plate", "tiff"}, "plate", []string{"plate"}, "", http.StatusOK, ""},
+		{"happy path - job", string(jobBody), tasks, nil, false, map[string]string{"LabName": "lab", "Plate": "7"},
+			[]string{"tiff"}, "tiff", []string{"tiff"}, "", http.StatusOK, ""},
+		{"happy path - fan out", filenameBody, tasks, nil, true, map[string]string{"Plate": "12"},
+			[]string{"plate", "tiff"}, "plate", []string{"plate", "tiff"}, "", http.StatusOK, ""},
+		{"happy path - invalid selector after the winner", filenameBody, append(tasks, badTask), nil, false, map[string]string{"Plate": "12"},
+			[]string{"plate", "tiff"}, "plate", []string{"plate"}, "", http.StatusOK, ""},
+		{"invalid selector before the winner", filenameBody, []types.Task{tiffTask, plateTask, disabledTask, {Id: "bad", JobSelector: `{ "bogus" : [ 1, 2 ] }`, Priority: 3}},
+			nil, false, map[string]string{"Plate": "12"}, []string{"plate", "tiff"}, "", nil, "could not apply json logic for task id bad", http.StatusOK, ""},
+		{"no input file", `{"Id": "1"}`, tasks, nil, false, nil, nil, "", nil, "", http.StatusBadRequest, "either a job with an input file name or a filename is required"},
+		{"bad json", `{"Filename"`, tasks, nil, false, nil, nil, "", nil, "", http.StatusBadRequest, "failed to unmarshal request"},
+		{"get tasks failed", filenameBody, nil, errors.New("get failed"), false, nil, nil, "", nil, "", http.StatusInternalServerError, "could not retrieve tasks"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("GetAll").Return(append([]types.Task{}, test.Tasks...), test.GetAllErr)
+			taskConfig := config.Configuration{FanOut: test.FanOut, FilenameDecoder: types.FilenameDecoder{AttributeParser: parser}}
+			taskHandler := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &taskConfig)
+
+			req := httptest.NewRequest(http.MethodPost, "http://localhost", bytes.NewReader([]byte(test.Body)))
+			w := httptest.NewRecorder()
+			taskHandler.MatchTaskExplain(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var explanation MatchExplanation
+			require.NoError(t, json.Unmarshal(body, &explanation))
+			assert.Equal(t, test.ExpectedAttributes, explanation.Attributes)
+			assert.Equal(t, test.ExpectedWinner, explanation.MatchedTaskId)
+			assert.Equal(t, test.ExpectedLaunched, explanation.LaunchedTaskIds)
+			assert.Contains(t, explanation.MatchError, test.ExpectedMatchError)
+			require.Len(t, explanation.Tasks, len(test.Tasks))
+			var matched []string
+			for _, taskMatch := range explanation.Tasks {
+				if taskMatch.Matched {
+					matched = append(matched, taskMatch.TaskId)
+				}
+				if taskMatch.TaskId == disabledTask.Id {
+					assert.False(t, taskMatch.Enabled)
+				}
+				if taskMatch.TaskId == badTask.Id {
+					assert.NotEmpty(t, taskMatch.Error)
+				}
+			}
+			assert.Equal(t, test.ExpectedMatched, matched)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// sampleJob is the job the JobSelector of a task is evaluated against to find selectors that fail on every job
+var sampleJob = types.Job{
+	Id:    "sample",
+	Owner: pkg.OwnerDataOrg,
+	InputFile: types.FileInfo{
+		Hostname:   "oem",
+		DirName:    "/tmp/files/input",
+		Name:       "sample.tiff",
+		Extension:  ".tiff",
+		Attributes: map[string]string{},
+	},
+	Status: pkg.StatusIncomplete,
+}
+
+// invalidTaskResponse is the body of the response to a task that is not valid
+type invalidTaskResponse struct {
+	Error  string
+	Fields map[string]string
+}
+
+// validateTask checks the fields of a task that is created, or the fields set by the update of a task.
+// The JobSelector must be json logic that evaluates to a boolean, the PipelineId must be a topic of the
+// pipelines advertised by the pipeline services when they are configured, the ResultFileFolder and ModelParameters
+// must be valid templates, and the follow-up tasks must not lead back to the task. It returns an InvalidTaskError listing the invalid fields.
+func (c *Controller) validateTask(task types.Task, update bool) error {
+	invalid := make(map[string]string)
+	if !update {
+		if task.Description == "" {
+			invalid["Description"] = "required"
+		}
+		if task.JobSelector == "" {
+			invalid["JobSelector"] = "required"
+		}
+		if task.PipelineId == "" {
+			invalid["PipelineId"] = "required"
+		}
+	}
+
+	if task.JobSelector != "" {
+		_, err := helpers.ApplyJsonLogicToJob(sampleJob, task.JobSelector)
+		if err != nil {
+			invalid["JobSelector"] = err.Error()
+		}
+	}
+	if task.PipelineId != "" {
+		if reason := c.checkPipelineId(task.PipelineId); reason != "" {
+			invalid["PipelineId"] = reason
+		}
+	}
+	if err := types.ValidateTemplate(task.ResultFileFolder, sampleJob); err != nil {
+		invalid["ResultFileFolder"] = err.Error()
+	}
+	for key, value := range task.ModelParameters {
+		if err := types.ValidateTemplate(value, sampleJob); err != nil {
+			invalid["ModelParameters."+key] = err.Error()
+		}
+	}
+	if task.PipelineTimeout != "" {
+		timeout, err := time.ParseDuration(task.PipelineTimeout)
+		if err != nil || timeout <= 0 {
+			invalid["PipelineTimeout"] = fmt.Sprintf("got %q, expected a positive duration such as 30m", task.PipelineTimeout)
+		}
+	}
+	if strings.ContainsAny(task.ResultsTopic, "+# ") {
+		invalid["ResultsTopic"] = fmt.Sprintf("got %q, expected a topic without wildcards or spaces", task.ResultsTopic)
+	}
+	for _, field := range task.ClearFields {
+		if !update {
+			invalid["ClearFields"] = "only used by updates"
+			break
+		}
+		if !types.IsClearableTaskField(field) {
+			invalid["ClearFields"] = fmt.Sprintf("got %q, expected one of %q", field, types.ClearableTaskFields)
+			break
+		}
+	}
+	if task.NextTaskId != "" {
+		reason, err := c.checkTaskChain(task)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			invalid["NextTaskId"] = reason
+		}
+	}
+
+	if len(invalid) > 0 {
+		return &taskPkg.InvalidTaskError{Fields: invalid}
+	}
+	return nil
+}
+
+// handleInvalidTask writes the error of validateTask, with the reason of each invalid field in the response body
+// when the task is not valid
+func (c *Controller) handleInvalidTask(writer http.ResponseWriter, err error) {
+	invalidTaskErr, ok := err.(*taskPkg.InvalidTaskError)
+	if !ok {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusInternalServerError)
+		return
+	}
+	c.lc.Error(err.Error())
+	rspBody, err := json.Marshal(invalidTaskResponse{Error: taskPkg.ErrTaskInvalid.Error(), Fields: invalidTaskErr.Fields})
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapErr(err, pkg.ErrJSONMarshalErr), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusBadRequest)
+	_, err = writer.Write(rspBody)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// checkPipelineId returns the reason the pipeline id is invalid when it is not a topic of the pipelines advertised by
+// the pipeline services. The pipeline id is accepted when no pipeline service is configured or none of them responds.
+func (c *Controller) checkPipelineId(pipelineId string) string {
+	if len(c.pipelineClients) == 0 {
+		return ""
+	}
+	var topics []string
+	retrieved := false
+	for _, client := range c.pipelineClients {
+		pipelines, err := client.GetPipelines()
+		if err != nil {
+			c.lc.Warnf("could not retrieve pipelines to check pipeline id %s: %s", pipelineId, err.Error())
+			continue
+		}
+		retrieved = true
+		for _, pipeline := range pipelines {
+			if topicMatches(pipeline.SubscriptionTopic, pipelineId) {
+				return ""
+			}
+			topics = append(topics, pipeline.SubscriptionTopic)
+		}
+	}
+	if !retrieved {
+		c.lc.Warnf("could not check pipeline id %s, no pipeline service responded", pipelineId)
+		return ""
+	}
+	return fmt.Sprintf("got %q, expected one of the pipeline topics %q", pipelineId, topics)
+}
+
+// topicMatches reports whether the topic matches the subscription topic of a pipeline,
+// which can use the + and # wildcards of an MQTT topic filter
+func topicMatches(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for k, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if k >= len(topicLevels) || (level != "+" && level != topicLevels[k]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// checkTaskChain returns the reason the follow-up task is invalid when following the follow-up tasks from the task
+// leads back to it. Follow-up tasks that do not exist yet end the chain, they are checked when the stage is launched.
+func (c *Controller) checkTaskChain(task types.Task) (string, error) {
+	tasks, err := c.persist.GetAll()
+	if err != nil {
+		return "", werrors.WrapErr(err, taskPkg.ErrTaskRetrieval)
+	}
+	nextTaskIds := make(map[string]string, len(tasks))
+	for _, existing := range tasks {
+		nextTaskIds[existing.Id] = existing.NextTaskId
+	}
+	if task.Id != "" {
+		nextTaskIds[task.Id] = task.NextTaskId
+	}
+
+	visited := map[string]bool{task.Id: true}
+	for nextTaskId := task.NextTaskId; nextTaskId != ""; nextTaskId = nextTaskIds[nextTaskId] {
+		if visited[nextTaskId] {
+			return fmt.Sprintf(taskPkg.ErrFmtTaskChainLoop, nextTaskId), nil
+		}
+		visited[nextTaskId] = true
+	}
+	return "", nil
+}
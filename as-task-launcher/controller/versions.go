@@ -0,0 +1,108 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// GetVersions is a request to retrieve every version of the task for a specified id, from the first to the current one
+func (c *Controller) GetVersions(writer http.ResponseWriter, request *http.Request) {
+	id, err := helpers.GetByKeyFromRequest(request, pkg.TaskIdKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+
+	versions, err := c.persist.GetVersions(id)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, taskPkg.ErrTaskRetrieving), http.StatusNotFound)
+		return
+	}
+
+	c.writeTaskJson(writer, versions)
+}
+
+// Rollback is a request to make a previous version of the task the current one. The task is not changed in place,
+// the previous version is restored as a new version of the task that is returned.
+func (c *Controller) Rollback(writer http.ResponseWriter, request *http.Request) {
+	id, err := helpers.GetByKeyFromRequest(request, pkg.TaskIdKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	versionValue, err := helpers.GetByKeyFromRequest(request, pkg.VersionKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.ParseInt(versionValue, 10, 64)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapMsgf(err, "invalid version %s in url", versionValue), http.StatusBadRequest)
+		return
+	}
+
+	versions, err := c.persist.GetVersions(id)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, taskPkg.ErrTaskRetrieving), http.StatusNotFound)
+		return
+	}
+	var previous *types.Task
+	for k := range versions {
+		if versions[k].Version == version {
+			previous = &versions[k]
+		}
+	}
+	if previous == nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			fmt.Errorf(taskPkg.ErrFmtTaskVersionNotFound, version, id), http.StatusNotFound)
+		return
+	}
+
+	// the pipelines and the follow-up tasks may have changed since the version was stored
+	err = c.validateTask(*previous, false)
+	if err != nil {
+		c.handleInvalidTask(writer, err)
+		return
+	}
+
+	task, err := c.persist.Rollback(id, version)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			fmt.Errorf("failed to roll back task for Id (%s) to version %d: %s", id, version, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	c.lc.Infof("Rolled back task %s to version %d as version %d", id, version, task.Version)
+	c.writeTaskJson(writer, task)
+}
+
+// writeTaskJson writes the task or tasks as the json response
+func (c *Controller) writeTaskJson(writer http.ResponseWriter, value interface{}) {
+	jsonRsp, err := json.Marshal(value)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, taskPkg.ErrMarshallingTask), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
@@ -0,0 +1,133 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aicsd/as-task-launcher/config"
+	persistMocks "aicsd/as-task-launcher/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testTaskVersions() []types.Task {
+	return []types.Task{
+		{Id: "1", Description: "Count Cells", JobSelector: `{ "==" : [ { "var" : "Id" }, "1" ] }`, PipelineId: "100", Version: 1},
+		{Id: "1", Description: "Count Cells", JobSelector: `{ "bogus" : [ 1, 2 ] }`, PipelineId: "100", Version: 2},
+		{Id: "1", Description: "Count Cells", JobSelector: `{ "==" : [ { "var" : "Id" }, "2" ] }`, PipelineId: "200", Version: 3},
+	}
+}
+
+func TestController_GetVersions(t *testing.T) {
+	tests := []struct {
+		Name               string
+		Id                 string
+		PersistErr         error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", "1", nil, http.StatusOK, ""},
+		{"missing id", "nil", nil, http.StatusBadRequest, "missing taskid in url"},
+		{"task not found", "1", errors.New("specified task id 1 not found"), http.StatusNotFound, "specified task id 1 not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("GetVersions", test.Id).Return(testTaskVersions(), test.PersistErr)
+			taskHandler := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if test.Id != "nil" {
+				req = mux.SetURLVars(req, map[string]string{pkg.TaskIdKey: test.Id})
+			}
+			w := httptest.NewRecorder()
+			taskHandler.GetVersions(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var versions []types.Task
+			require.NoError(t, json.Unmarshal(body, &versions))
+			assert.Equal(t, testTaskVersions(), versions)
+		})
+	}
+}
+
+func TestController_Rollback(t *testing.T) {
+	restored := testTaskVersions()[0]
+	restored.Version = 4
+
+	tests := []struct {
+		Name               string
+		Version            string
+		GetVersionsErr     error
+		RollbackErr        error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", "1", nil, nil, http.StatusOK, ""},
+		{"missing version", "nil", nil, nil, http.StatusBadRequest, "missing version in url"},
+		{"invalid version", "latest", nil, nil, http.StatusBadRequest, "invalid version latest in url"},
+		{"task not found", "1", errors.New("specified task id 1 not found"), nil, http.StatusNotFound, "specified task id 1 not found"},
+		{"version not found", "7", nil, nil, http.StatusNotFound, "version 7 of task 1 not found"},
+		{"invalid version of the task", "2", nil, nil, http.StatusBadRequest, "failed to validate task"},
+		{"rollback failed", "1", nil, errors.New("rollback failed"), http.StatusInternalServerError, "failed to roll back task for Id (1) to version 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("GetVersions", "1").Return(testTaskVersions(), test.GetVersionsErr)
+			persistMock.On("Rollback", "1", mock.Anything).Return(restored, test.RollbackErr)
+			taskHandler := New(logger.MockLogger{}, &persistMock, nil, nil, nil, nil, nil, &config.Configuration{})
+
+			req := httptest.NewRequest(http.MethodPost, "http://localhost", nil)
+			vars := map[string]string{pkg.TaskIdKey: "1"}
+			if test.Version != "nil" {
+				vars[pkg.VersionKey] = test.Version
+			}
+			req = mux.SetURLVars(req, vars)
+			w := httptest.NewRecorder()
+			taskHandler.Rollback(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				if test.RollbackErr == nil {
+					persistMock.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything)
+				}
+				return
+			}
+			var task types.Task
+			require.NoError(t, json.Unmarshal(body, &task))
+			assert.Equal(t, restored, task)
+			persistMock.AssertCalled(t, "Rollback", "1", int64(1))
+		})
+	}
+}
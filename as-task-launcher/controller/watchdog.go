@@ -0,0 +1,190 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+)
+
+// maxBackoff caps the timeout of a stuck job as it doubles with every attempt
+const maxBackoff = 24 * time.Hour
+
+// RunWatchdog checks for the jobs stuck processing on a pipeline every interval until the context is cancelled
+func (c *Controller) RunWatchdog(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := c.CheckStuckJobs(time.Now().UTC())
+			if err != nil {
+				c.lc.Errorf("watchdog failed to handle the stuck jobs: %s", err.Error())
+			}
+		}
+	}
+}
+
+// CheckStuckJobs finds the jobs whose pipeline has not reported their status within the pipeline timeout of their task
+// at the given time. A stuck job is published again to its pipeline and the attempt is recorded in the job, with the
+// timeout doubling with every attempt. A job still stuck after the maximum number of attempts fails with the
+// PipelineTimedOut pipeline status.
+func (c *Controller) CheckStuckJobs(now time.Time) error {
+	jobs, err := c.jobRepoClient.RetrieveAllByOwner(pkg.OwnerTaskLauncher)
+	if err != nil {
+		return fmt.Errorf("could not retrieve %s data: %s", pkg.OwnerTaskLauncher, err.Error())
+	}
+	queued, err := c.queuedJobIds()
+	if err != nil {
+		return fmt.Errorf("could not retrieve the dispatch queue: %s", err.Error())
+	}
+	var errs error
+	for _, job := range jobs {
+		// a queued job is not processing on its pipeline yet
+		if job.PipelineDetails.Status != pkg.TaskStatusProcessing || queued[job.Id] {
+			continue
+		}
+		// a fanned out job whose runs all reported is waiting for its summary, which the retry takes care of
+		if len(job.PipelineRuns) > 0 && !hasProcessingRun(job) {
+			continue
+		}
+		attempts := job.PipelineDetails.Attempts
+		if now.Sub(time.Unix(0, job.LastUpdated)) < backoff(c.pipelineTimeout(job), attempts) {
+			continue
+		}
+		if attempts >= c.config.WatchdogMaxAttempts {
+			err = c.timeOutJob(job)
+		} else {
+			err = c.republishJob(job, attempts+1)
+		}
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// republishJob records the attempt in the job and publishes the job again to the pipeline of its task,
+// or to the pipelines of its processing runs when the job is fanned out
+func (c *Controller) republishJob(job types.Job, attempt int) error {
+	jobFields := make(map[string]interface{})
+	jobFields[types.JobPipelineAttempts] = attempt
+	updated, err := c.jobRepoClient.Update(job.Id, jobFields)
+	if err != nil {
+		return fmt.Errorf("could not update job repo with attempt %d for job id %s: %s", attempt, job.Id, err.Error())
+	}
+	job = updated
+
+	c.lc.Warnf("Publishing Job with input file %s again as its pipeline did not report its status in time, attempt %d of %d",
+		job.FullInputFileLocation(), attempt, c.config.WatchdogMaxAttempts)
+	if len(job.PipelineRuns) > 0 {
+		return c.resendPipelineRuns(job)
+	}
+	// the job is published again with the version of the task it was launched with
+	task, err := c.persist.GetVersion(job.PipelineDetails.TaskId, job.PipelineDetails.TaskVersion)
+	if err != nil {
+		return fmt.Errorf("could not retrieve task %s for job id %s: %s", job.PipelineDetails.TaskId, job.Id, err.Error())
+	}
+	return c.publishEventForTask(job, &task)
+}
+
+// timeOutJob fails the job and its processing runs with the PipelineTimedOut pipeline status
+func (c *Controller) timeOutJob(job types.Job) error {
+	c.lc.Errorf("Pipeline did not report the status of Job with input file %s after %d attempts",
+		job.FullInputFileLocation(), job.PipelineDetails.Attempts)
+	jobFields := make(map[string]interface{})
+	jobFields[types.JobOwner] = pkg.OwnerNone
+	jobFields[types.JobStatus] = pkg.StatusPipelineError
+	jobFields[types.JobPipelineStatus] = pkg.TaskStatusTimedOut
+	jobFields[types.JobErrorDetailsOwner] = pkg.OwnerTaskLauncher
+	jobFields[types.JobErrorDetailsErrorMsg] = pkg.ErrPipelineTimedOut.Error()
+	if len(job.PipelineRuns) > 0 {
+		runs := make([]types.PipelineInfo, len(job.PipelineRuns))
+		copy(runs, job.PipelineRuns)
+		for k := range runs {
+			if runs[k].Status == pkg.TaskStatusProcessing {
+				runs[k].Status = pkg.TaskStatusTimedOut
+			}
+		}
+		jobFields[types.JobPipelineRuns] = runs
+	}
+	_, err := c.jobRepoClient.Update(job.Id, jobFields)
+	if err != nil {
+		return fmt.Errorf("could not update job repo to pipeline status %s for job id %s: %s", pkg.TaskStatusTimedOut, job.Id, err.Error())
+	}
+	// the pipelines are free for the next queued jobs
+	var errs error
+	for _, taskId := range job.TaskIds() {
+		err = c.releaseDispatch(job.Id, taskId)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// pipelineTimeout returns how long the pipeline of the job may process it, the longest timeout of the versions of the
+// tasks the job is processing for. The default timeout is used for the tasks that do not set one or can not be retrieved.
+func (c *Controller) pipelineTimeout(job types.Job) time.Duration {
+	taskIds := []string{job.PipelineDetails.TaskId}
+	if len(job.PipelineRuns) > 0 {
+		taskIds = nil
+		for _, run := range job.PipelineRuns {
+			if run.Status == pkg.TaskStatusProcessing {
+				taskIds = append(taskIds, run.TaskId)
+			}
+		}
+	}
+
+	var timeout time.Duration
+	for _, taskId := range taskIds {
+		taskTimeout := c.config.PipelineTimeout
+		task, err := c.persist.GetVersion(taskId, job.TaskVersion(taskId))
+		if err != nil {
+			c.lc.Warnf("could not retrieve task %s for the pipeline timeout of job id %s: %s", taskId, job.Id, err.Error())
+		} else if task.PipelineTimeout != "" {
+			taskTimeout, err = time.ParseDuration(task.PipelineTimeout)
+			if err != nil {
+				c.lc.Warnf("invalid pipeline timeout %s of task %s: %s", task.PipelineTimeout, taskId, err.Error())
+				taskTimeout = c.config.PipelineTimeout
+			}
+		}
+		if taskTimeout > timeout {
+			timeout = taskTimeout
+		}
+	}
+	return timeout
+}
+
+// backoff returns the timeout of a job after the given number of attempts, doubling it with every attempt
+// up to the maxBackoff or the timeout itself when it is longer
+func backoff(timeout time.Duration, attempts int) time.Duration {
+	doubled := timeout
+	for i := 0; i < attempts && doubled < maxBackoff; i++ {
+		doubled *= 2
+	}
+	return min(doubled, max(timeout, maxBackoff))
+}
+
+// hasProcessingRun checks if a pipeline run of the fanned out job is still processing
+func hasProcessingRun(job types.Job) bool {
+	for _, run := range job.PipelineRuns {
+		if run.Status == pkg.TaskStatusProcessing {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aicsd/as-task-launcher/config"
+	persistMocks "aicsd/as-task-launcher/persist/mocks"
+	"aicsd/pkg"
+	jobRepoMocks "aicsd/pkg/clients/job_repo/mocks"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	appsdk "github.com/edgexfoundry/app-functions-sdk-go/v2/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces/mocks"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_CheckStuckJobs(t *testing.T) {
+	now := time.Now().UTC()
+	stuckJob := func(taskId string, age time.Duration, attempts int) types.Job {
+		job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+		job.PipelineDetails.TaskId = taskId
+		job.PipelineDetails.TaskVersion = 2
+		job.PipelineDetails.Status = pkg.TaskStatusProcessing
+		job.PipelineDetails.Attempts = attempts
+		job.LastUpdated = now.Add(-age).UnixNano()
+		return job
+	}
+	fanOutJob := stuckJob("", 11*time.Minute, 0)
+	fanOutJob.PipelineDetails.TaskVersion = 0
+	fanOutJob.PipelineRuns = []types.PipelineInfo{
+		{TaskId: "count", TaskVersion: 2, Status: pkg.TaskStatusProcessing},
+		{TaskId: "slow", TaskVersion: 2, Status: pkg.TaskStatusComplete},
+	}
+	timedOutFanOutJob := fanOutJob
+	timedOutFanOutJob.PipelineDetails.Attempts = 2
+	timedOutFanOutJob.LastUpdated = now.Add(-time.Hour).UnixNano()
+	reportedFanOutJob := stuckJob("", time.Hour, 0)
+	reportedFanOutJob.PipelineRuns = []types.PipelineInfo{{TaskId: "count", Status: pkg.TaskStatusComplete}}
+	completeJob := stuckJob("count", time.Hour, 0)
+	completeJob.PipelineDetails.Status = pkg.TaskStatusComplete
+
+	timedOutFields := func(runs []types.PipelineInfo) map[string]interface{} {
+		jobFields := map[string]interface{}{
+			types.JobOwner:                pkg.OwnerNone,
+			types.JobStatus:               pkg.StatusPipelineError,
+			types.JobPipelineStatus:       pkg.TaskStatusTimedOut,
+			types.JobErrorDetailsOwner:    pkg.OwnerTaskLauncher,
+			types.JobErrorDetailsErrorMsg: pkg.ErrPipelineTimedOut.Error(),
+		}
+		if runs != nil {
+			jobFields[types.JobPipelineRuns] = runs
+		}
+		return jobFields
+	}
+
+	tests := []struct {
+		Name              string
+		Job               types.Job
+		RetrieveErr       error
+		UpdateErr         error
+		ExpectedFields    map[string]interface{}
+		ExpectedPublishes int
+		ExpectedErrorMsg  string
+	}{
+		{"not stuck", stuckJob("count", 5*time.Minute, 0), nil, nil, nil, 0, ""},
+		{"stuck", stuckJob("count", 11*time.Minute, 0), nil, nil, map[string]interface{}{types.JobPipelineAttempts: 1}, 1, ""},
+		{"waiting for backoff", stuckJob("count", 15*time.Minute, 1), nil, nil, nil, 0, ""},
+		{"stuck after backoff", stuckJob("count", 21*time.Minute, 1), nil, nil, map[string]interface{}{types.JobPipelineAttempts: 2}, 1, ""},
+		{"timed out", stuckJob("count", 41*time.Minute, 2), nil, nil, timedOutFields(nil), 0, ""},
+		{"task timeout not reached", stuckJob("slow", 30*time.Minute, 0), nil, nil, nil, 0, ""},
+		{"task timeout reached", stuckJob("slow", 61*time.Minute, 0), nil, nil, map[string]interface{}{types.JobPipelineAttempts: 1}, 1, ""},
+		{"task not found uses default timeout", stuckJob("deleted", 11*time.Minute, 0), nil, nil, map[string]interface{}{types.JobPipelineAttempts: 1}, 0,
+			"could not retrieve task deleted"},
+		{"fan out stuck", fanOutJob, nil, nil, map[string]interface{}{types.JobPipelineAttempts: 1}, 1, ""},
+		{"fan out timed out", timedOutFanOutJob, nil, nil, timedOutFields([]types.PipelineInfo{
+			{TaskId: "count", TaskVersion: 2, Status: pkg.TaskStatusTimedOut},
+			{TaskId: "slow", TaskVersion: 2, Status: pkg.TaskStatusComplete},
+		}), 0, ""},
+		{"fan out waiting for summary", reportedFanOutJob, nil, nil, nil, 0, ""},
+		{"not processing", completeJob, nil, nil, nil, 0, ""},
+		{"update failed", stuckJob("count", 11*time.Minute, 0), nil, pkg.ErrUpdating, map[string]interface{}{types.JobPipelineAttempts: 1}, 0,
+			"could not update job repo with attempt 1"},
+		{"retrieve failed", types.Job{}, pkg.ErrRetrieving, nil, nil, 0, "could not retrieve task-launcher data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockLogger := logger.MockLogger{}
+			persistMock := persistMocks.Persistence{}
+			repoMock := jobRepoMocks.Client{}
+			backgroundPublisherMock := mocks.BackgroundPublisher{}
+			appServiceMock := mocks.ApplicationService{}
+			taskConfig := config.Configuration{FileHostname: fileHostname, PipelineTimeout: 10 * time.Minute, WatchdogMaxAttempts: 2}
+			taskHandler := New(&mockLogger, &persistMock, &repoMock, nil, nil, &backgroundPublisherMock, &appServiceMock, &taskConfig)
+
+			repoMock.On("RetrieveAllByOwner", pkg.OwnerTaskLauncher).Return([]types.Job{test.Job}, test.RetrieveErr)
+			repoMock.On("Update", test.Job.Id, mock.Anything).Return(test.Job, test.UpdateErr)
+			// the jobs are processed with version 2 of their tasks
+			persistMock.On("GetVersion", "count", int64(2)).Return(types.Task{Id: "count", PipelineId: "count-cells", Version: 2}, nil)
+			persistMock.On("GetVersion", "slow", int64(2)).Return(types.Task{Id: "slow", PipelineId: "measure", PipelineTimeout: "1h", Version: 2}, nil)
+			persistMock.On("GetVersion", mock.Anything, mock.Anything).Return(types.Task{}, errors.New("not found"))
+			ctx := appsdk.NewAppFuncContextForTest(uuid.NewString(), mockLogger)
+			appServiceMock.On("BuildContext", mock.Anything, mock.Anything).Return(ctx)
+			backgroundPublisherMock.On("Publish", mock.Anything, ctx).Return(nil)
+
+			err := taskHandler.CheckStuckJobs(now)
+			if test.ExpectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			if test.ExpectedFields != nil {
+				repoMock.AssertCalled(t, "Update", test.Job.Id, test.ExpectedFields)
+			} else {
+				repoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			}
+			backgroundPublisherMock.AssertNumberOfCalls(t, "Publish", test.ExpectedPublishes)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Minute, backoff(10*time.Minute, 0))
+	assert.Equal(t, 40*time.Minute, backoff(10*time.Minute, 2))
+	assert.Equal(t, maxBackoff, backoff(10*time.Hour, 3))
+	assert.Equal(t, 48*time.Hour, backoff(48*time.Hour, 1))
+}
@@ -9,11 +9,14 @@ import (
 	"aicsd/pkg/clients/job_handler"
 	"aicsd/pkg/clients/redis"
 	"aicsd/pkg/wait"
+	"context"
 	"fmt"
 	"os"
+	"sync"
 
 	"aicsd/as-task-launcher/config"
 	"aicsd/pkg/clients/job_repo"
+	"aicsd/pkg/clients/pipeline"
 
 	"aicsd/as-task-launcher/controller"
 	"aicsd/as-task-launcher/persist"
@@ -38,17 +41,31 @@ func main() {
 		os.Exit(-1)
 	}
 
-	secrets, err := service.GetSecret(pkg.DatabasePath, "username", "password")
-	if err != nil {
-		lc.Errorf("failed to GetSecret for database %s: %s", pkg.DatabasePath, err.Error())
+	if err := service.LoadCustomConfig(&configuration.FilenameDecoder, "AttributeParser"); err != nil {
+		lc.Errorf("unable to load custom attribute parser configuration: %s", err.Error())
 		os.Exit(-1)
 	}
 
-	redisClient := redis.NewClient(configuration.RedisHost, configuration.RedisPort, service.RequestTimeout(), secrets)
-	persistence, err := persist.NewRedisDB(lc, redisClient)
-	if err != nil {
-		lc.Errorf("failed to connect to redis db: %s", err.Error())
-		os.Exit(-1)
+	var persistence persist.Persistence
+	if configuration.PersistenceType == pkg.PersistenceTypeBolt {
+		persistence, err = persist.NewBoltDB(lc, configuration.PersistenceFile)
+		if err != nil {
+			lc.Errorf("failed to open bolt db: %s", err.Error())
+			os.Exit(-1)
+		}
+	} else {
+		secrets, err := service.GetSecret(pkg.DatabasePath, "username", "password")
+		if err != nil {
+			lc.Errorf("failed to GetSecret for database %s: %s", pkg.DatabasePath, err.Error())
+			os.Exit(-1)
+		}
+
+		redisClient := redis.NewClient(configuration.RedisHost, configuration.RedisPort, service.RequestTimeout(), secrets)
+		persistence, err = persist.NewRedisDB(lc, redisClient)
+		if err != nil {
+			lc.Errorf("failed to connect to redis db: %s", err.Error())
+			os.Exit(-1)
+		}
 	}
 
 	jobRepoClient := job_repo.NewClient(configuration.JobRepoBaseUrl, service.RequestTimeout(), nil)
@@ -59,7 +76,16 @@ func main() {
 		os.Exit(-1)
 	}
 
-	taskLauncherController := controller.New(lc, persistence, jobRepoClient, senderClient, publisher, service, configuration)
+	var pipelineClients []pipeline.Client
+	for _, url := range configuration.PipelineServiceUrls {
+		pipelineClients = append(pipelineClients, pipeline.NewClient(url, service.RequestTimeout(), nil))
+	}
+
+	taskLauncherController := controller.New(lc, persistence, jobRepoClient, senderClient, pipelineClients, publisher, service, configuration)
+	if configuration.PersistenceType == pkg.PersistenceTypeBolt {
+		// the embedded database does not need the redis service
+		taskLauncherController.DependentServices = taskLauncherController.DependentServices.Without(wait.ServiceRedis)
+	}
 	if err = wait.ForDependencies(lc, taskLauncherController.DependentServices, service.RequestTimeout()); err != nil {
 		lc.Errorf("failed to wait.ForDependencies: %s", err.Error())
 		os.Exit(-1)
@@ -77,10 +103,25 @@ func main() {
 		lc.Errorf("Retry on startup failed: %s", err.Error())
 	}
 
+	err = taskLauncherController.DispatchQueued()
+	if err != nil {
+		lc.Errorf("Dispatching the queued jobs failed: %s", err.Error())
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	if configuration.WatchdogInterval > 0 {
+		wg.Add(1)
+		go taskLauncherController.RunWatchdog(ctx, wg, configuration.WatchdogInterval)
+	}
+
 	if err := service.MakeItRun(); err != nil {
 		lc.Errorf("MakeItRun returned error: %s", err.Error())
 		os.Exit(-1)
 	}
 
+	cancelFunc()
+	wg.Wait()
+
 	os.Exit(0)
 }
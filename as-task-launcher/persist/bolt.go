@@ -0,0 +1,376 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout is how long to wait for the file lock held by another process using the database file
+const boltOpenTimeout = 5 * time.Second
+
+var (
+	bucketTask = []byte("task")
+	// bucketTaskVersion stores every version of the tasks under the keys made by versionKey
+	bucketTaskVersion = []byte("task_version")
+	// bucketDispatch stores the dispatches of the jobs queued or in flight under the dispatch key
+	bucketDispatch = []byte("dispatch")
+)
+
+// versionPrefix returns the prefix of the keys of the versions of a task
+func versionPrefix(id string) []byte {
+	return []byte(id + "/")
+}
+
+// versionKey returns the key of a version of a task, the versions of a task sort in version order
+func versionKey(id string, version int64) []byte {
+	return append(versionPrefix(id), fmt.Sprintf("%020d", version)...)
+}
+
+// BoltDB is a Persistence that stores the tasks in an embedded bbolt database file,
+// for deployments that do not run a Redis server.
+type BoltDB struct {
+	lc logger.LoggingClient
+	db *bolt.DB
+}
+
+// NewBoltDB returns a persistence interface that uses a bbolt database stored in the given file.
+// The file is created if it does not exist.
+func NewBoltDB(lc logger.LoggingClient, path string) (Persistence, error) {
+	db, err := bolt.Open(path, pkg.FilePermissions, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, "failed to open bolt db %s", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketTask)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(bucketTaskVersion)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(bucketDispatch)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, werrors.WrapMsgf(err, "failed to create buckets in bolt db %s", path)
+	}
+	return BoltDB{lc: lc, db: db}, nil
+}
+
+// Create stores a new task object in the task bucket under the key task.Id, and its first version in the
+// version bucket
+func (bdb BoltDB) Create(task types.Task) (string, error) {
+
+	if task.JobSelector == "" {
+		return "", taskPkg.ErrTaskEmptyJobSelector
+	}
+
+	if task.PipelineId == "" {
+		return "", taskPkg.ErrTaskEmptyPipelineId
+	}
+
+	if task.Description == "" {
+		return "", taskPkg.ErrTaskEmptyDescription
+	}
+
+	task.Id = uuid.NewString()
+	task.SetLastUpdated()
+	task.Created = task.LastUpdated
+	task.Version = 1
+
+	err := bdb.db.Update(func(tx *bolt.Tx) error {
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return task.Id, nil
+}
+
+// Update replaces the values of the existing task with the non-empty values of the given task,
+// storing the result as a new version of the task
+func (bdb BoltDB) Update(task types.Task) error {
+	// check id exists - not empty
+	if task.Id == "" {
+		return taskPkg.ErrTaskIdEmpty
+	}
+
+	return bdb.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketTask)
+		data := bucket.Get([]byte(task.Id))
+		if data == nil {
+			return fmt.Errorf(taskPkg.ErrFmtTaskIdNotFound, task.Id)
+		}
+
+		var existingTask types.Task
+		err := json.Unmarshal(data, &existingTask)
+		if err != nil {
+			return werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
+		}
+
+		if existingTask.Version == 0 {
+			// a task stored before the tasks were versioned becomes the first version of its history
+			existingTask.Version = 1
+			err = putTask(tx, existingTask)
+			if err != nil {
+				return err
+			}
+		}
+
+		existingTask.ReplaceTask(task)
+		existingTask.Version++
+
+		return putTask(tx, existingTask)
+	})
+}
+
+func (bdb BoltDB) Delete(id string) error {
+
+	if id == "" {
+		return taskPkg.ErrTaskIdEmpty
+	}
+
+	return bdb.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketTask)
+		if bucket.Get([]byte(id)) == nil {
+			return taskPkg.ErrNoTaskDeleted
+		}
+		err := bucket.Delete([]byte(id))
+		if err != nil {
+			return werrors.WrapErr(err, taskPkg.ErrDeleteTask)
+		}
+		// the keys are collected first as deleting while iterating with a cursor skips keys
+		versions := tx.Bucket(bucketTaskVersion)
+		prefix := versionPrefix(id)
+		var keys [][]byte
+		cursor := versions.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			keys = append(keys, key)
+		}
+		for _, key := range keys {
+			err = versions.Delete(key)
+			if err != nil {
+				return werrors.WrapErr(err, taskPkg.ErrDeleteTask)
+			}
+		}
+		return nil
+	})
+}
+
+// GetById returns task object corresponding to a particular task Id
+func (bdb BoltDB) GetById(id string) (types.Task, error) {
+
+	if id == "" {
+		return types.Task{}, taskPkg.ErrTaskIdEmpty
+	}
+
+	task := types.Task{}
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		taskBytes := tx.Bucket(bucketTask).Get([]byte(id))
+		if taskBytes == nil {
+			return fmt.Errorf(taskPkg.ErrFmtTaskIdNotFound, id)
+		}
+		err := json.Unmarshal(taskBytes, &task)
+		if err != nil {
+			return werrors.WrapErr(err, taskPkg.ErrTaskRetrieval)
+		}
+		return nil
+	})
+	if err != nil {
+		return types.Task{}, err
+	}
+
+	return task, nil
+}
+
+// GetAll returns all tasks from the bolt db
+func (bdb BoltDB) GetAll() ([]types.Task, error) {
+	tasks := []types.Task{}
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketTask).ForEach(func(_, taskJson []byte) error {
+			task := types.Task{}
+			err := json.Unmarshal(taskJson, &task)
+			if err != nil {
+				return werrors.WrapErr(err, taskPkg.ErrTaskRetrieval)
+			}
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+	if err != nil {
+		return []types.Task{}, err
+	}
+
+	return tasks, nil
+}
+
+// GetVersions returns every version of the task from the bolt db, from the first to the current one
+func (bdb BoltDB) GetVersions(id string) ([]types.Task, error) {
+	current, err := bdb.GetById(id)
+	if err != nil {
+		return []types.Task{}, err
+	}
+
+	var versions []types.Task
+	err = bdb.db.View(func(tx *bolt.Tx) error {
+		versions, err = getBoltVersions(tx, current)
+		return err
+	})
+	if err != nil {
+		return []types.Task{}, err
+	}
+	return versions, nil
+}
+
+// GetVersion returns the given version of the task from the bolt db, or the current version when the version is 0
+func (bdb BoltDB) GetVersion(id string, version int64) (types.Task, error) {
+	versions, err := bdb.GetVersions(id)
+	if err != nil {
+		return types.Task{}, err
+	}
+	return getVersion(versions, id, version)
+}
+
+// Rollback makes a previous version of the task the current one, as a new version of the task
+func (bdb BoltDB) Rollback(id string, version int64) (types.Task, error) {
+	if id == "" {
+		return types.Task{}, taskPkg.ErrTaskIdEmpty
+	}
+
+	var task types.Task
+	err := bdb.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketTask).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf(taskPkg.ErrFmtTaskIdNotFound, id)
+		}
+		err := json.Unmarshal(data, &task)
+		if err != nil {
+			return werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
+		}
+
+		versions, err := getBoltVersions(tx, task)
+		if err != nil {
+			return err
+		}
+		previous, found := findVersion(versions, version)
+		if !found {
+			return fmt.Errorf(taskPkg.ErrFmtTaskVersionNotFound, version, id)
+		}
+
+		task.RestoreVersion(previous)
+		return putTask(tx, task)
+	})
+	if err != nil {
+		return types.Task{}, err
+	}
+	return task, nil
+}
+
+// putTask stores the task as the current task and as a new version in the version history
+func putTask(tx *bolt.Tx, task types.Task) error {
+	jsonTask, err := json.Marshal(task)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrMarshallingTask)
+	}
+	err = tx.Bucket(bucketTask).Put([]byte(task.Id), jsonTask)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrTaskCreation)
+	}
+	err = tx.Bucket(bucketTaskVersion).Put(versionKey(task.Id, task.Version), jsonTask)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrTaskCreation)
+	}
+	return nil
+}
+
+// getBoltVersions reads the version history of the task, a task stored before the tasks were versioned
+// only has its current version
+func getBoltVersions(tx *bolt.Tx, current types.Task) ([]types.Task, error) {
+	versions := []types.Task{}
+	prefix := versionPrefix(current.Id)
+	cursor := tx.Bucket(bucketTaskVersion).Cursor()
+	for key, versionJson := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, versionJson = cursor.Next() {
+		task := types.Task{}
+		err := json.Unmarshal(versionJson, &task)
+		if err != nil {
+			return []types.Task{}, werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
+		}
+		versions = append(versions, task)
+	}
+	if len(versions) == 0 {
+		return []types.Task{current}, nil
+	}
+	return versions, nil
+}
+
+// PutDispatch stores the dispatch in the dispatch bucket under the dispatch key
+func (bdb BoltDB) PutDispatch(dispatch types.Dispatch) error {
+	jsonDispatch, err := json.Marshal(dispatch)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrMarshallingDispatch)
+	}
+	err = bdb.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDispatch).Put([]byte(dispatch.Key()), jsonDispatch)
+	})
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrDispatchUpdate)
+	}
+	return nil
+}
+
+// DeleteDispatch removes the dispatch of the job to the pipeline of the task from the bolt db
+func (bdb BoltDB) DeleteDispatch(jobId string, taskId string) error {
+	err := bdb.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDispatch).Delete([]byte(types.DispatchKey(jobId, taskId)))
+	})
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrDispatchUpdate)
+	}
+	return nil
+}
+
+// GetDispatches returns the dispatches of the jobs queued or in flight from the bolt db
+func (bdb BoltDB) GetDispatches() ([]types.Dispatch, error) {
+	dispatches := []types.Dispatch{}
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDispatch).ForEach(func(_, dispatchJson []byte) error {
+			dispatch := types.Dispatch{}
+			err := json.Unmarshal(dispatchJson, &dispatch)
+			if err != nil {
+				return werrors.WrapErr(err, taskPkg.ErrDispatchRetrieval)
+			}
+			dispatches = append(dispatches, dispatch)
+			return nil
+		})
+	})
+	if err != nil {
+		return []types.Dispatch{}, err
+	}
+	return dispatches, nil
+}
+
+func (bdb BoltDB) Disconnect() error {
+	return bdb.db.Close()
+}
+
+func (bdb BoltDB) Filter(task types.Task) ([]types.Task, error) {
+	return []types.Task{}, nil
+}
@@ -5,7 +5,12 @@
 
 package persist
 
-import "aicsd/pkg/types"
+import (
+	"fmt"
+
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg/types"
+)
 
 const (
 	StatusCreated = "Created"
@@ -18,6 +23,40 @@ type Persistence interface {
 	Delete(id string) error
 	GetById(id string) (task types.Task, err error)
 	GetAll() ([]types.Task, error)
+	// GetVersions returns every version of the task, from the first to the current one
+	GetVersions(id string) ([]types.Task, error)
+	// GetVersion returns the given version of the task, or the current version when the version is 0
+	GetVersion(id string, version int64) (types.Task, error)
+	// Rollback makes a previous version of the task the current one, as a new version of the task
+	Rollback(id string, version int64) (types.Task, error)
+	// PutDispatch stores the dispatch of a job to a pipeline, replacing the stored dispatch of the job and task
+	PutDispatch(dispatch types.Dispatch) error
+	// DeleteDispatch removes the dispatch of a job to the pipeline of a task
+	DeleteDispatch(jobId string, taskId string) error
+	// GetDispatches returns the dispatches of the jobs queued or in flight on the pipelines
+	GetDispatches() ([]types.Dispatch, error)
 	Filter(task types.Task) (results []types.Task, err error)
 	Disconnect() error
 }
+
+// findVersion returns the given version of a task from its versions
+func findVersion(versions []types.Task, version int64) (types.Task, bool) {
+	for _, task := range versions {
+		if task.Version == version {
+			return task, true
+		}
+	}
+	return types.Task{}, false
+}
+
+// getVersion returns the given version of a task from its versions, or the current version when the version is 0
+func getVersion(versions []types.Task, id string, version int64) (types.Task, error) {
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	task, found := findVersion(versions, version)
+	if !found {
+		return types.Task{}, fmt.Errorf(taskPkg.ErrFmtTaskVersionNotFound, version, id)
+	}
+	return task, nil
+}
@@ -51,6 +51,20 @@ func (_m *Persistence) Delete(id string) error {
 	return r0
 }
 
+// DeleteDispatch provides a mock function with given fields: jobId, taskId
+func (_m *Persistence) DeleteDispatch(jobId string, taskId string) error {
+	ret := _m.Called(jobId, taskId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(jobId, taskId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Disconnect provides a mock function with given fields:
 func (_m *Persistence) Disconnect() error {
 	ret := _m.Called()
@@ -117,6 +131,32 @@ func (_m *Persistence) GetAll() ([]types.Task, error) {
 	return r0, r1
 }
 
+// GetDispatches provides a mock function with given fields:
+func (_m *Persistence) GetDispatches() ([]types.Dispatch, error) {
+	ret := _m.Called()
+
+	var r0 []types.Dispatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]types.Dispatch, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []types.Dispatch); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.Dispatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetById provides a mock function with given fields: id
 func (_m *Persistence) GetById(id string) (types.Task, error) {
 	ret := _m.Called(id)
@@ -141,6 +181,94 @@ func (_m *Persistence) GetById(id string) (types.Task, error) {
 	return r0, r1
 }
 
+// GetVersion provides a mock function with given fields: id, version
+func (_m *Persistence) GetVersion(id string, version int64) (types.Task, error) {
+	ret := _m.Called(id, version)
+
+	var r0 types.Task
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int64) (types.Task, error)); ok {
+		return rf(id, version)
+	}
+	if rf, ok := ret.Get(0).(func(string, int64) types.Task); ok {
+		r0 = rf(id, version)
+	} else {
+		r0 = ret.Get(0).(types.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
+		r1 = rf(id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersions provides a mock function with given fields: id
+func (_m *Persistence) GetVersions(id string) ([]types.Task, error) {
+	ret := _m.Called(id)
+
+	var r0 []types.Task
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]types.Task, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) []types.Task); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.Task)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PutDispatch provides a mock function with given fields: dispatch
+func (_m *Persistence) PutDispatch(dispatch types.Dispatch) error {
+	ret := _m.Called(dispatch)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(types.Dispatch) error); ok {
+		r0 = rf(dispatch)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rollback provides a mock function with given fields: id, version
+func (_m *Persistence) Rollback(id string, version int64) (types.Task, error) {
+	ret := _m.Called(id, version)
+
+	var r0 types.Task
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int64) (types.Task, error)); ok {
+		return rf(id, version)
+	}
+	if rf, ok := ret.Get(0).(func(string, int64) types.Task); ok {
+		r0 = rf(id, version)
+	} else {
+		r0 = ret.Get(0).(types.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
+		r1 = rf(id, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: task
 func (_m *Persistence) Update(task types.Task) error {
 	ret := _m.Called(task)
@@ -0,0 +1,249 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	taskPkg "aicsd/as-task-launcher/pkg"
+	"aicsd/pkg"
+	"aicsd/pkg/clients/redis"
+	"aicsd/pkg/types"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file run against every Persistence backend to verify they behave the same way.
+// The Redis backend uses an in memory Redis server so no database needs to be running.
+
+// backends returns a constructor for each Persistence backend
+func backends() map[string]func(t *testing.T) Persistence {
+	return map[string]func(t *testing.T) Persistence{
+		pkg.PersistenceTypeRedis: func(t *testing.T) Persistence {
+			server := miniredis.RunT(t)
+			persistence, err := NewRedisDB(logger.MockLogger{}, redis.NewClient(server.Host(), server.Port(), time.Second, nil))
+			require.NoError(t, err)
+			return persistence
+		},
+		pkg.PersistenceTypeBolt: func(t *testing.T) Persistence {
+			persistence, err := NewBoltDB(logger.MockLogger{}, filepath.Join(t.TempDir(), "tasks.db"))
+			require.NoError(t, err)
+			return persistence
+		},
+	}
+}
+
+// runForEachBackend runs the test against a new, empty persistence of every backend
+func runForEachBackend(t *testing.T, test func(t *testing.T, persistence Persistence)) {
+	for name, newPersistence := range backends() {
+		t.Run(name, func(t *testing.T) {
+			persistence := newPersistence(t)
+			defer func() { _ = persistence.Disconnect() }()
+			test(t, persistence)
+		})
+	}
+}
+
+func TestPersistence_CreateAndGet(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		tasks, err := persistence.GetAll()
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+
+		first := taskPkg.CreateTestTask("", "Count Cells", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "100")
+		second := taskPkg.CreateTestTask("", "Count Nuclei", `{ "==" : [ { "var" : "Id" }, "2" ] }`, "200")
+		first.Id, err = persistence.Create(first)
+		require.NoError(t, err)
+		second.Id, err = persistence.Create(second)
+		require.NoError(t, err)
+		assert.NotEmpty(t, first.Id)
+		assert.NotEqual(t, first.Id, second.Id)
+
+		task, err := persistence.GetById(first.Id)
+		require.NoError(t, err)
+		assert.Equal(t, first.Description, task.Description)
+		assert.Equal(t, first.JobSelector, task.JobSelector)
+		assert.Equal(t, first.PipelineId, task.PipelineId)
+		assert.Equal(t, first.ModelParameters, task.ModelParameters)
+		assert.NotZero(t, task.Created)
+		assert.Equal(t, task.LastUpdated, task.Created)
+		assert.Equal(t, int64(1), task.Version)
+
+		tasks, err = persistence.GetAll()
+		require.NoError(t, err)
+		require.Len(t, tasks, 2)
+		assert.ElementsMatch(t, []string{first.Id, second.Id}, []string{tasks[0].Id, tasks[1].Id})
+
+		_, err = persistence.GetById("unknown")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "specified task id unknown not found")
+
+		_, err = persistence.GetById("")
+		assert.Equal(t, taskPkg.ErrTaskIdEmpty, err)
+
+		_, err = persistence.Create(taskPkg.CreateTestTask("", "", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "100"))
+		assert.Equal(t, taskPkg.ErrTaskEmptyDescription, err)
+		_, err = persistence.Create(taskPkg.CreateTestTask("", "Count Cells", "", "100"))
+		assert.Equal(t, taskPkg.ErrTaskEmptyJobSelector, err)
+		_, err = persistence.Create(taskPkg.CreateTestTask("", "Count Cells", `{ "==" : [ { "var" : "Id" }, "1" ] }`, ""))
+		assert.Equal(t, taskPkg.ErrTaskEmptyPipelineId, err)
+	})
+}
+
+func TestPersistence_Update(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		id, err := persistence.Create(taskPkg.CreateTestTask("", "Count Cells", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "100"))
+		require.NoError(t, err)
+
+		created, err := persistence.GetById(id)
+		require.NoError(t, err)
+
+		disabled := false
+		err = persistence.Update(types.Task{Id: id, PipelineId: "300", ModelParameters: map[string]string{"Gamma": "255"}, Priority: 5, Enabled: &disabled})
+		require.NoError(t, err)
+
+		task, err := persistence.GetById(id)
+		require.NoError(t, err)
+		assert.Equal(t, "Count Cells", task.Description)
+		assert.Equal(t, "300", task.PipelineId)
+		assert.Equal(t, map[string]string{"Brightness": "0", "Gamma": "255"}, task.ModelParameters)
+		assert.Equal(t, 5, task.Priority)
+		assert.False(t, task.IsEnabled())
+		assert.Equal(t, created.Created, task.Created)
+		assert.Equal(t, int64(2), task.Version)
+
+		err = persistence.Update(types.Task{Id: id, ClearFields: []string{"Priority", "Enabled"}})
+		require.NoError(t, err)
+
+		task, err = persistence.GetById(id)
+		require.NoError(t, err)
+		assert.Zero(t, task.Priority)
+		assert.True(t, task.IsEnabled())
+		assert.Nil(t, task.ClearFields)
+		assert.Equal(t, int64(3), task.Version)
+
+		err = persistence.Update(types.Task{Id: "unknown", PipelineId: "300"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "specified task id unknown not found")
+
+		assert.Equal(t, taskPkg.ErrTaskIdEmpty, persistence.Update(types.Task{PipelineId: "300"}))
+	})
+}
+
+func TestPersistence_Versions(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		id, err := persistence.Create(taskPkg.CreateTestTask("", "Count Cells", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "100"))
+		require.NoError(t, err)
+		require.NoError(t, persistence.Update(types.Task{Id: id, JobSelector: `{ "==" : [ { "var" : "Id" }, "2" ] }`, ModelParameters: map[string]string{"Gamma": "255"}}))
+		require.NoError(t, persistence.Update(types.Task{Id: id, PipelineId: "300"}))
+
+		versions, err := persistence.GetVersions(id)
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		for i, version := range versions {
+			assert.Equal(t, id, version.Id)
+			assert.Equal(t, int64(i+1), version.Version)
+		}
+		assert.Equal(t, `{ "==" : [ { "var" : "Id" }, "1" ] }`, versions[0].JobSelector)
+		assert.Equal(t, "100", versions[0].PipelineId)
+		assert.Equal(t, map[string]string{"Brightness": "0"}, versions[0].ModelParameters)
+		assert.Equal(t, `{ "==" : [ { "var" : "Id" }, "2" ] }`, versions[1].JobSelector)
+		assert.Equal(t, map[string]string{"Brightness": "0", "Gamma": "255"}, versions[1].ModelParameters)
+		assert.Equal(t, "300", versions[2].PipelineId)
+
+		task, err := persistence.GetVersion(id, 2)
+		require.NoError(t, err)
+		assert.Equal(t, versions[1], task)
+		task, err = persistence.GetVersion(id, 0)
+		require.NoError(t, err)
+		assert.Equal(t, versions[2], task)
+		_, err = persistence.GetVersion(id, 7)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "version 7 of task "+id+" not found")
+
+		task, err = persistence.Rollback(id, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), task.Version)
+		assert.Equal(t, versions[0].Created, task.Created)
+		assert.Equal(t, `{ "==" : [ { "var" : "Id" }, "1" ] }`, task.JobSelector)
+		assert.Equal(t, "100", task.PipelineId)
+		assert.Equal(t, map[string]string{"Brightness": "0"}, task.ModelParameters)
+
+		current, err := persistence.GetById(id)
+		require.NoError(t, err)
+		assert.Equal(t, task, current)
+		versions, err = persistence.GetVersions(id)
+		require.NoError(t, err)
+		require.Len(t, versions, 4)
+		assert.Equal(t, task, versions[3])
+
+		_, err = persistence.Rollback(id, 7)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "version 7 of task "+id+" not found")
+		_, err = persistence.Rollback("unknown", 1)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "specified task id unknown not found")
+		_, err = persistence.GetVersions("unknown")
+		require.Error(t, err)
+
+		// the versions of a deleted task are deleted with it
+		require.NoError(t, persistence.Delete(id))
+		id, err = persistence.Create(taskPkg.CreateTestTask("", "Count Nuclei", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "200"))
+		require.NoError(t, err)
+		versions, err = persistence.GetVersions(id)
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		assert.Equal(t, "Count Nuclei", versions[0].Description)
+	})
+}
+
+func TestPersistence_Delete(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		id, err := persistence.Create(taskPkg.CreateTestTask("", "Count Cells", `{ "==" : [ { "var" : "Id" }, "1" ] }`, "100"))
+		require.NoError(t, err)
+
+		require.NoError(t, persistence.Delete(id))
+		_, err = persistence.GetById(id)
+		require.Error(t, err)
+		tasks, err := persistence.GetAll()
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+
+		assert.Equal(t, taskPkg.ErrNoTaskDeleted, persistence.Delete(id))
+		assert.Equal(t, taskPkg.ErrTaskIdEmpty, persistence.Delete(""))
+	})
+}
+
+func TestPersistence_Dispatches(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		dispatches, err := persistence.GetDispatches()
+		require.NoError(t, err)
+		assert.Empty(t, dispatches)
+
+		first := types.Dispatch{JobId: "1", TaskId: "count", PipelineId: "count-cells", Queued: 1, InFlight: true}
+		second := types.Dispatch{JobId: "2", TaskId: "count", PipelineId: "count-cells", Queued: 2}
+		require.NoError(t, persistence.PutDispatch(first))
+		require.NoError(t, persistence.PutDispatch(second))
+		dispatches, err = persistence.GetDispatches()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []types.Dispatch{first, second}, dispatches)
+
+		// the dispatch of a job and task is replaced
+		second.InFlight = true
+		require.NoError(t, persistence.PutDispatch(second))
+		require.NoError(t, persistence.DeleteDispatch(first.JobId, first.TaskId))
+		dispatches, err = persistence.GetDispatches()
+		require.NoError(t, err)
+		assert.Equal(t, []types.Dispatch{second}, dispatches)
+
+		// deleting a dispatch that is not stored is not an error
+		require.NoError(t, persistence.DeleteDispatch(first.JobId, first.TaskId))
+	})
+}
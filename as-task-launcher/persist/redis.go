@@ -41,6 +41,7 @@ func NewRedisDB(lc logger.LoggingClient, redisClient redis.DBClient) (Persistenc
 
 // Create stores a new task object in the Redis DB
 // Task is stored in the {key,field,value} format where key is "task", field is task.Id & value is {taskObject}
+// Every version of the task is appended to the list stored under the key "task|version:<task.Id>"
 func (rdb RedisDB) Create(task types.Task) (string, error) {
 
 	if task.JobSelector == "" {
@@ -60,13 +61,18 @@ func (rdb RedisDB) Create(task types.Task) (string, error) {
 
 	task.Id = uuid.NewString()
 	task.SetLastUpdated()
+	task.Created = task.LastUpdated
+	task.Version = 1
 
 	jsonTask, err := json.Marshal(task)
 	if err != nil {
 		return "", werrors.WrapErr(err, taskPkg.ErrMarshallingTask)
 	}
 
-	_, err = conn.Do(redis.HSET, redis.KeyTask, task.Id, jsonTask)
+	conn.Send(redis.MULTI)
+	conn.Send(redis.HSET, redis.KeyTask, task.Id, jsonTask)
+	conn.Send(redis.RPUSH, redis.CreateKey(redis.KeyTaskVersion, task.Id), jsonTask)
+	_, err = conn.Do(redis.EXEC)
 	if err != nil {
 		return "", werrors.WrapErr(err, taskPkg.ErrTaskCreation)
 	}
@@ -75,6 +81,8 @@ func (rdb RedisDB) Create(task types.Task) (string, error) {
 
 }
 
+// Update replaces the values of the existing task with the non-empty values of the given task,
+// storing the result as a new version of the task
 func (rdb RedisDB) Update(task types.Task) error {
 	// check id exists - not empty
 	if task.Id == "" {
@@ -114,7 +122,19 @@ func (rdb RedisDB) Update(task types.Task) error {
 		return werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
 	}
 
+	versionKey := redis.CreateKey(redis.KeyTaskVersion, task.Id)
+	var jsonFirstVersion []byte
+	if existingTask.Version == 0 {
+		// a task stored before the tasks were versioned becomes the first version of its history
+		existingTask.Version = 1
+		jsonFirstVersion, err = json.Marshal(existingTask)
+		if err != nil {
+			return werrors.WrapErr(err, taskPkg.ErrMarshallingTask)
+		}
+	}
+
 	existingTask.ReplaceTask(task)
+	existingTask.Version++
 
 	jsonTask, err := json.Marshal(existingTask)
 	if err != nil {
@@ -123,10 +143,14 @@ func (rdb RedisDB) Update(task types.Task) error {
 
 	conn.Send(redis.MULTI)
 	conn.Send(redis.HSET, redis.KeyTask, task.Id, jsonTask)
+	if jsonFirstVersion != nil {
+		conn.Send(redis.RPUSH, versionKey, jsonFirstVersion)
+	}
+	conn.Send(redis.RPUSH, versionKey, jsonTask)
 	conn.Send(redis.SET, lockKey, "")
 	reply, err := redigo.Values(conn.Do(redis.EXEC))
 	if err != nil {
-		return werrors.WrapErr(err, taskPkg.ErrTaskCreation)
+		return werrors.WrapErr(err, taskPkg.ErrTaskUpdate)
 	}
 	if reply[len(reply)-1] != "OK" {
 		return fmt.Errorf("unexpected value in reply got %s instead of OK", reply[len(reply)-1])
@@ -143,11 +167,17 @@ func (rdb RedisDB) Delete(id string) error {
 	conn := rdb.redisClient.GetConnection()
 	defer func() { _ = conn.Close() }()
 
-	numDeletes, err := redigo.Int(conn.Do(redis.HDEL, redis.KeyTask, id))
+	// the task and its version history are removed together, and the lock is removed so that an update
+	// of the task in progress fails instead of storing the task again
+	conn.Send(redis.MULTI)
+	conn.Send(redis.HDEL, redis.KeyTask, id)
+	conn.Send(redis.DEL, redis.CreateKey(redis.KeyTaskVersion, id))
+	conn.Send(redis.DEL, redis.CreateKey(redis.KeyLock, redis.KeyTask, id))
+	reply, err := redigo.Ints(conn.Do(redis.EXEC))
 	if err != nil {
 		return werrors.WrapErr(err, taskPkg.ErrDeleteTask)
 	}
-	if numDeletes == 0 {
+	if len(reply) == 0 || reply[0] == 0 {
 		return taskPkg.ErrNoTaskDeleted
 	}
 
@@ -214,6 +244,156 @@ func (rdb RedisDB) GetAll() ([]types.Task, error) {
 	return tasks, nil
 }
 
+// GetVersions returns every version of the task from the Redis DB, from the first to the current one
+func (rdb RedisDB) GetVersions(id string) ([]types.Task, error) {
+	current, err := rdb.GetById(id)
+	if err != nil {
+		return []types.Task{}, err
+	}
+
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	return getVersions(conn, current)
+}
+
+// GetVersion returns the given version of the task from the Redis DB, or the current version when the version is 0
+func (rdb RedisDB) GetVersion(id string, version int64) (types.Task, error) {
+	versions, err := rdb.GetVersions(id)
+	if err != nil {
+		return types.Task{}, err
+	}
+	return getVersion(versions, id, version)
+}
+
+// Rollback makes a previous version of the task the current one, as a new version of the task
+func (rdb RedisDB) Rollback(id string, version int64) (types.Task, error) {
+	if id == "" {
+		return types.Task{}, taskPkg.ErrTaskIdEmpty
+	}
+
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	lockKey := redis.CreateKey(redis.KeyLock, redis.KeyTask, id)
+	_, err := conn.Do(redis.WATCH, lockKey)
+	if err != nil {
+		return types.Task{}, werrors.WrapMsgf(err, pkg.ErrFmtRedisWatchFailed, id)
+	}
+
+	data, err := redigo.Bytes(conn.Do(redis.HGET, redis.KeyTask, id))
+	if err == redigo.ErrNil {
+		return types.Task{}, werrors.WrapMsgf(err, taskPkg.ErrFmtTaskIdNotFound, id)
+	}
+	if err != nil {
+		return types.Task{}, werrors.WrapErr(err, taskPkg.ErrTaskRetrieval)
+	}
+
+	var task types.Task
+	err = json.Unmarshal(data, &task)
+	if err != nil {
+		return types.Task{}, werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
+	}
+
+	versions, err := getVersions(conn, task)
+	if err != nil {
+		return types.Task{}, err
+	}
+	previous, found := findVersion(versions, version)
+	if !found {
+		return types.Task{}, fmt.Errorf(taskPkg.ErrFmtTaskVersionNotFound, version, id)
+	}
+
+	task.RestoreVersion(previous)
+
+	jsonTask, err := json.Marshal(task)
+	if err != nil {
+		return types.Task{}, werrors.WrapErr(err, taskPkg.ErrMarshallingTask)
+	}
+
+	conn.Send(redis.MULTI)
+	conn.Send(redis.HSET, redis.KeyTask, id, jsonTask)
+	conn.Send(redis.RPUSH, redis.CreateKey(redis.KeyTaskVersion, id), jsonTask)
+	conn.Send(redis.SET, lockKey, "")
+	reply, err := redigo.Values(conn.Do(redis.EXEC))
+	if err != nil {
+		return types.Task{}, werrors.WrapErr(err, taskPkg.ErrTaskRollback)
+	}
+	if reply[len(reply)-1] != "OK" {
+		return types.Task{}, fmt.Errorf("unexpected value in reply got %s instead of OK", reply[len(reply)-1])
+	}
+	return task, nil
+}
+
+// getVersions reads the version history of the task, a task stored before the tasks were versioned
+// only has its current version
+func getVersions(conn redigo.Conn, current types.Task) ([]types.Task, error) {
+	versionsJson, err := redigo.ByteSlices(conn.Do(redis.LRANGE, redis.CreateKey(redis.KeyTaskVersion, current.Id), 0, -1))
+	if err != nil && err != redigo.ErrNil {
+		return []types.Task{}, werrors.WrapErr(err, taskPkg.ErrTaskRetrieval)
+	}
+	if len(versionsJson) == 0 {
+		return []types.Task{current}, nil
+	}
+
+	versions := make([]types.Task, len(versionsJson))
+	for i, versionJson := range versionsJson {
+		err = json.Unmarshal(versionJson, &versions[i])
+		if err != nil {
+			return []types.Task{}, werrors.WrapErr(err, taskPkg.ErrUnmarshallingTask)
+		}
+	}
+	return versions, nil
+}
+
+// PutDispatch stores the dispatch in the hash under the key "task|dispatch", with the dispatch key as field
+func (rdb RedisDB) PutDispatch(dispatch types.Dispatch) error {
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	jsonDispatch, err := json.Marshal(dispatch)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrMarshallingDispatch)
+	}
+	_, err = conn.Do(redis.HSET, redis.KeyDispatch, dispatch.Key(), jsonDispatch)
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrDispatchUpdate)
+	}
+	return nil
+}
+
+// DeleteDispatch removes the dispatch of the job to the pipeline of the task from the Redis DB
+func (rdb RedisDB) DeleteDispatch(jobId string, taskId string) error {
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	_, err := conn.Do(redis.HDEL, redis.KeyDispatch, types.DispatchKey(jobId, taskId))
+	if err != nil {
+		return werrors.WrapErr(err, taskPkg.ErrDispatchUpdate)
+	}
+	return nil
+}
+
+// GetDispatches returns the dispatches of the jobs queued or in flight from the Redis DB
+func (rdb RedisDB) GetDispatches() ([]types.Dispatch, error) {
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	dispatchesJson, err := redigo.ByteSlices(conn.Do(redis.HVALS, redis.KeyDispatch))
+	if err != nil && err != redigo.ErrNil {
+		return []types.Dispatch{}, werrors.WrapErr(err, taskPkg.ErrDispatchRetrieval)
+	}
+
+	dispatches := make([]types.Dispatch, len(dispatchesJson))
+	for i, dispatchJson := range dispatchesJson {
+		err = json.Unmarshal(dispatchJson, &dispatches[i])
+		if err != nil {
+			return []types.Dispatch{}, werrors.WrapErr(err, taskPkg.ErrDispatchRetrieval)
+		}
+	}
+	return dispatches, nil
+}
+
 func (rdb RedisDB) Disconnect() error {
 	return rdb.redisClient.Disconnect()
 }
@@ -61,7 +61,10 @@ func TestRedisDB_Create(t *testing.T) {
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
-			mockConn.On("Do", redis.HSET, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.ConnDoErr)
+			mockConn.On("Send", redis.MULTI).Return(nil)
+			mockConn.On("Send", redis.HSET, redis.KeyTask, mock.Anything, mock.Anything).Return(nil)
+			mockConn.On("Send", redis.RPUSH, mock.Anything, mock.Anything).Return(nil)
+			mockConn.On("Do", redis.EXEC).Return(nil, test.ConnDoErr)
 
 			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
 			require.NoError(t, err)
@@ -73,14 +76,15 @@ func TestRedisDB_Create(t *testing.T) {
 				// Call mockConn.asserts for Do, only when error is generated from it
 				if test.ConnDoErr != nil {
 					mockRedisClient.AssertCalled(t, "GetConnection", mock.Anything)
-					mockConn.AssertCalled(t, "Do", redis.HSET, mock.Anything, mock.Anything, mock.Anything)
+					mockConn.AssertCalled(t, "Do", redis.EXEC)
 				}
 
 			} else {
 				require.NoError(t, actualErr)
 				assert.NotEmpty(t, actualTaskId)
 				mockRedisClient.AssertCalled(t, "GetConnection", mock.Anything)
-				mockConn.AssertCalled(t, "Do", redis.HSET, mock.Anything, mock.Anything, mock.Anything)
+				mockConn.AssertCalled(t, "Send", redis.HSET, redis.KeyTask, actualTaskId, mock.Anything)
+				mockConn.AssertCalled(t, "Send", redis.RPUSH, redis.CreateKey(redis.KeyTaskVersion, actualTaskId), mock.Anything)
 			}
 
 			mockRedisClient.AssertCalled(t, "TestConnection", mock.Anything)
@@ -218,12 +222,13 @@ func TestRedisDB_Delete(t *testing.T) {
 		Name          string
 		TaskId        string
 		NumDeletes    int
+		ExecErr       error
 		ExpectedError error
 	}{
-		{"happy path", "1", 1, nil},
-		{"empty id", "", 0, taskPkg.ErrTaskIdEmpty},
-		{"delete fail", "1", 0, taskPkg.ErrDeleteTask},
-		{"no task deleted", "1", 0, taskPkg.ErrNoTaskDeleted},
+		{"happy path", "1", 1, nil, nil},
+		{"empty id", "", 0, nil, taskPkg.ErrTaskIdEmpty},
+		{"delete fail", "1", 0, redigo.ErrPoolExhausted, taskPkg.ErrDeleteTask},
+		{"no task deleted", "1", 0, nil, taskPkg.ErrNoTaskDeleted},
 	}
 
 	for _, test := range tests {
@@ -234,15 +239,21 @@ func TestRedisDB_Delete(t *testing.T) {
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
-			mockConn.On("Do", redis.HDEL, redis.KeyTask, test.TaskId).Return(test.NumDeletes, test.ExpectedError)
+			mockConn.On("Send", redis.MULTI).Return(nil)
+			mockConn.On("Send", redis.HDEL, redis.KeyTask, test.TaskId).Return(nil)
+			mockConn.On("Send", redis.DEL, redis.CreateKey(redis.KeyTaskVersion, test.TaskId)).Return(nil)
+			mockConn.On("Send", redis.DEL, redis.CreateKey(redis.KeyLock, redis.KeyTask, test.TaskId)).Return(nil)
+			mockConn.On("Do", redis.EXEC).Return([]interface{}{int64(test.NumDeletes), int64(1), int64(0)}, test.ExecErr)
 
 			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
 			require.NoError(t, err)
 			actualErr := persistence.Delete(test.TaskId)
 			if test.ExpectedError != nil {
+				require.Error(t, actualErr)
 				require.Contains(t, actualErr.Error(), test.ExpectedError.Error())
 				return
 			}
+			require.NoError(t, actualErr)
 
 			mockRedisClient.AssertExpectations(t)
 			mockConn.AssertExpectations(t)
@@ -283,7 +294,7 @@ func TestRedisDB_Update(t *testing.T) {
 		{"task exists error", validTask, []uint8("0"), redigo.ErrPoolExhausted, jsonValid, nil, nil, expectedReply, taskPkg.ErrTaskRetrieval},
 		{"task get error", validTask, []uint8("1"), nil, jsonValid, redigo.ErrPoolExhausted, nil, expectedReply, taskPkg.ErrTaskRetrieval},
 		{"task get not found", validTask, []uint8("1"), nil, jsonValid, redigo.ErrNil, nil, expectedReply, fmt.Errorf(taskPkg.ErrFmtTaskIdNotFound, validTask.Id)},
-		{"task update error", validTask, []uint8("1"), nil, jsonValid, nil, nil, expectedReply, taskPkg.ErrTaskCreation},
+		{"task update error", validTask, []uint8("1"), nil, jsonValid, nil, nil, expectedReply, taskPkg.ErrTaskUpdate},
 		{"lock db error", validTask, []uint8("1"), nil, jsonValid, nil, redigo.ErrPoolExhausted, expectedReply, fmt.Errorf(pkg.ErrFmtRedisWatchFailed, validTask.Id)},
 		{"bad reply error", validTask, []uint8("1"), nil, jsonValid, nil, nil, badReply, fmt.Errorf("unexpected value in reply")},
 		{"unmarshal error", validTask, []uint8("1"), nil, []uint8("bogus"), nil, nil, expectedReply, taskPkg.ErrUnmarshallingTask},
@@ -304,6 +315,7 @@ func TestRedisDB_Update(t *testing.T) {
 			mockConn.On("Do", redis.HGET, redis.KeyTask, test.Task.Id).Return(test.HGetTaskArg, test.MockGetErr)
 			mockConn.On("Send", redis.MULTI).Return(nil)
 			mockConn.On("Send", redis.HSET, redis.KeyTask, test.Task.Id, mock.Anything).Return(nil)
+			mockConn.On("Send", redis.RPUSH, redis.CreateKey(redis.KeyTaskVersion, test.Task.Id), mock.Anything).Return(nil)
 			mockConn.On("Send", redis.SET, lockKey, "").Return(nil)
 			mockConn.On("Do", redis.EXEC).Return(test.MockExecReply, test.ExpectedErr)
 
@@ -5,7 +5,11 @@
 
 package pkg
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 // Common task errors
 var (
@@ -13,6 +17,8 @@ var (
 	ErrMarshallingTask           = fmt.Errorf("failed to marshal tasks")
 	ErrUnmarshallingTask         = fmt.Errorf("failed to unmarshal tasks")
 	ErrTaskCreation              = fmt.Errorf("failed to create task")
+	ErrTaskUpdate                = fmt.Errorf("failed to update task")
+	ErrTaskRollback              = fmt.Errorf("failed to roll back task")
 	ErrTaskRetrieval             = fmt.Errorf("failed to retrieve task(s)")
 	ErrTaskEmptyJobSelector      = fmt.Errorf("job selector field is empty")
 	ErrTaskEmptyPipelineId       = fmt.Errorf("pipeline id field is empty")
@@ -25,4 +31,27 @@ var (
 	ErrNoTaskDeleted             = fmt.Errorf("no task was deleted")
 	ErrTaskInvalid               = fmt.Errorf("failed to validate task")
 	ErrTaskRetrieving            = fmt.Errorf("failed to retrieve task from task launcher")
+	ErrFmtTaskChainLoop          = "follow-up task %s leads back to the task"
+	ErrFmtTaskVersionNotFound    = "version %d of task %s not found"
+	ErrMarshallingDispatch       = fmt.Errorf("failed to marshal job dispatch")
+	ErrDispatchUpdate            = fmt.Errorf("failed to update job dispatch")
+	ErrDispatchRetrieval         = fmt.Errorf("failed to retrieve job dispatches")
 )
+
+// InvalidTaskError is returned when the fields of a task that is created or updated are not valid
+type InvalidTaskError struct {
+	// Fields holds the reason each invalid field was rejected
+	Fields map[string]string
+}
+
+func (e *InvalidTaskError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s (%s)", name, e.Fields[name])
+	}
+	return "invalid task: " + strings.Join(names, "; ")
+}
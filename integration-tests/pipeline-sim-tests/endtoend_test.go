@@ -11,7 +11,9 @@ import (
 	"aicsd/pkg"
 	"aicsd/pkg/clients/job_repo"
 	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"io"
@@ -427,7 +429,15 @@ func TestResultsMQTT(t *testing.T) {
 		for {
 			select {
 			case msg := <-messageChan:
-				assert.Equal(t, string(msg.Payload()), "filename:gateway=/tmp/files/input/test-image-4.tiff; results:CellCount, 598")
+				var envelope types.ResultsEnvelope
+				require.NoError(t, json.Unmarshal(msg.Payload(), &envelope))
+				assert.Equal(t, types.ResultsEnvelopeVersion, envelope.Version)
+				assert.Equal(t, taskObj.Id, envelope.TaskId)
+				assert.Equal(t, "only-results", envelope.PipelineId)
+				assert.Equal(t, "gateway", envelope.InputFile.Hostname)
+				assert.Equal(t, "/tmp/files/input/test-image-4.tiff", path.Join(envelope.InputFile.DirName, envelope.InputFile.Name))
+				assert.Equal(t, "CellCount, 598", envelope.Results)
+				assert.Equal(t, map[string]interface{}{"CellCount": "598"}, envelope.ParsedResults)
 				return true
 			}
 		}
@@ -17,6 +17,23 @@ import (
 	"aicsd/pkg/types"
 )
 
+// StatusError is the error of a notification the data organizer responded to with a status other than OK
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("TransmitJob API status not OK: %s", e.Status)
+}
+
+// Rejected reports whether the data organizer rejected the notification itself, so that sending it again
+// will not succeed, as opposed to a timeout or a server error
+func (e *StatusError) Rejected() bool {
+	return e.StatusCode >= http.StatusBadRequest && e.StatusCode < http.StatusInternalServerError &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
 type ClientImpl struct {
 	baseUrl      string // this is the base url to the endpoint
 	fileHostname string
@@ -57,7 +74,7 @@ func (c *ClientImpl) NotifyNewFile(filename string) error {
 		return err
 	}
 	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusAlreadyReported {
-		return fmt.Errorf("TransmitJob API status not OK: %s", response.Status)
+		return &StatusError{StatusCode: response.StatusCode, Status: response.Status}
 	}
 	return nil
 }
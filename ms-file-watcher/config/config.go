@@ -9,7 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"aicsd/pkg/helpers"
 
@@ -19,6 +22,11 @@ import (
 const (
 	// these keys need to correspond to job values in the configuration.toml
 	jobKeys = "LabName,LabEquipment,Operator"
+
+	// ModeNotify watches a folder for the events of its new files
+	ModeNotify = "notify"
+	// ModePoll periodically scans a folder for new or changed files, for the network shares that deliver no events
+	ModePoll = "poll"
 )
 
 type Configuration struct {
@@ -27,32 +35,76 @@ type Configuration struct {
 	FileJob           map[string]string
 	FileHostname      string
 	FileExclusionList []string
+	// StabilityQuietPeriod is how long the size and modification time of a new file must stay unchanged before the
+	// file is considered done being written, zero hands the files to the data organizer as soon as they are created
+	StabilityQuietPeriod time.Duration
+	// DoneMarkerSuffix is the suffix of the marker file that must exist next to a new file before the file is
+	// considered done being written, e.g. ".done" for image.tiff.done. No marker is needed when it is empty.
+	DoneMarkerSuffix string
+	// PollIndexFile is the file the files seen in the polled folders are kept in, so that the unchanged files are not
+	// sent to the data organizer again after a restart. The seen files are only kept in memory when it is empty.
+	PollIndexFile string
+	// OutboxFile is the database file the new file notifications that could not be sent to the data organizer are
+	// kept in until they are sent again. The notifications that could not be sent are dropped when it is empty.
+	OutboxFile string
+	// OutboxRetryInterval is how long to wait before sending a notification of the outbox again the first time,
+	// it doubles with each failed attempt
+	OutboxRetryInterval time.Duration
+	// OutboxMaxAttempts is the number of failed attempts after which a notification of the outbox is moved to the
+	// dead letters, zero keeps sending it until it goes through
+	OutboxMaxAttempts int
 	App               App
 }
 type App struct {
 	UpdatableSettings UpdatableSettings
 }
 type UpdatableSettings struct {
+	// FoldersToWatch is the comma separated list of folders watched for new files
+	FoldersToWatch    string
 	WatchSubfolders   bool
 	FileExclusionList string
+	// FolderRules select the files of each watched folder that are sent to the data organizer, by rule name
+	FolderRules map[string]FolderRule
+	// FolderModes select how each watched folder is watched for new files, by mode name
+	FolderModes map[string]FolderMode
+}
+
+// FolderMode selects how a folder of the FoldersToWatch is watched for new files, the folders without a mode
+// are watched for the events of their new files
+type FolderMode struct {
+	// Folder is the watched folder the mode applies to
+	Folder string
+	// Mode is "notify" to watch the folder for events or "poll" to periodically scan it, as for a network share
+	Mode string
+	// PollInterval is how often a polled folder is scanned, e.g. "30s"
+	PollInterval string
+}
+
+// FolderRule selects the files of a folder and its subfolders that are sent to the data organizer, on top of the
+// FileExclusionList. The globs and extensions are comma separated lists and an empty list, regex or size sets no limit.
+// The rule of the deepest folder applies when the folders of several rules contain a file.
+type FolderRule struct {
+	// Folder is the folder the rule applies to
+	Folder string
+	// IncludeGlobs are the file name patterns, e.g. "plate-*.tiff", one of which the file name must match
+	IncludeGlobs string
+	// ExcludeGlobs are the file name patterns the file name must not match
+	ExcludeGlobs string
+	// IncludeRegex is a regular expression the file name must match
+	IncludeRegex string
+	// ExcludeRegex is a regular expression the file name must not match
+	ExcludeRegex string
+	// Extensions are the allowed file extensions, e.g. ".tiff,.tif", compared regardless of case
+	Extensions string
+	// MinSize is the minimum size of the file in bytes
+	MinSize int64
+	// MaxSize is the maximum size of the file in bytes
+	MaxSize int64
 }
 
 func New(service interfaces.ApplicationService) (*Configuration, error) {
 	config := Configuration{}
 	var err error
-	config.FoldersToWatch, err = service.GetAppSettingStrings("FoldersToWatch")
-	if err != nil {
-		return nil, err
-	}
-	if len(config.FoldersToWatch) == 0 {
-		return nil, errors.New("must specify at least one folder to watch. None specified")
-	}
-	for _, dir := range config.FoldersToWatch {
-		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			return nil, fmt.Errorf("watch Folder Directory Not Found: %s", dir)
-		}
-	}
-
 	config.DataOrgBaseUrl, err = helpers.GetUrlFromAppSetting(service, "DataOrg", "http", false)
 	if err != nil {
 		return nil, err
@@ -74,9 +126,96 @@ func New(service interfaces.ApplicationService) (*Configuration, error) {
 		return nil, err
 	}
 
+	quietPeriod, err := helpers.GetAppSetting(service, "StabilityQuietPeriod", true)
+	if err != nil {
+		return nil, err
+	}
+	if quietPeriod != "" {
+		config.StabilityQuietPeriod, err = time.ParseDuration(quietPeriod)
+		if err != nil || config.StabilityQuietPeriod < 0 {
+			return nil, fmt.Errorf("could not parse non-negative duration for stability quiet period, got %s", quietPeriod)
+		}
+	}
+
+	config.DoneMarkerSuffix, err = helpers.GetAppSetting(service, "DoneMarkerSuffix", true)
+	if err != nil {
+		return nil, err
+	}
+
+	config.PollIndexFile, err = helpers.GetAppSetting(service, "PollIndexFile", true)
+	if err != nil {
+		return nil, err
+	}
+
+	// the outbox is opt-in, an empty file leaves it disabled
+	config.OutboxFile, err = helpers.GetAppSetting(service, "OutboxFile", true)
+	if err != nil {
+		return nil, err
+	}
+	if config.OutboxFile != "" {
+		retryInterval, err := helpers.GetAppSetting(service, "OutboxRetryInterval", false)
+		if err != nil {
+			return nil, err
+		}
+		config.OutboxRetryInterval, err = time.ParseDuration(retryInterval)
+		if err != nil || config.OutboxRetryInterval <= 0 {
+			return nil, fmt.Errorf("could not parse positive duration for outbox retry interval, got %s", retryInterval)
+		}
+
+		maxAttempts, err := helpers.GetAppSetting(service, "OutboxMaxAttempts", false)
+		if err != nil {
+			return nil, err
+		}
+		config.OutboxMaxAttempts, err = strconv.Atoi(maxAttempts)
+		if err != nil || config.OutboxMaxAttempts < 0 {
+			return nil, fmt.Errorf("could not parse non-negative integer for outbox max attempts, got %s", maxAttempts)
+		}
+	}
+
 	return &config, nil
 }
 
+// SplitFolders splits the comma separated FoldersToWatch setting into its folders
+func SplitFolders(setting string) []string {
+	var folders []string
+	for _, folder := range strings.Split(setting, ",") {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// ValidateFolders checks that there is at least one folder to watch and that each folder is an existing directory.
+// It returns the cleaned folders without duplicates.
+func ValidateFolders(folders []string) ([]string, error) {
+	var validated []string
+	seen := make(map[string]bool)
+	for _, folder := range folders {
+		folder = strings.TrimSpace(folder)
+		if folder == "" {
+			continue
+		}
+		folder = filepath.Clean(folder)
+		if seen[folder] {
+			continue
+		}
+		info, err := os.Stat(folder)
+		if err != nil {
+			return nil, fmt.Errorf("watch Folder Directory Not Found: %s", folder)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("watch Folder is not a directory: %s", folder)
+		}
+		seen[folder] = true
+		validated = append(validated, folder)
+	}
+	if len(validated) == 0 {
+		return nil, errors.New("must specify at least one folder to watch. None specified")
+	}
+	return validated, nil
+}
+
 func (c *App) UpdateFromRaw(rawConfig interface{}) bool {
 	configuration, ok := rawConfig.(*App)
 	if !ok {
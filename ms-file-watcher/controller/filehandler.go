@@ -13,32 +13,56 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	data_organizer "aicsd/ms-file-watcher/clients/data_organizer"
 	"aicsd/ms-file-watcher/config"
+	"aicsd/ms-file-watcher/persist"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
 	"github.com/fsnotify/fsnotify"
 )
 
 type FileHandler struct {
-	lc                logger.LoggingClient
-	dataOrgClient     data_organizer.Client
+	lc            logger.LoggingClient
+	dataOrgClient data_organizer.Client
+	// outbox keeps the notifications that could not be sent to the data organizer, nil when there is no outbox
+	outbox            persist.Outbox
 	DependentServices wait.Services
 	Config            *config.Configuration
+	stability         *fileStability
+	// filters are the compiled FolderRules of the watched folders, guarded by the foldersMutex
+	filters []folderFilter
+	// watcher is the fsnotify watcher of the running WatchFolders goroutine
+	watcher *fsnotify.Watcher
+	// folderUpdates are the changes of the watched folders applied by the WatchFolders goroutine
+	folderUpdates chan folderUpdate
+	// foldersMutex guards the watched folders read by the requests and the filters replaced by the config updates
+	foldersMutex    sync.RWMutex
+	folders         []string
+	watchSubfolders bool
+	// pollIntervals are the scan intervals of the folders polled rather than watched for events
+	pollIntervals map[string]time.Duration
+	polled        []string
+	poller        *folderPoller
 }
 
-func New(lc logger.LoggingClient, dataOrgClient data_organizer.Client, Config *config.Configuration) *FileHandler {
+func New(lc logger.LoggingClient, dataOrgClient data_organizer.Client, outbox persist.Outbox, Config *config.Configuration) *FileHandler {
 	return &FileHandler{
 		lc:                lc,
 		dataOrgClient:     dataOrgClient,
+		outbox:            outbox,
 		DependentServices: wait.Services{wait.ServiceConsul},
 		Config:            Config,
+		stability:         newFileStability(Config.StabilityQuietPeriod, Config.DoneMarkerSuffix),
+		folderUpdates:     make(chan folderUpdate),
+		pollIntervals:     make(map[string]time.Duration),
+		poller:            newFolderPoller(Config.PollIndexFile),
 	}
 }
 
 // WatchFolders is a goroutine that is called to watch a set of folders for files to be created. When a file is created,
-// it is checked and if it is included, then the data organizer is notified.
+// it is checked and if it is included, then the data organizer is notified once the file is done being written.
 func (fh *FileHandler) WatchFolders(ctx context.Context, wg *sync.WaitGroup, config *config.Configuration) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -47,66 +71,63 @@ func (fh *FileHandler) WatchFolders(ctx context.Context, wg *sync.WaitGroup, con
 	}
 	defer watcher.Close()
 
-	for _, dir := range config.FoldersToWatch {
-		err = watcher.Add(dir)
-		if err != nil {
-			fh.lc.Errorf("WatchFolders could not add folder to watcher: %s", dir)
-			return
-		}
-		fh.walkDirectory(dir, config.App.UpdatableSettings.WatchSubfolders, config.FileExclusionList)
-		fh.lc.Debugf("Folder %s walked", dir)
+	// the files being written are checked for stability on every tick
+	var stabilityCheck <-chan time.Time
+	if fh.stability.enabled() {
+		ticker := time.NewTicker(fh.stability.checkInterval())
+		defer ticker.Stop()
+		stabilityCheck = ticker.C
+	}
+
+	// the folders are polled every tick that one of them is due
+	pollCheck := time.NewTicker(pollCheckInterval)
+	defer pollCheck.Stop()
+	err = fh.poller.load()
+	if err != nil {
+		fh.lc.Errorf("Could not load the index of the polled folders, all their files are handled as new files: %s", err.Error())
 	}
 
+	fh.foldersMutex.Lock()
+	fh.watcher = watcher
+	fh.foldersMutex.Unlock()
+	fh.applyFolderUpdate(folderUpdate{folders: config.FoldersToWatch, watchSubfolders: config.App.UpdatableSettings.WatchSubfolders})
+
 	for {
 		select {
 		case <-ctx.Done():
+			fh.foldersMutex.Lock()
+			fh.watcher = nil
+			fh.foldersMutex.Unlock()
 			watcher.Close()
 			wg.Done()
 			fh.lc.Info("Watch Folders Exiting")
 			return
+		case update := <-fh.folderUpdates:
+			fh.applyFolderUpdate(update)
+			close(update.done)
+		case now := <-pollCheck.C:
+			fh.pollFolders(now.UTC())
+		case now := <-stabilityCheck:
+			for _, filename := range fh.stability.stable(now.UTC()) {
+				fh.poller.handedOver(filename)
+				fh.notifyNewFile(filename)
+			}
 		case event := <-watcher.Events:
+			if event.Op&fsnotify.Write == fsnotify.Write {
+				fh.stability.written(event.Name, time.Now().UTC())
+			}
 			if event.Op&fsnotify.Create == fsnotify.Create {
-				// TODO: resolve the fact that the file may not be written or closed when it is created!
 				fileInfo, err := os.Stat(event.Name)
 				if err != nil {
 					fh.lc.Errorf("Error checking new file/folder info %s: %s", event.Name, err.Error())
 					continue
 				}
 				if !fileInfo.IsDir() {
-					excluded := false
-					if len(config.FileExclusionList) != 0 {
-						_, filename := filepath.Split(event.Name)
-
-						for _, file := range config.FileExclusionList {
-							if strings.Contains(filename, file) {
-								excluded = true
-								break
-							}
-						}
-					}
-					if !excluded {
-						fh.lc.Debugf("Create: %s: %s", event.Op, event.Name)
-						// todo add job to the notify new file
-						err := fh.dataOrgClient.NotifyNewFile(event.Name)
-						if err != nil {
-							fh.lc.Errorf("Error sending new file notification for file %s: %s", event.Name, err.Error())
-							continue
-						} else {
-							fh.lc.Debugf("Sent new file notification for %s", event.Name)
-						}
-					} else {
-						fh.lc.Debugf("Ignoring specified file: %s", event.Name)
-					}
-				} else if config.App.UpdatableSettings.WatchSubfolders {
+					fh.lc.Debugf("Create: %s: %s", event.Op, event.Name)
+					fh.handleNewFile(event.Name, config.FileExclusionList)
+				} else if fh.watchSubfolders {
 					fh.lc.Debugf("Found new folder at %s", event.Name)
-					err := watcher.Add(event.Name)
-					if err != nil {
-						fh.lc.Errorf("WatchFolders could not add folder to watcher: %s", event.Name)
-						return
-					}
-					fh.lc.Debugf("Walking path at: %s", event.Name)
-					fh.walkDirectory(event.Name, config.App.UpdatableSettings.WatchSubfolders, config.FileExclusionList)
-					fh.lc.Debugf("Folder %s walked", event.Name)
+					fh.watchFolder(event.Name, config.FileExclusionList)
 				}
 			}
 		case err := <-watcher.Errors:
@@ -115,9 +136,95 @@ func (fh *FileHandler) WatchFolders(ctx context.Context, wg *sync.WaitGroup, con
 	}
 }
 
+// handleNewFile checks a new file against the file exclusion list and sends the included file to the data organizer.
+// When the write stability is checked, the file is held until it is done being written instead, and the marker of
+// a file stands for the file it is named after.
+func (fh *FileHandler) handleNewFile(filename string, fileExclusionList []string) {
+	if fh.stability.isMarker(filename) {
+		filename = fh.stability.markedFile(filename)
+	}
+	_, name := filepath.Split(filename)
+	for _, entry := range fileExclusionList {
+		if strings.Contains(name, entry) {
+			fh.lc.Debugf("Ignoring specified file: %s", filename)
+			return
+		}
+	}
+	if filter := fh.filterForFile(filename); filter != nil {
+		if allowed, reason := filter.allowsName(name); !allowed {
+			fh.lc.Debugf("Ignoring file %s for folder rule %s: %s", filename, filter.name, reason)
+			return
+		}
+	}
+	if fh.stability.enabled() {
+		fh.lc.Debugf("Waiting for file %s to finish writing", filename)
+		fh.stability.track(filename, time.Now().UTC())
+		return
+	}
+	fh.notifyNewFile(filename)
+}
+
+// notifyNewFile sends the new file notification to the data organizer, unless the size of the file is out of the
+// limits of its folder rule. The size is only checked once the file is done being written.
+func (fh *FileHandler) notifyNewFile(filename string) {
+	if filter := fh.filterForFile(filename); filter != nil && (filter.minSize > 0 || filter.maxSize > 0) {
+		fileInfo, err := os.Stat(filename)
+		if err != nil {
+			fh.lc.Errorf("Error checking the size of new file %s: %s", filename, err.Error())
+			return
+		}
+		if !filter.allowsSize(fileInfo.Size()) {
+			fh.lc.Debugf("Ignoring file %s for folder rule %s: size %d out of limits", filename, filter.name, fileInfo.Size())
+			return
+		}
+	}
+	err := fh.dataOrgClient.NotifyNewFile(filename)
+	if err != nil {
+		// don't stop after erroring out here in case it is just an issue with a particular file
+		fh.lc.Errorf("Error sending new file notification for file %s: %s", filename, err.Error())
+		if fh.outbox == nil {
+			return
+		}
+		queueErr := fh.queueNotification(filename, time.Now().UTC(), err)
+		if queueErr != nil {
+			fh.lc.Errorf("Could not add the notification of file %s to the outbox: %s", filename, queueErr.Error())
+			return
+		}
+		if !rejected(err) {
+			fh.lc.Infof("Added the notification of file %s to the outbox to be sent again", filename)
+		}
+		return
+	}
+	fh.lc.Debugf("Sent new file notification for %s", filename)
+}
+
+// pollFolders scans the polled folders that are due for new or changed files, which are checked and sent to
+// the data organizer the same way as the files found walking a folder
+func (fh *FileHandler) pollFolders(now time.Time) {
+	due := fh.poller.due(now)
+	if len(due) == 0 {
+		return
+	}
+	for _, folder := range due {
+		files, err := fh.poller.scan(folder, fh.watchSubfolders, fh.stability.isPending)
+		if err != nil {
+			fh.lc.Errorf("Could not scan polled folder %s: %s", folder, err.Error())
+			continue
+		}
+		for _, filename := range files {
+			fh.lc.Debugf("Found file %s while polling folder %s", filename, folder)
+			fh.handleNewFile(filename, fh.Config.FileExclusionList)
+		}
+	}
+	err := fh.poller.save()
+	if err != nil {
+		fh.lc.Errorf("Could not save the index of the polled folders: %s", err.Error())
+	}
+}
+
 // walkDirectory is a helper function that is called on startup to help iterate over all the files in a given folder.
 // Each file is pre-checked through a file-exclusion filter, the included files are sent to the data organizer,
-// so that it may decide if the file needs to be processed or not.
+// so that it may decide if the file needs to be processed or not. See handleNewFile.
 func (fh *FileHandler) walkDirectory(folder string, watchSubfolders bool, fileExclusionList []string) {
 	files, err := os.ReadDir(folder)
 	if err != nil {
@@ -126,49 +233,48 @@ func (fh *FileHandler) walkDirectory(folder string, watchSubfolders bool, fileEx
 	for _, file := range files {
 		filename := filepath.Join(folder, file.Name())
 		if !file.IsDir() {
-			excluded := false
-			if len(fileExclusionList) != 0 {
-				for _, entry := range fileExclusionList {
-					if strings.Contains(file.Name(), entry) {
-						excluded = true
-						break
-					}
-				}
-			}
-			if !excluded {
-				fh.lc.Debugf("Found file %s while walking directory", filename)
-				fh.lc.Debugf("Create: CREATE: %s", filename)
-				err := fh.dataOrgClient.NotifyNewFile(filename)
-				if err != nil {
-					fh.lc.Errorf("Error walking directory and sending new file notification for file %s: %s", filename, err.Error())
-					// don't return after erroring out here in case it is just an issue with a particular file
-				} else {
-					fh.lc.Debugf("Sent new file notification for %s", filename)
-				}
-			} else {
-				fh.lc.Debugf("Ignoring specified file: %s", filename)
-			}
+			fh.lc.Debugf("Found file %s while walking directory", filename)
+			fh.handleNewFile(filename, fileExclusionList)
 		} else if watchSubfolders {
 			fh.lc.Debugf("Found new folder at %s", file.Name())
-			watcher, err := fsnotify.NewWatcher()
-			if err != nil {
-				fh.lc.Errorf("New watcher could not be created: %s", err.Error())
-				return
-			}
-			defer watcher.Close()
-
-			fh.lc.Debugf("Walking path at: %s", filename)
-			err = watcher.Add(filename)
-			if err != nil {
-				fh.lc.Errorf("WalkDirectory could not add folder to watcher: %s", filename)
-				return
-			}
-			fh.walkDirectory(filename, watchSubfolders, fileExclusionList)
-			fh.lc.Debugf("Folder %s walked", filename)
+			fh.watchFolder(filename, fileExclusionList)
 		}
 	}
 }
 
+// UpdateFolderRules compiles the folder rules selecting the files of the watched folders.
+// The previous rules are kept when a rule is not valid.
+func (fh *FileHandler) UpdateFolderRules(rules map[string]config.FolderRule) error {
+	filters, err := newFolderFilters(rules)
+	if err != nil {
+		return err
+	}
+	fh.foldersMutex.Lock()
+	fh.filters = filters
+	fh.foldersMutex.Unlock()
+	return nil
+}
+
+// filterForFile returns the filter of the folder rule that applies to the file, nil if no rule applies
+func (fh *FileHandler) filterForFile(filename string) *folderFilter {
+	fh.foldersMutex.RLock()
+	defer fh.foldersMutex.RUnlock()
+	return filterForFile(fh.filters, filename)
+}
+
+// UpdateFolderModes parses the folder modes selecting the folders that are polled rather than watched for events.
+// The modes apply with the next change of the watched folders, and the previous modes are kept when a mode is not valid.
+func (fh *FileHandler) UpdateFolderModes(modes map[string]config.FolderMode) error {
+	intervals, err := parseFolderModes(modes)
+	if err != nil {
+		return err
+	}
+	fh.foldersMutex.Lock()
+	fh.pollIntervals = intervals
+	fh.foldersMutex.Unlock()
+	return nil
+}
+
 func (fh *FileHandler) ProcessConfigUpdates(rawWritableConfig interface{}) {
 
 	updated, ok := rawWritableConfig.(*config.UpdatableSettings)
@@ -185,9 +291,38 @@ func (fh *FileHandler) ProcessConfigUpdates(rawWritableConfig interface{}) {
 		return
 	}
 
-	if previous.UpdatableSettings.WatchSubfolders != updated.WatchSubfolders {
-		// TODO: React to this value changing
-		fh.lc.Infof("Watch Subfolders set to: %v ", updated.WatchSubfolders)
+	foldersChanged := previous.UpdatableSettings.WatchSubfolders != updated.WatchSubfolders ||
+		previous.UpdatableSettings.FoldersToWatch != updated.FoldersToWatch
+
+	if !reflect.DeepEqual(previous.UpdatableSettings.FolderModes, updated.FolderModes) {
+		err := fh.UpdateFolderModes(updated.FolderModes)
+		if err != nil {
+			fh.lc.Errorf("Folder modes not updated: %s", err.Error())
+		} else {
+			fh.lc.Infof("Folder modes set to: %v", updated.FolderModes)
+			foldersChanged = true
+		}
+	}
+
+	if foldersChanged {
+		folders, err := config.ValidateFolders(config.SplitFolders(updated.FoldersToWatch))
+		if err == nil {
+			err = fh.UpdateWatchedFolders(folders, updated.WatchSubfolders)
+		}
+		if err != nil {
+			fh.lc.Errorf("Watched folders not updated: %s", err.Error())
+		} else {
+			fh.lc.Infof("Folders To Watch set to: %v, Watch Subfolders set to: %v", folders, updated.WatchSubfolders)
+		}
+	}
+
+	if !reflect.DeepEqual(previous.UpdatableSettings.FolderRules, updated.FolderRules) {
+		err := fh.UpdateFolderRules(updated.FolderRules)
+		if err != nil {
+			fh.lc.Errorf("Folder rules not updated: %s", err.Error())
+		} else {
+			fh.lc.Infof("Folder rules set to: %v", updated.FolderRules)
+		}
 	}
 
 	if !reflect.DeepEqual(previous.UpdatableSettings.FileExclusionList, updated.FileExclusionList) {
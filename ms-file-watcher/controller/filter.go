@@ -0,0 +1,151 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aicsd/ms-file-watcher/config"
+)
+
+// folderFilter is the compiled FolderRule of a folder
+type folderFilter struct {
+	name         string
+	folder       string
+	includeGlobs []string
+	excludeGlobs []string
+	includeRegex *regexp.Regexp
+	excludeRegex *regexp.Regexp
+	extensions   map[string]bool
+	minSize      int64
+	maxSize      int64
+}
+
+// newFolderFilters compiles the folder rules into the filters of the folders, the filters of the deepest folders
+// first. It returns an error naming the rule of an invalid pattern.
+func newFolderFilters(rules map[string]config.FolderRule) ([]folderFilter, error) {
+	filters := make([]folderFilter, 0, len(rules))
+	for name, rule := range rules {
+		if rule.Folder == "" {
+			return nil, fmt.Errorf("folder rule %s has no folder", name)
+		}
+		if rule.MaxSize > 0 && rule.MinSize > rule.MaxSize {
+			return nil, fmt.Errorf("folder rule %s has a min size %d over its max size %d", name, rule.MinSize, rule.MaxSize)
+		}
+		filter := folderFilter{
+			name:         name,
+			folder:       filepath.Clean(rule.Folder),
+			includeGlobs: splitList(rule.IncludeGlobs),
+			excludeGlobs: splitList(rule.ExcludeGlobs),
+			minSize:      rule.MinSize,
+			maxSize:      rule.MaxSize,
+		}
+		for _, glob := range append(filter.includeGlobs, filter.excludeGlobs...) {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("folder rule %s has an invalid glob %s: %s", name, glob, err.Error())
+			}
+		}
+		var err error
+		if rule.IncludeRegex != "" {
+			filter.includeRegex, err = regexp.Compile(rule.IncludeRegex)
+			if err != nil {
+				return nil, fmt.Errorf("folder rule %s has an invalid include regex: %s", name, err.Error())
+			}
+		}
+		if rule.ExcludeRegex != "" {
+			filter.excludeRegex, err = regexp.Compile(rule.ExcludeRegex)
+			if err != nil {
+				return nil, fmt.Errorf("folder rule %s has an invalid exclude regex: %s", name, err.Error())
+			}
+		}
+		if extensions := splitList(rule.Extensions); len(extensions) > 0 {
+			filter.extensions = make(map[string]bool, len(extensions))
+			for _, extension := range extensions {
+				filter.extensions[normalizeExtension(extension)] = true
+			}
+		}
+		filters = append(filters, filter)
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		if len(filters[i].folder) != len(filters[j].folder) {
+			return len(filters[i].folder) > len(filters[j].folder)
+		}
+		return filters[i].name < filters[j].name
+	})
+	return filters, nil
+}
+
+// filterForFile returns the filter of the deepest folder containing the file, or nil if no rule applies to the file
+func filterForFile(filters []folderFilter, filename string) *folderFilter {
+	dir := filepath.Dir(filepath.Clean(filename))
+	for k := range filters {
+		folder := filters[k].folder
+		if dir == folder || strings.HasPrefix(dir, strings.TrimSuffix(folder, string(filepath.Separator))+string(filepath.Separator)) {
+			return &filters[k]
+		}
+	}
+	return nil
+}
+
+// allowsName checks the name of a file against the patterns and extensions of the filter,
+// and returns the reason the file is excluded when it is not allowed
+func (f *folderFilter) allowsName(name string) (bool, string) {
+	if f.extensions != nil && !f.extensions[normalizeExtension(filepath.Ext(name))] {
+		return false, "extension not allowed"
+	}
+	if len(f.includeGlobs) > 0 && !matchesGlob(f.includeGlobs, name) {
+		return false, "no include glob matched"
+	}
+	if f.includeRegex != nil && !f.includeRegex.MatchString(name) {
+		return false, "include regex not matched"
+	}
+	if matchesGlob(f.excludeGlobs, name) {
+		return false, "exclude glob matched"
+	}
+	if f.excludeRegex != nil && f.excludeRegex.MatchString(name) {
+		return false, "exclude regex matched"
+	}
+	return true, ""
+}
+
+// allowsSize checks the size of a file against the limits of the filter
+func (f *folderFilter) allowsSize(size int64) bool {
+	return size >= f.minSize && (f.maxSize <= 0 || size <= f.maxSize)
+}
+
+// matchesGlob checks if the name matches any of the globs
+func matchesGlob(globs []string, name string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExtension returns the extension in lower case with its leading dot
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if extension != "" && !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
+
+// splitList splits a comma separated list, leaving out the empty entries
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
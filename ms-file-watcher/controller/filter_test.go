@@ -0,0 +1,128 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"path/filepath"
+	"testing"
+
+	"aicsd/ms-file-watcher/clients/data_organizer/mocks"
+	"aicsd/ms-file-watcher/config"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFolderFilter_AllowsName(t *testing.T) {
+	filters, err := newFolderFilters(map[string]config.FolderRule{
+		"microscope": {
+			Folder:       "/tmp/foo/microscope/",
+			IncludeGlobs: "plate-*, well-*",
+			ExcludeGlobs: "*-preview.*",
+			ExcludeRegex: "^~",
+			Extensions:   "TIFF,.tif",
+		},
+		"reader": {Folder: "/tmp/foo/reader", IncludeRegex: `^run\d+\.csv$`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name            string
+		Filename        string
+		ExpectedRule    string
+		ExpectedAllowed bool
+	}{
+		{"included", "/tmp/foo/microscope/plate-1.tiff", "microscope", true},
+		{"included in subfolder", "/tmp/foo/microscope/day1/well-A1.TIF", "microscope", true},
+		{"extension not allowed", "/tmp/foo/microscope/plate-1.png", "microscope", false},
+		{"no include glob matched", "/tmp/foo/microscope/scan.tiff", "microscope", false},
+		{"exclude glob matched", "/tmp/foo/microscope/plate-1-preview.tiff", "microscope", false},
+		{"exclude regex matched", "/tmp/foo/microscope/~plate-1.tiff", "microscope", false},
+		{"include regex matched", "/tmp/foo/reader/run12.csv", "reader", true},
+		{"include regex not matched", "/tmp/foo/reader/run12.csv.bak", "reader", false},
+		{"no rule", "/tmp/foo/microscope2/anything.png", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter := filterForFile(filters, test.Filename)
+			if test.ExpectedRule == "" {
+				assert.Nil(t, filter)
+				return
+			}
+			require.NotNil(t, filter)
+			assert.Equal(t, test.ExpectedRule, filter.name)
+			allowed, reason := filter.allowsName(filepath.Base(test.Filename))
+			assert.Equal(t, test.ExpectedAllowed, allowed, reason)
+		})
+	}
+}
+
+func TestFolderFilter_DeepestFolder(t *testing.T) {
+	filters, err := newFolderFilters(map[string]config.FolderRule{
+		"all":  {Folder: "/tmp/foo"},
+		"deep": {Folder: "/tmp/foo/microscope"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "deep", filterForFile(filters, "/tmp/foo/microscope/a/plate.tiff").name)
+	assert.Equal(t, "all", filterForFile(filters, "/tmp/foo/reader/run.csv").name)
+	assert.Nil(t, filterForFile(filters, "/tmp/bar/run.csv"))
+}
+
+func TestFolderFilter_AllowsSize(t *testing.T) {
+	filter := folderFilter{minSize: 10, maxSize: 100}
+	assert.False(t, filter.allowsSize(9))
+	assert.True(t, filter.allowsSize(10))
+	assert.True(t, filter.allowsSize(100))
+	assert.False(t, filter.allowsSize(101))
+	assert.True(t, (&folderFilter{minSize: 10}).allowsSize(1<<40), "no max size")
+}
+
+func TestNewFolderFilters_Invalid(t *testing.T) {
+	tests := []struct {
+		Name             string
+		Rule             config.FolderRule
+		ExpectedErrorMsg string
+	}{
+		{"no folder", config.FolderRule{}, "has no folder"},
+		{"invalid glob", config.FolderRule{Folder: "/tmp/foo", IncludeGlobs: "plate-[1"}, "invalid glob plate-[1"},
+		{"invalid include regex", config.FolderRule{Folder: "/tmp/foo", IncludeRegex: "plate-("}, "invalid include regex"},
+		{"invalid exclude regex", config.FolderRule{Folder: "/tmp/foo", ExcludeRegex: "plate-("}, "invalid exclude regex"},
+		{"min size over max size", config.FolderRule{Folder: "/tmp/foo", MinSize: 10, MaxSize: 5}, "min size 10 over its max size 5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := newFolderFilters(map[string]config.FolderRule{"rule": test.Rule})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "folder rule rule")
+			assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+		})
+	}
+}
+
+func TestFileHandler_UpdateFolderRulesWhileWatching(t *testing.T) {
+	a, _, _ := createFolders(t)
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+	fh := New(logger.MockLogger{}, &dataOrgMock, nil, &config.Configuration{})
+
+	// the rules are replaced by the config updates while the watcher goroutine checks the files against them
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for k := 0; k < 100; k++ {
+			assert.NoError(t, fh.UpdateFolderRules(map[string]config.FolderRule{"a": {Folder: a, MinSize: int64(k % 2)}}))
+		}
+	}()
+	for k := 0; k < 100; k++ {
+		fh.handleNewFile(filepath.Join(a, "1.tiff"), nil)
+	}
+	<-done
+	dataOrgMock.AssertNumberOfCalls(t, "NotifyNewFile", 100)
+}
@@ -0,0 +1,248 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aicsd/ms-file-watcher/config"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/werrors"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
+)
+
+// folderUpdateTimeout is how long a change of the watched folders waits for the watcher to pick it up
+const folderUpdateTimeout = 30 * time.Second
+
+var errWatcherNotRunning = errors.New("the folder watcher is not running")
+
+// WatchedFolders are the folders the file watcher watches for new files
+type WatchedFolders struct {
+	FoldersToWatch  []string
+	WatchSubfolders bool
+	// Watched are the folders that have a watch on the running watcher, including the watched subfolders
+	Watched []string
+	// Polled are the folders that are periodically scanned rather than watched for events
+	Polled []string
+}
+
+// WatchedFoldersUpdate is the request changing the folders the file watcher watches,
+// WatchSubfolders is left unchanged when it is not set
+type WatchedFoldersUpdate struct {
+	FoldersToWatch  []string
+	WatchSubfolders *bool
+}
+
+// folderUpdate is a change of the watched folders applied by the WatchFolders goroutine, which closes done once applied
+type folderUpdate struct {
+	folders         []string
+	watchSubfolders bool
+	done            chan struct{}
+}
+
+// RegisterRoutes adds the routes of the file watcher to the service
+func (fh *FileHandler) RegisterRoutes(service interfaces.ApplicationService) error {
+	err := service.AddRoute(pkg.EndpointWatchedFolders, fh.GetWatchedFolders, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "get watched folders")
+	}
+
+	err = service.AddRoute(pkg.EndpointWatchedFolders, fh.PutWatchedFolders, http.MethodPut)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "put watched folders")
+	}
+
+	return nil
+}
+
+// UpdateWatchedFolders has the running watcher add or remove the watches of the folders, recursively when
+// watchSubfolders is set, and returns once they are applied. The files already in the newly watched folders
+// are handled as new files.
+func (fh *FileHandler) UpdateWatchedFolders(folders []string, watchSubfolders bool) error {
+	fh.foldersMutex.RLock()
+	running := fh.watcher != nil
+	fh.foldersMutex.RUnlock()
+	if !running {
+		return errWatcherNotRunning
+	}
+
+	update := folderUpdate{folders: folders, watchSubfolders: watchSubfolders, done: make(chan struct{})}
+	select {
+	case fh.folderUpdates <- update:
+	case <-time.After(folderUpdateTimeout):
+		return errWatcherNotRunning
+	}
+	<-update.done
+	return nil
+}
+
+// applyFolderUpdate removes the watches of the folders no longer watched and adds the watches of the new folders.
+// The folders with the poll mode are handed to the poller instead. It must only be called by the WatchFolders goroutine.
+func (fh *FileHandler) applyFolderUpdate(update folderUpdate) {
+	previous := make(map[string]bool)
+	for _, folder := range fh.watcher.WatchList() {
+		previous[folder] = true
+	}
+	addSubfolders := update.watchSubfolders && !fh.watchSubfolders
+
+	fh.foldersMutex.Lock()
+	var notified []string
+	polled := make(map[string]time.Duration)
+	for _, folder := range update.folders {
+		if interval, found := fh.pollIntervals[folder]; found {
+			polled[folder] = interval
+		} else {
+			notified = append(notified, folder)
+		}
+	}
+	fh.poller.setFolders(polled, time.Now().UTC())
+	fh.folders = update.folders
+	fh.watchSubfolders = update.watchSubfolders
+	fh.polled = fh.poller.folders()
+	fh.foldersMutex.Unlock()
+
+	for folder := range previous {
+		if watchedBy(folder, notified, update.watchSubfolders) {
+			continue
+		}
+		err := fh.watcher.Remove(folder)
+		if err != nil {
+			fh.lc.Errorf("Could not remove folder %s from the watcher: %s", folder, err.Error())
+			continue
+		}
+		fh.lc.Debugf("Stopped watching folder %s", folder)
+	}
+
+	for _, folder := range notified {
+		if !previous[folder] {
+			fh.watchFolder(folder, fh.Config.FileExclusionList)
+		} else if addSubfolders {
+			fh.watchSubfoldersOf(folder)
+		}
+	}
+}
+
+// watchFolder adds the folder to the watcher and walks it for the files already in it
+func (fh *FileHandler) watchFolder(folder string, fileExclusionList []string) {
+	err := fh.watcher.Add(folder)
+	if err != nil {
+		fh.lc.Errorf("Could not add folder %s to the watcher: %s", folder, err.Error())
+		return
+	}
+	fh.lc.Debugf("Walking path at: %s", folder)
+	fh.walkDirectory(folder, fh.watchSubfolders, fileExclusionList)
+	fh.lc.Debugf("Folder %s walked", folder)
+}
+
+// watchSubfoldersOf adds the subfolders of an already watched folder to the watcher, once the subfolders are watched
+func (fh *FileHandler) watchSubfoldersOf(folder string) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		fh.lc.Errorf("Could not read folder %s: %s", folder, err.Error())
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			fh.watchFolder(filepath.Join(folder, entry.Name()), fh.Config.FileExclusionList)
+		}
+	}
+}
+
+// watchedBy reports whether the folder is one of the watched folders or, when watchSubfolders is set, one of their subfolders
+func watchedBy(folder string, folders []string, watchSubfolders bool) bool {
+	for _, watched := range folders {
+		if folder == watched {
+			return true
+		}
+		if watchSubfolders && strings.HasPrefix(folder, watched+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedFolders returns the folders the file watcher watches
+func (fh *FileHandler) watchedFolders() WatchedFolders {
+	fh.foldersMutex.RLock()
+	defer fh.foldersMutex.RUnlock()
+
+	watched := WatchedFolders{
+		FoldersToWatch:  append([]string{}, fh.folders...),
+		WatchSubfolders: fh.watchSubfolders,
+		Watched:         []string{},
+		Polled:          append([]string{}, fh.polled...),
+	}
+	if fh.watcher != nil {
+		watched.Watched = append(watched.Watched, fh.watcher.WatchList()...)
+		sort.Strings(watched.Watched)
+	}
+	return watched
+}
+
+// GetWatchedFolders is a request to retrieve the folders the running watcher watches
+func (fh *FileHandler) GetWatchedFolders(writer http.ResponseWriter, request *http.Request) {
+	fh.writeJson(writer, fh.watchedFolders())
+}
+
+// PutWatchedFolders is a request to change the folders the running watcher watches. The change is not written
+// to the configuration provider, so it lasts until the service restarts or the FoldersToWatch setting changes.
+func (fh *FileHandler) PutWatchedFolders(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		helpers.HandleErrorMessage(fh.lc, writer, werrors.WrapErr(err, pkg.ErrInvalidInput), http.StatusBadRequest)
+		return
+	}
+	var update WatchedFoldersUpdate
+	err = json.Unmarshal(body, &update)
+	if err != nil {
+		helpers.HandleErrorMessage(fh.lc, writer, werrors.WrapErr(err, pkg.ErrInvalidInput), http.StatusBadRequest)
+		return
+	}
+	folders, err := config.ValidateFolders(update.FoldersToWatch)
+	if err != nil {
+		helpers.HandleErrorMessage(fh.lc, writer, werrors.WrapErr(err, pkg.ErrInvalidInput), http.StatusBadRequest)
+		return
+	}
+
+	fh.foldersMutex.RLock()
+	watchSubfolders := fh.watchSubfolders
+	fh.foldersMutex.RUnlock()
+	if update.WatchSubfolders != nil {
+		watchSubfolders = *update.WatchSubfolders
+	}
+
+	err = fh.UpdateWatchedFolders(folders, watchSubfolders)
+	if err != nil {
+		helpers.HandleErrorMessage(fh.lc, writer, err, http.StatusServiceUnavailable)
+		return
+	}
+	fh.lc.Infof("Folders To Watch set to: %v, Watch Subfolders set to: %v", folders, watchSubfolders)
+
+	fh.writeJson(writer, fh.watchedFolders())
+}
+
+func (fh *FileHandler) writeJson(writer http.ResponseWriter, value interface{}) {
+	jsonRsp, err := json.Marshal(value)
+	if err != nil {
+		helpers.HandleErrorMessage(fh.lc, writer, werrors.WrapMsg(err, "failed to marshal the watched folders"), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		fh.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
@@ -0,0 +1,175 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"aicsd/ms-file-watcher/clients/data_organizer/mocks"
+	"aicsd/ms-file-watcher/config"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// createFolders creates the folders a, a/sub and b with a file in each in a temporary folder
+func createFolders(t *testing.T) (string, string, string) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a")
+	b := filepath.Join(root, "b")
+	require.NoError(t, os.MkdirAll(filepath.Join(a, "sub"), 0755))
+	require.NoError(t, os.MkdirAll(b, 0755))
+	for _, filename := range []string{filepath.Join(a, "1.tiff"), filepath.Join(a, "sub", "2.tiff"), filepath.Join(b, "3.tiff")} {
+		require.NoError(t, os.WriteFile(filename, []byte("image"), 0644))
+	}
+	return a, filepath.Join(a, "sub"), b
+}
+
+func TestFileHandler_ApplyFolderUpdate(t *testing.T) {
+	a, sub, b := createFolders(t)
+
+	tests := []struct {
+		Name            string
+		Initial         folderUpdate
+		Update          folderUpdate
+		ExpectedWatched []string
+		ExpectedNotify  []string
+	}{
+		{"folder added", folderUpdate{folders: []string{a}}, folderUpdate{folders: []string{a, b}},
+			[]string{a, b}, []string{filepath.Join(b, "3.tiff")}},
+		{"folder removed", folderUpdate{folders: []string{a, b}}, folderUpdate{folders: []string{b}},
+			[]string{b}, nil},
+		{"subfolders watched", folderUpdate{folders: []string{a}}, folderUpdate{folders: []string{a}, watchSubfolders: true},
+			[]string{a, sub}, []string{filepath.Join(sub, "2.tiff")}},
+		{"subfolders no longer watched", folderUpdate{folders: []string{a}, watchSubfolders: true}, folderUpdate{folders: []string{a}},
+			[]string{a}, nil},
+		{"folder with subfolders removed", folderUpdate{folders: []string{a, b}, watchSubfolders: true}, folderUpdate{folders: []string{b}, watchSubfolders: true},
+			[]string{b}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			watcher, err := fsnotify.NewWatcher()
+			require.NoError(t, err)
+			defer watcher.Close()
+			initialMock := mocks.Client{}
+			initialMock.On("NotifyNewFile", mock.Anything).Return(nil)
+			fh := New(logger.MockLogger{}, &initialMock, nil, &config.Configuration{})
+			fh.watcher = watcher
+			fh.applyFolderUpdate(test.Initial)
+
+			dataOrgMock := mocks.Client{}
+			dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+			fh.dataOrgClient = &dataOrgMock
+			fh.applyFolderUpdate(test.Update)
+
+			watched := fh.watchedFolders()
+			assert.Equal(t, test.Update.folders, watched.FoldersToWatch)
+			assert.Equal(t, test.Update.watchSubfolders, watched.WatchSubfolders)
+			assert.Equal(t, test.ExpectedWatched, watched.Watched)
+			var notified []string
+			for _, call := range dataOrgMock.Calls {
+				notified = append(notified, call.Arguments.String(0))
+			}
+			sort.Strings(notified)
+			assert.Equal(t, test.ExpectedNotify, notified)
+		})
+	}
+}
+
+func TestFileHandler_PutWatchedFolders(t *testing.T) {
+	a, _, b := createFolders(t)
+
+	tests := []struct {
+		Name               string
+		Body               string
+		NotRunning         bool
+		ExpectedStatusCode int
+		ExpectedWatched    []string
+	}{
+		{"happy path", `{"FoldersToWatch": ["` + b + `"]}`, false, http.StatusOK, []string{b}},
+		{"subfolders watched", `{"FoldersToWatch": ["` + a + `/"], "WatchSubfolders": true}`, false, http.StatusOK,
+			[]string{a, filepath.Join(a, "sub")}},
+		{"folder not found", `{"FoldersToWatch": ["` + a + `", "/not/a/folder"]}`, false, http.StatusBadRequest, nil},
+		{"no folders", `{"FoldersToWatch": []}`, false, http.StatusBadRequest, nil},
+		{"invalid body", `{"FoldersToWatch": "` + a + `"}`, false, http.StatusBadRequest, nil},
+		{"watcher not running", `{"FoldersToWatch": ["` + b + `"]}`, true, http.StatusServiceUnavailable, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dataOrgMock := mocks.Client{}
+			dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+			watcherConfig := config.Configuration{FoldersToWatch: []string{a}}
+			fh := New(logger.MockLogger{}, &dataOrgMock, nil, &watcherConfig)
+			if !test.NotRunning {
+				ctx, cancel := context.WithCancel(context.Background())
+				wg := &sync.WaitGroup{}
+				wg.Add(1)
+				go fh.WatchFolders(ctx, wg, &watcherConfig)
+				defer func() {
+					cancel()
+					wg.Wait()
+				}()
+				require.Eventually(t, func() bool {
+					return len(fh.watchedFolders().Watched) > 0
+				}, time.Second, 10*time.Millisecond)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "http://localhost", bytes.NewBufferString(test.Body))
+			w := httptest.NewRecorder()
+			fh.PutWatchedFolders(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				return
+			}
+			var watched WatchedFolders
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&watched))
+			assert.Equal(t, test.ExpectedWatched, watched.Watched)
+			assert.Equal(t, test.ExpectedWatched[:1], watched.FoldersToWatch)
+		})
+	}
+}
+
+func TestFileHandler_GetWatchedFolders(t *testing.T) {
+	a, sub, b := createFolders(t)
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer watcher.Close()
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+	fh := New(logger.MockLogger{}, &dataOrgMock, nil, &config.Configuration{})
+	fh.watcher = watcher
+	require.NoError(t, fh.UpdateFolderModes(map[string]config.FolderMode{"share": {Folder: b, Mode: config.ModePoll}}))
+	fh.applyFolderUpdate(folderUpdate{folders: []string{a, b}, watchSubfolders: true})
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	w := httptest.NewRecorder()
+	fh.GetWatchedFolders(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode, "invalid status code")
+	var watched WatchedFolders
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&watched))
+	assert.Equal(t, WatchedFolders{FoldersToWatch: []string{a, b}, WatchSubfolders: true, Watched: []string{a, sub}, Polled: []string{b}}, watched)
+}
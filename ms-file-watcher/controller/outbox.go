@@ -0,0 +1,142 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"aicsd/ms-file-watcher/clients/data_organizer"
+	"aicsd/ms-file-watcher/persist"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// maxOutboxBackoff caps the time between two attempts to send a notification of the outbox
+const maxOutboxBackoff = 5 * time.Minute
+
+// RunOutbox is a goroutine that sends the notifications of the outbox again every interval, until the context is done
+func (fh *FileHandler) RunOutbox(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fh.lc.Info("Outbox Exiting")
+			return
+		case now := <-ticker.C:
+			err := fh.SendPending(now.UTC())
+			if err != nil {
+				fh.lc.Errorf("Sending the notifications of the outbox failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// SendPending sends the notifications of the outbox that are due at the given time, in the order they were queued.
+// A notification that can not be sent is backed off and the next ones are still sent, unless the data organizer
+// could not be reached at all, in which case the rest are left for the next check. A notification the data organizer
+// rejects, or that reached the maximum attempts, is moved to the dead letters, and the notifications of the files
+// that no longer exist are dropped.
+func (fh *FileHandler) SendPending(now time.Time) error {
+	notifications, err := fh.outbox.GetAll()
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, notification := range notifications {
+		if now.UnixNano() < notification.NextAttempt {
+			continue
+		}
+		if _, err := os.Stat(notification.Filename); err != nil {
+			fh.lc.Warnf("Dropping the notification of file %s from the outbox: %s", notification.Filename, err.Error())
+			err = fh.outbox.Delete(notification.Filename)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = fh.dataOrgClient.NotifyNewFile(notification.Filename)
+		if err == nil {
+			err = fh.outbox.Delete(notification.Filename)
+			if err != nil {
+				return err
+			}
+			fh.lc.Debugf("Sent new file notification for %s from the outbox after %d failed attempts", notification.Filename, notification.Attempts)
+			continue
+		}
+
+		notification.Attempts++
+		notification.Error = err.Error()
+		if rejected(err) || (fh.Config.OutboxMaxAttempts > 0 && notification.Attempts >= fh.Config.OutboxMaxAttempts) {
+			err = fh.deadLetter(notification)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		notification.NextAttempt = now.Add(outboxBackoff(fh.Config.OutboxRetryInterval, notification.Attempts)).UnixNano()
+		putErr := fh.outbox.Put(notification)
+		if putErr != nil {
+			return putErr
+		}
+		var statusErr *data_organizer.StatusError
+		unreachable := !errors.As(err, &statusErr)
+		errs = multierror.Append(errs, fmt.Errorf("could not send new file notification for file %s on attempt %d: %s",
+			notification.Filename, notification.Attempts, err.Error()))
+		if unreachable {
+			// the data organizer is most likely still down, the rest are sent on a later check
+			return errs
+		}
+	}
+	return errs
+}
+
+// deadLetter moves the notification to the dead letters of the outbox, which are no longer sent
+func (fh *FileHandler) deadLetter(notification persist.Notification) error {
+	fh.lc.Errorf("Moving the notification of file %s to the dead letters of the outbox after %d attempts: %s",
+		notification.Filename, notification.Attempts, notification.Error)
+	return fh.outbox.PutDeadLetter(notification)
+}
+
+// rejected reports whether the data organizer rejected the notification, so that sending it again will not succeed
+func rejected(err error) bool {
+	var statusErr *data_organizer.StatusError
+	return errors.As(err, &statusErr) && statusErr.Rejected()
+}
+
+// queueNotification adds the notification of the file that could not be sent to the outbox, to be sent again
+// after the retry interval. A notification the data organizer rejected goes to the dead letters right away.
+func (fh *FileHandler) queueNotification(filename string, now time.Time, sendErr error) error {
+	notification := persist.Notification{
+		Filename:    filename,
+		Queued:      now.UnixNano(),
+		Attempts:    1,
+		NextAttempt: now.Add(outboxBackoff(fh.Config.OutboxRetryInterval, 1)).UnixNano(),
+		Error:       sendErr.Error(),
+	}
+	if rejected(sendErr) {
+		return fh.deadLetter(notification)
+	}
+	return fh.outbox.Put(notification)
+}
+
+// outboxBackoff returns how long to wait before sending a notification again after the given number of failed
+// attempts, doubling the retry interval with each attempt up to the maximum backoff
+func outboxBackoff(retryInterval time.Duration, attempts int) time.Duration {
+	backoff := retryInterval
+	for attempt := 1; attempt < attempts && backoff < maxOutboxBackoff; attempt++ {
+		backoff *= 2
+	}
+	return min(backoff, maxOutboxBackoff)
+}
@@ -0,0 +1,156 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aicsd/ms-file-watcher/clients/data_organizer"
+	"aicsd/ms-file-watcher/clients/data_organizer/mocks"
+	"aicsd/ms-file-watcher/config"
+	"aicsd/ms-file-watcher/persist"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileHandler_SendPending(t *testing.T) {
+	a, sub, b := createFolders(t)
+	now := time.Now().UTC()
+	first := persist.Notification{Filename: filepath.Join(a, "1.tiff"), Queued: 1, Attempts: 1, NextAttempt: now.UnixNano()}
+	second := persist.Notification{Filename: filepath.Join(sub, "2.tiff"), Queued: 2, Attempts: 2, NextAttempt: now.UnixNano()}
+	waiting := persist.Notification{Filename: filepath.Join(b, "3.tiff"), Queued: 3, Attempts: 3, NextAttempt: now.Add(time.Minute).UnixNano()}
+	removed := persist.Notification{Filename: filepath.Join(a, "removed.tiff"), Queued: 4, Attempts: 1, NextAttempt: now.UnixNano()}
+	backedOff := func(notification persist.Notification, err error) persist.Notification {
+		notification.Attempts++
+		notification.NextAttempt = now.Add(outboxBackoff(10*time.Second, notification.Attempts)).UnixNano()
+		notification.Error = err.Error()
+		return notification
+	}
+	deadLetter := func(notification persist.Notification, err error) persist.Notification {
+		notification.Attempts++
+		notification.Error = err.Error()
+		return notification
+	}
+	downErr := errors.New("connection refused")
+	serverErr := &data_organizer.StatusError{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error"}
+	rejectedErr := &data_organizer.StatusError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}
+	lastAttempt := persist.Notification{Filename: filepath.Join(a, "1.tiff"), Queued: 1, Attempts: 4, NextAttempt: now.UnixNano()}
+
+	tests := []struct {
+		Name                string
+		Notifications       []persist.Notification
+		NotifyErr           error
+		ExpectedSent        []string
+		ExpectedPending     []persist.Notification
+		ExpectedDeadLetters []persist.Notification
+		ExpectedErrorMsg    string
+	}{
+		{"sent", []persist.Notification{second, first}, nil, []string{first.Filename, second.Filename}, []persist.Notification{}, []persist.Notification{}, ""},
+		{"not due", []persist.Notification{first, waiting}, nil, []string{first.Filename}, []persist.Notification{waiting}, []persist.Notification{}, ""},
+		{"file removed", []persist.Notification{removed}, nil, nil, []persist.Notification{}, []persist.Notification{}, ""},
+		{"data organizer down", []persist.Notification{first, second}, downErr, []string{first.Filename},
+			[]persist.Notification{backedOff(first, downErr), second}, []persist.Notification{},
+			"could not send new file notification for file " + first.Filename + " on attempt 2"},
+		{"data organizer failed", []persist.Notification{first, second}, serverErr, []string{first.Filename, second.Filename},
+			[]persist.Notification{backedOff(first, serverErr), backedOff(second, serverErr)}, []persist.Notification{},
+			"could not send new file notification for file " + second.Filename + " on attempt 3"},
+		{"rejected", []persist.Notification{first, second}, rejectedErr, []string{first.Filename, second.Filename},
+			[]persist.Notification{}, []persist.Notification{deadLetter(first, rejectedErr), deadLetter(second, rejectedErr)}, ""},
+		{"max attempts", []persist.Notification{lastAttempt, second}, serverErr, []string{lastAttempt.Filename, second.Filename},
+			[]persist.Notification{backedOff(second, serverErr)}, []persist.Notification{deadLetter(lastAttempt, serverErr)},
+			"could not send new file notification for file " + second.Filename + " on attempt 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			outbox, err := persist.NewBoltOutbox(filepath.Join(t.TempDir(), "outbox.db"))
+			require.NoError(t, err)
+			defer func() { _ = outbox.Disconnect() }()
+			for _, notification := range test.Notifications {
+				require.NoError(t, outbox.Put(notification))
+			}
+			dataOrgMock := mocks.Client{}
+			dataOrgMock.On("NotifyNewFile", mock.Anything).Return(test.NotifyErr)
+			fh := New(logger.MockLogger{}, &dataOrgMock, outbox, &config.Configuration{OutboxRetryInterval: 10 * time.Second, OutboxMaxAttempts: 5})
+
+			err = fh.SendPending(now)
+			if test.ExpectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			var sent []string
+			for _, call := range dataOrgMock.Calls {
+				sent = append(sent, call.Arguments.String(0))
+			}
+			assert.Equal(t, test.ExpectedSent, sent)
+			pending, err := outbox.GetAll()
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedPending, pending)
+			deadLetters, err := outbox.GetDeadLetters()
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedDeadLetters, deadLetters)
+		})
+	}
+}
+
+func TestFileHandler_NotifyNewFileQueued(t *testing.T) {
+	a, _, _ := createFolders(t)
+	filename := filepath.Join(a, "1.tiff")
+	outbox, err := persist.NewBoltOutbox(filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	defer func() { _ = outbox.Disconnect() }()
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", filename).Return(errors.New("connection refused"))
+	fh := New(logger.MockLogger{}, &dataOrgMock, outbox, &config.Configuration{OutboxRetryInterval: 10 * time.Second})
+
+	before := time.Now().UTC()
+	fh.notifyNewFile(filename)
+
+	pending, err := outbox.GetAll()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, filename, pending[0].Filename)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.GreaterOrEqual(t, pending[0].NextAttempt, before.Add(10*time.Second).UnixNano())
+}
+
+func TestFileHandler_NotifyNewFileRejected(t *testing.T) {
+	a, _, _ := createFolders(t)
+	filename := filepath.Join(a, "1.tiff")
+	outbox, err := persist.NewBoltOutbox(filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	defer func() { _ = outbox.Disconnect() }()
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", filename).Return(&data_organizer.StatusError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"})
+	fh := New(logger.MockLogger{}, &dataOrgMock, outbox, &config.Configuration{OutboxRetryInterval: 10 * time.Second})
+
+	fh.notifyNewFile(filename)
+
+	pending, err := outbox.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	deadLetters, err := outbox.GetDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, filename, deadLetters[0].Filename)
+	assert.Equal(t, "TransmitJob API status not OK: 400 Bad Request", deadLetters[0].Error)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, outboxBackoff(10*time.Second, 1))
+	assert.Equal(t, 40*time.Second, outboxBackoff(10*time.Second, 3))
+	assert.Equal(t, maxOutboxBackoff, outboxBackoff(10*time.Second, 20))
+	assert.Equal(t, maxOutboxBackoff, outboxBackoff(time.Hour, 1))
+}
@@ -0,0 +1,240 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"aicsd/ms-file-watcher/config"
+)
+
+const (
+	// defaultPollInterval is how often a polled folder is scanned when its folder mode sets no PollInterval
+	defaultPollInterval = 30 * time.Second
+	// pollCheckInterval is how often the polled folders are checked for a due scan
+	pollCheckInterval = time.Second
+)
+
+// seenFile is the size and modification time of a file the last time its polled folder was scanned
+type seenFile struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// folderPoller scans the folders that are polled rather than watched for events, such as network shares, for new or
+// changed files. The seen files are kept in an index written to the index file, so that the unchanged files are not
+// handed over again after a restart. It is only used by the goroutine watching the folders.
+type folderPoller struct {
+	indexFile string
+	intervals map[string]time.Duration
+	nextScan  map[string]time.Time
+	index     map[string]seenFile
+	// changed is set when the index changed since it was last saved
+	changed bool
+}
+
+func newFolderPoller(indexFile string) *folderPoller {
+	return &folderPoller{
+		indexFile: indexFile,
+		intervals: make(map[string]time.Duration),
+		nextScan:  make(map[string]time.Time),
+		index:     make(map[string]seenFile),
+	}
+}
+
+// parseFolderModes returns the scan interval of each polled folder of the folder modes
+func parseFolderModes(modes map[string]config.FolderMode) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	for name, mode := range modes {
+		if mode.Folder == "" {
+			return nil, fmt.Errorf("folder mode %s has no folder", name)
+		}
+		switch mode.Mode {
+		case "", config.ModeNotify:
+			continue
+		case config.ModePoll:
+		default:
+			return nil, fmt.Errorf("invalid mode %s for folder mode %s, expected %s or %s", mode.Mode, name, config.ModeNotify, config.ModePoll)
+		}
+		interval := defaultPollInterval
+		if mode.PollInterval != "" {
+			var err error
+			interval, err = time.ParseDuration(mode.PollInterval)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("could not parse positive duration for the poll interval of folder mode %s, got %s", name, mode.PollInterval)
+			}
+		}
+		intervals[filepath.Clean(mode.Folder)] = interval
+	}
+	return intervals, nil
+}
+
+// load reads the index file, the index is left empty when there is no index file yet
+func (p *folderPoller) load() error {
+	if p.indexFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(p.indexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &p.index)
+}
+
+// save writes the index to the index file when it changed, replacing the previous index file at once
+func (p *folderPoller) save() error {
+	if p.indexFile == "" || !p.changed {
+		return nil
+	}
+	data, err := json.Marshal(p.index)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p.indexFile), 0755)
+	if err != nil {
+		return err
+	}
+	tempFile := p.indexFile + ".tmp"
+	err = os.WriteFile(tempFile, data, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempFile, p.indexFile)
+	if err != nil {
+		return err
+	}
+	p.changed = false
+	return nil
+}
+
+// handedOver records the size and modification time of a file of a polled folder that was held until it was done
+// being written, so that the writes seen while it was held do not make it a changed file
+func (p *folderPoller) handedOver(filename string) {
+	if !watchedBy(filepath.Dir(filename), p.folders(), true) {
+		return
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+	p.index[filename] = seenFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+	p.changed = true
+}
+
+// setFolders sets the polled folders and their scan intervals. A newly polled folder is scanned right away, and the
+// seen files of the folders that are no longer polled are dropped.
+func (p *folderPoller) setFolders(intervals map[string]time.Duration, now time.Time) {
+	folders := make([]string, 0, len(intervals))
+	for folder := range intervals {
+		folders = append(folders, folder)
+		if _, found := p.nextScan[folder]; !found {
+			p.nextScan[folder] = now
+		}
+	}
+	for folder := range p.nextScan {
+		if _, found := intervals[folder]; !found {
+			delete(p.nextScan, folder)
+		}
+	}
+	p.intervals = intervals
+
+	for filename := range p.index {
+		if !watchedBy(filepath.Dir(filename), folders, true) {
+			delete(p.index, filename)
+			p.changed = true
+		}
+	}
+}
+
+// folders returns the polled folders in name order
+func (p *folderPoller) folders() []string {
+	folders := make([]string, 0, len(p.intervals))
+	for folder := range p.intervals {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// due returns the polled folders whose scan is due at the given time, in name order, and schedules their next scan
+func (p *folderPoller) due(now time.Time) []string {
+	var due []string
+	for _, folder := range p.folders() {
+		if now.Before(p.nextScan[folder]) {
+			continue
+		}
+		due = append(due, folder)
+		p.nextScan[folder] = now.Add(p.intervals[folder])
+	}
+	return due
+}
+
+// scan walks the folder, and its subfolders when recursive, and returns the files that are new or changed since the
+// last scan in name order. The files that are no longer in the folder are dropped from the index, unless the folder
+// could not be read at all, as when a network share is not mounted. The files still pending are left as they are in
+// the index, they are recorded once they are handed over instead.
+func (p *folderPoller) scan(folder string, recursive bool, pending func(filename string) bool) ([]string, error) {
+	seen := make(map[string]seenFile)
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == folder {
+				return err
+			}
+			// an unreadable entry does not stop the scan of the rest of the folder
+			return nil
+		}
+		if entry.IsDir() {
+			if path != folder && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		seen[path] = seenFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for filename, file := range seen {
+		if pending(filename) {
+			continue
+		}
+		previous, found := p.index[filename]
+		if found && previous.Size == file.Size && previous.ModTime.Equal(file.ModTime) {
+			continue
+		}
+		changed = append(changed, filename)
+		p.index[filename] = file
+		p.changed = true
+	}
+	for filename := range p.index {
+		if _, found := seen[filename]; !found && watchedBy(filepath.Dir(filename), []string{folder}, true) {
+			delete(p.index, filename)
+			p.changed = true
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
@@ -0,0 +1,181 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aicsd/ms-file-watcher/clients/data_organizer/mocks"
+	"aicsd/ms-file-watcher/config"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFolderModes(t *testing.T) {
+	tests := []struct {
+		Name              string
+		Modes             map[string]config.FolderMode
+		ExpectedIntervals map[string]time.Duration
+		ExpectedErrorMsg  string
+	}{
+		{"poll", map[string]config.FolderMode{"share": {Folder: "/tmp/foo/share/", Mode: config.ModePoll, PollInterval: "10s"}},
+			map[string]time.Duration{"/tmp/foo/share": 10 * time.Second}, ""},
+		{"default interval", map[string]config.FolderMode{"share": {Folder: "/tmp/foo/share", Mode: config.ModePoll}},
+			map[string]time.Duration{"/tmp/foo/share": defaultPollInterval}, ""},
+		{"notify", map[string]config.FolderMode{"local": {Folder: "/tmp/foo", Mode: config.ModeNotify}},
+			map[string]time.Duration{}, ""},
+		{"invalid mode", map[string]config.FolderMode{"share": {Folder: "/tmp/foo/share", Mode: "scan"}}, nil,
+			"invalid mode scan for folder mode share"},
+		{"invalid interval", map[string]config.FolderMode{"share": {Folder: "/tmp/foo/share", Mode: config.ModePoll, PollInterval: "0s"}}, nil,
+			"could not parse positive duration for the poll interval of folder mode share"},
+		{"no folder", map[string]config.FolderMode{"share": {Mode: config.ModePoll}}, nil, "folder mode share has no folder"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			intervals, err := parseFolderModes(test.Modes)
+			if test.ExpectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedIntervals, intervals)
+		})
+	}
+}
+
+// notPending is the pending check of the files when the write stability is not checked
+func notPending(string) bool {
+	return false
+}
+
+func TestFolderPoller_Scan(t *testing.T) {
+	a, sub, _ := createFolders(t)
+	first := filepath.Join(a, "1.tiff")
+	second := filepath.Join(sub, "2.tiff")
+	poller := newFolderPoller("")
+
+	changed, err := poller.scan(a, false, notPending)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, changed, "new file")
+
+	changed, err = poller.scan(a, true, notPending)
+	require.NoError(t, err)
+	assert.Equal(t, []string{second}, changed, "new file in subfolder")
+
+	changed, err = poller.scan(a, true, notPending)
+	require.NoError(t, err)
+	assert.Empty(t, changed, "unchanged files")
+
+	require.NoError(t, os.WriteFile(first, []byte("larger image"), 0644))
+	changed, err = poller.scan(a, true, notPending)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, changed, "changed file")
+
+	require.NoError(t, os.Remove(second))
+	changed, err = poller.scan(a, true, notPending)
+	require.NoError(t, err)
+	assert.Empty(t, changed, "removed file")
+	assert.NotContains(t, poller.index, second)
+
+	_, err = poller.scan(filepath.Join(a, "unmounted"), true, notPending)
+	require.Error(t, err)
+	assert.Contains(t, poller.index, first, "index kept when the folder can not be read")
+}
+
+func TestFolderPoller_SaveLoad(t *testing.T) {
+	a, _, _ := createFolders(t)
+	indexFile := filepath.Join(t.TempDir(), "index", "index.json")
+	poller := newFolderPoller(indexFile)
+	poller.setFolders(map[string]time.Duration{a: time.Minute}, time.Now())
+	changed, err := poller.scan(a, true, notPending)
+	require.NoError(t, err)
+	require.Len(t, changed, 2)
+	require.NoError(t, poller.save())
+
+	restarted := newFolderPoller(indexFile)
+	require.NoError(t, restarted.load())
+	restarted.setFolders(map[string]time.Duration{a: time.Minute}, time.Now())
+	changed, err = restarted.scan(a, true, notPending)
+	require.NoError(t, err)
+	assert.Empty(t, changed, "files seen before the restart")
+
+	restarted.setFolders(map[string]time.Duration{}, time.Now())
+	assert.Empty(t, restarted.index, "seen files of the folders no longer polled")
+}
+
+func TestFolderPoller_Due(t *testing.T) {
+	now := time.Now()
+	poller := newFolderPoller("")
+	poller.setFolders(map[string]time.Duration{"/tmp/fast": time.Second, "/tmp/slow": time.Minute}, now)
+
+	assert.Equal(t, []string{"/tmp/fast", "/tmp/slow"}, poller.due(now))
+	assert.Empty(t, poller.due(now.Add(500*time.Millisecond)))
+	assert.Equal(t, []string{"/tmp/fast"}, poller.due(now.Add(time.Second)))
+	assert.Equal(t, []string{"/tmp/fast", "/tmp/slow"}, poller.due(now.Add(time.Minute)))
+}
+
+func TestFileHandler_PollFolders(t *testing.T) {
+	a, sub, b := createFolders(t)
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer watcher.Close()
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+	fh := New(logger.MockLogger{}, &dataOrgMock, nil, &config.Configuration{FileExclusionList: []string{"3"}})
+	fh.watcher = watcher
+	require.NoError(t, fh.UpdateFolderModes(map[string]config.FolderMode{"share": {Folder: a, Mode: config.ModePoll}}))
+	fh.applyFolderUpdate(folderUpdate{folders: []string{a, b}, watchSubfolders: true})
+
+	assert.Equal(t, []string{b}, watcher.WatchList(), "polled folder not watched for events")
+	fh.pollFolders(time.Now())
+	dataOrgMock.AssertCalled(t, "NotifyNewFile", filepath.Join(a, "1.tiff"))
+	dataOrgMock.AssertCalled(t, "NotifyNewFile", filepath.Join(sub, "2.tiff"))
+	dataOrgMock.AssertNumberOfCalls(t, "NotifyNewFile", 2)
+
+	fh.pollFolders(time.Now().Add(time.Hour))
+	dataOrgMock.AssertNumberOfCalls(t, "NotifyNewFile", 2)
+}
+
+func TestFileHandler_PollFoldersPendingFile(t *testing.T) {
+	a, _, _ := createFolders(t)
+	filename := filepath.Join(a, "1.tiff")
+	dataOrgMock := mocks.Client{}
+	dataOrgMock.On("NotifyNewFile", mock.Anything).Return(nil)
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer watcher.Close()
+	fh := New(logger.MockLogger{}, &dataOrgMock, nil, &config.Configuration{StabilityQuietPeriod: time.Minute})
+	fh.watcher = watcher
+	require.NoError(t, fh.UpdateFolderModes(map[string]config.FolderMode{"share": {Folder: a, Mode: config.ModePoll, PollInterval: "1s"}}))
+	fh.applyFolderUpdate(folderUpdate{folders: []string{a}})
+
+	now := time.Now().UTC()
+	fh.pollFolders(now)
+	assert.True(t, fh.stability.isPending(filename), "new file held until it is done being written")
+
+	// the file is still being written after the scans that found it
+	fh.pollFolders(now.Add(time.Second))
+	require.NoError(t, os.WriteFile(filename, []byte("larger image"), 0644))
+	assert.Empty(t, fh.stability.stable(now.Add(2*time.Second)), "write seen by the stability check")
+	for _, stable := range fh.stability.stable(now.Add(2 * time.Minute)) {
+		fh.poller.handedOver(stable)
+		fh.notifyNewFile(stable)
+	}
+	dataOrgMock.AssertNumberOfCalls(t, "NotifyNewFile", 1)
+
+	fh.pollFolders(now.Add(3 * time.Minute))
+	assert.False(t, fh.stability.isPending(filename), "handed over file is not a changed file")
+	dataOrgMock.AssertNumberOfCalls(t, "NotifyNewFile", 1)
+}
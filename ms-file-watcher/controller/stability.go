@@ -0,0 +1,123 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// minCheckInterval keeps the stability checks of the files being written from running back to back
+// with a short quiet period
+const minCheckInterval = 100 * time.Millisecond
+
+// pendingFile is a new file that is not handed to the data organizer until it is done being written
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	// lastChange is the last time the file was seen being written
+	lastChange time.Time
+}
+
+// fileStability holds the new files until they are done being written: their size and modification time must stay
+// unchanged for the quiet period and, when a marker suffix is set, the marker file named after the file with the
+// suffix, e.g. image.tiff.done, must exist. It is only used by the goroutine watching the folders.
+type fileStability struct {
+	quietPeriod  time.Duration
+	markerSuffix string
+	pending      map[string]*pendingFile
+}
+
+func newFileStability(quietPeriod time.Duration, markerSuffix string) *fileStability {
+	return &fileStability{
+		quietPeriod:  quietPeriod,
+		markerSuffix: markerSuffix,
+		pending:      make(map[string]*pendingFile),
+	}
+}
+
+// enabled reports whether the new files are held until they are done being written
+func (s *fileStability) enabled() bool {
+	return s.quietPeriod > 0 || s.markerSuffix != ""
+}
+
+// checkInterval returns how often the files being written are checked for stability
+func (s *fileStability) checkInterval() time.Duration {
+	if s.quietPeriod == 0 {
+		return time.Second
+	}
+	return max(s.quietPeriod/2, minCheckInterval)
+}
+
+// isMarker reports whether the file is the marker of another file
+func (s *fileStability) isMarker(filename string) bool {
+	return s.markerSuffix != "" && strings.HasSuffix(filename, s.markerSuffix)
+}
+
+// markedFile returns the file a marker is named after
+func (s *fileStability) markedFile(marker string) string {
+	return strings.TrimSuffix(marker, s.markerSuffix)
+}
+
+// track starts the quiet period of a new file, a file that is already tracked keeps its quiet period.
+// A file that no longer exists is not tracked.
+func (s *fileStability) track(filename string, now time.Time) {
+	if _, found := s.pending[filename]; found {
+		return
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+	s.pending[filename] = &pendingFile{size: info.Size(), modTime: info.ModTime(), lastChange: now}
+}
+
+// isPending reports whether the file is held until it is done being written
+func (s *fileStability) isPending(filename string) bool {
+	_, found := s.pending[filename]
+	return found
+}
+
+// written restarts the quiet period of a tracked file that is written to
+func (s *fileStability) written(filename string, now time.Time) {
+	if file, found := s.pending[filename]; found {
+		file.lastChange = now
+	}
+}
+
+// stable returns the tracked files that are done being written at the given time, in name order, and stops tracking
+// them. A file whose size or modification time changed since the last check restarts its quiet period, and a file
+// removed before it was done is dropped.
+func (s *fileStability) stable(now time.Time) []string {
+	var stable []string
+	for filename, file := range s.pending {
+		info, err := os.Stat(filename)
+		if err != nil {
+			delete(s.pending, filename)
+			continue
+		}
+		if info.Size() != file.size || !info.ModTime().Equal(file.modTime) {
+			file.size = info.Size()
+			file.modTime = info.ModTime()
+			file.lastChange = now
+			continue
+		}
+		if now.Sub(file.lastChange) < s.quietPeriod {
+			continue
+		}
+		if s.markerSuffix != "" {
+			if _, err := os.Stat(filename + s.markerSuffix); err != nil {
+				continue
+			}
+		}
+		stable = append(stable, filename)
+		delete(s.pending, filename)
+	}
+	sort.Strings(stable)
+	return stable
+}
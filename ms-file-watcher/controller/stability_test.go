@@ -0,0 +1,81 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStability_QuietPeriod(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "image.tiff")
+	require.NoError(t, os.WriteFile(filename, []byte("part"), 0644))
+	start := time.Now().UTC()
+	stability := newFileStability(10*time.Second, "")
+
+	stability.track(filename, start)
+	assert.Empty(t, stability.stable(start.Add(5*time.Second)), "quiet period not over")
+
+	// the file grows, restarting the quiet period
+	require.NoError(t, os.WriteFile(filename, []byte("part and more"), 0644))
+	assert.Empty(t, stability.stable(start.Add(11*time.Second)), "size changed")
+	assert.Empty(t, stability.stable(start.Add(20*time.Second)), "quiet period restarted")
+
+	// a write event also restarts the quiet period
+	stability.written(filename, start.Add(15*time.Second))
+	assert.Empty(t, stability.stable(start.Add(24*time.Second)), "written")
+	assert.Equal(t, []string{filename}, stability.stable(start.Add(25*time.Second)))
+	assert.Empty(t, stability.pending, "stable file no longer tracked")
+}
+
+func TestFileStability_Marker(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "image.tiff")
+	require.NoError(t, os.WriteFile(filename, []byte("image"), 0644))
+	start := time.Now().UTC()
+	stability := newFileStability(0, ".done")
+
+	assert.True(t, stability.enabled())
+	assert.True(t, stability.isMarker(filename+".done"))
+	assert.False(t, stability.isMarker(filename))
+	assert.Equal(t, filename, stability.markedFile(filename+".done"))
+
+	stability.track(filename, start)
+	assert.Empty(t, stability.stable(start.Add(time.Minute)), "no marker")
+	require.NoError(t, os.WriteFile(filename+".done", nil, 0644))
+	assert.Equal(t, []string{filename}, stability.stable(start.Add(time.Minute)))
+}
+
+func TestFileStability_Removed(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "image.tiff")
+	start := time.Now().UTC()
+	stability := newFileStability(time.Second, "")
+
+	stability.track(filename, start)
+	assert.Empty(t, stability.pending, "missing file not tracked")
+
+	require.NoError(t, os.WriteFile(filename, []byte("image"), 0644))
+	stability.track(filename, start)
+	require.NoError(t, os.Remove(filename))
+	assert.Empty(t, stability.stable(start.Add(time.Minute)))
+	assert.Empty(t, stability.pending, "removed file dropped")
+}
+
+func TestFileStability_Disabled(t *testing.T) {
+	stability := newFileStability(0, "")
+	assert.False(t, stability.enabled())
+	assert.False(t, stability.isMarker("image.tiff.done"))
+	assert.Equal(t, time.Second, stability.checkInterval())
+	assert.Equal(t, minCheckInterval, newFileStability(50*time.Millisecond, "").checkInterval())
+	assert.Equal(t, 5*time.Second, newFileStability(10*time.Second, "").checkInterval())
+}
@@ -9,6 +9,7 @@ import (
 	"aicsd/ms-file-watcher/clients/data_organizer"
 	"aicsd/ms-file-watcher/config"
 	controller "aicsd/ms-file-watcher/controller"
+	"aicsd/ms-file-watcher/persist"
 	"aicsd/pkg"
 	"aicsd/pkg/wait"
 	"context"
@@ -40,7 +41,15 @@ func main() {
 	wg := &sync.WaitGroup{}
 	// set job to map
 	dataOrgClient := data_organizer.NewClient(configuration)
-	fileWatcher := controller.New(lc, dataOrgClient, configuration)
+	var outbox persist.Outbox
+	if configuration.OutboxFile != "" {
+		outbox, err = persist.NewBoltOutbox(configuration.OutboxFile)
+		if err != nil {
+			lc.Errorf("failed to open the outbox: %s", err.Error())
+			os.Exit(-1)
+		}
+	}
+	fileWatcher := controller.New(lc, dataOrgClient, outbox, configuration)
 
 	if err := wait.ForDependencies(lc, fileWatcher.DependentServices, service.RequestTimeout()); err != nil {
 		lc.Errorf("failed to wait.ForDependencies: %s", err.Error())
@@ -61,13 +70,39 @@ func main() {
 		fileWatcher.Config.FileExclusionList = strings.Split(formatedExclusionList, ",")
 	}
 
+	// The folders to watch are updated as a string in Consul but are stored as a slice in the Configuration
+	fileWatcher.Config.FoldersToWatch, err = config.ValidateFolders(config.SplitFolders(fileWatcher.Config.App.UpdatableSettings.FoldersToWatch))
+	if err != nil {
+		lc.Errorf("invalid folders to watch: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	if err := fileWatcher.UpdateFolderModes(fileWatcher.Config.App.UpdatableSettings.FolderModes); err != nil {
+		lc.Errorf("invalid folder modes: %s", err.Error())
+		os.Exit(-1)
+	}
+
+	if err := fileWatcher.UpdateFolderRules(fileWatcher.Config.App.UpdatableSettings.FolderRules); err != nil {
+		lc.Errorf("invalid folder rules: %s", err.Error())
+		os.Exit(-1)
+	}
+
 	if err := service.ListenForCustomConfigChanges(&fileWatcher.Config.App.UpdatableSettings, "UpdatableSettings", fileWatcher.ProcessConfigUpdates); err != nil {
 		lc.Errorf("unable to watch custom writable configuration: %s", err.Error())
 		os.Exit(-1)
 	}
 
+	if err := fileWatcher.RegisterRoutes(service); err != nil {
+		lc.Errorf("failed to register routes: %s", err.Error())
+		os.Exit(-1)
+	}
+
 	wg.Add(1)
 	go fileWatcher.WatchFolders(ctx, wg, fileWatcher.Config)
+	if outbox != nil {
+		wg.Add(1)
+		go fileWatcher.RunOutbox(ctx, wg, configuration.OutboxRetryInterval)
+	}
 
 	err = service.MakeItRun()
 	if err != nil {
@@ -77,6 +112,11 @@ func main() {
 
 	cancelFunc()
 	wg.Wait()
+	if outbox != nil {
+		if err := outbox.Disconnect(); err != nil {
+			lc.Errorf("failed to close the outbox: %s", err.Error())
+		}
+	}
 
 	os.Exit(0)
 }
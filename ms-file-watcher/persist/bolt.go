@@ -0,0 +1,144 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"aicsd/pkg"
+	"aicsd/pkg/werrors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout is how long to wait for the file lock held by another process using the database file
+const boltOpenTimeout = 5 * time.Second
+
+var (
+	// bucketNotification stores the notifications waiting to be sent under the file name
+	bucketNotification = []byte("notification")
+	// bucketDeadLetter stores the notifications that are no longer sent under the file name
+	bucketDeadLetter = []byte("dead_letter")
+)
+
+// BoltOutbox is an Outbox that stores the notifications in an embedded bbolt database file,
+// so that they are still sent after a restart of the service
+type BoltOutbox struct {
+	db *bolt.DB
+}
+
+// NewBoltOutbox returns an outbox that uses a bbolt database stored in the given file.
+// The file and its folder are created if they do not exist.
+func NewBoltOutbox(path string) (Outbox, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, "failed to create the folder of bolt db %s", path)
+	}
+	db, err := bolt.Open(path, pkg.FilePermissions, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, "failed to open bolt db %s", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketNotification)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(bucketDeadLetter)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, werrors.WrapMsgf(err, "failed to create buckets in bolt db %s", path)
+	}
+	return BoltOutbox{db: db}, nil
+}
+
+// Put stores the notification in the bolt db under the file name
+func (bo BoltOutbox) Put(notification Notification) error {
+	jsonNotification, err := json.Marshal(notification)
+	if err != nil {
+		return werrors.WrapMsgf(err, "failed to marshal the notification of file %s", notification.Filename)
+	}
+	err = bo.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketNotification).Put([]byte(notification.Filename), jsonNotification)
+	})
+	if err != nil {
+		return werrors.WrapMsgf(err, "failed to store the notification of file %s", notification.Filename)
+	}
+	return nil
+}
+
+// Delete removes the notification of the file from the bolt db
+func (bo BoltOutbox) Delete(filename string) error {
+	err := bo.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketNotification).Delete([]byte(filename))
+	})
+	if err != nil {
+		return werrors.WrapMsgf(err, "failed to delete the notification of file %s", filename)
+	}
+	return nil
+}
+
+// GetAll returns the notifications stored in the bolt db in the order they were queued
+func (bo BoltOutbox) GetAll() ([]Notification, error) {
+	return bo.getAll(bucketNotification)
+}
+
+// PutDeadLetter stores the notification in the dead letters of the bolt db and removes it from the notifications
+// in the same transaction
+func (bo BoltOutbox) PutDeadLetter(notification Notification) error {
+	jsonNotification, err := json.Marshal(notification)
+	if err != nil {
+		return werrors.WrapMsgf(err, "failed to marshal the notification of file %s", notification.Filename)
+	}
+	err = bo.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(bucketDeadLetter).Put([]byte(notification.Filename), jsonNotification)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketNotification).Delete([]byte(notification.Filename))
+	})
+	if err != nil {
+		return werrors.WrapMsgf(err, "failed to store the dead letter of file %s", notification.Filename)
+	}
+	return nil
+}
+
+// GetDeadLetters returns the dead letters stored in the bolt db in the order they were queued
+func (bo BoltOutbox) GetDeadLetters() ([]Notification, error) {
+	return bo.getAll(bucketDeadLetter)
+}
+
+// getAll returns the notifications of the bucket in the order they were queued
+func (bo BoltOutbox) getAll(bucket []byte) ([]Notification, error) {
+	notifications := []Notification{}
+	err := bo.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(key, notificationJson []byte) error {
+			notification := Notification{}
+			err := json.Unmarshal(notificationJson, &notification)
+			if err != nil {
+				return werrors.WrapMsgf(err, "failed to unmarshal the notification of file %s", key)
+			}
+			notifications = append(notifications, notification)
+			return nil
+		})
+	})
+	if err != nil {
+		return []Notification{}, err
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].Queued < notifications[j].Queued
+	})
+	return notifications, nil
+}
+
+func (bo BoltOutbox) Disconnect() error {
+	return bo.db.Close()
+}
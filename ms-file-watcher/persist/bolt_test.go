@@ -0,0 +1,57 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox", "outbox.db")
+	outbox, err := NewBoltOutbox(path)
+	require.NoError(t, err)
+
+	notifications, err := outbox.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	second := Notification{Filename: "/tmp/foo/b.tiff", Queued: 2, Attempts: 1, NextAttempt: 20}
+	first := Notification{Filename: "/tmp/foo/a.tiff", Queued: 3, Attempts: 1, NextAttempt: 30}
+	require.NoError(t, outbox.Put(second))
+	require.NoError(t, outbox.Put(first))
+	first.Queued = 1
+	first.Attempts = 2
+	require.NoError(t, outbox.Put(first))
+
+	notifications, err = outbox.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, []Notification{first, second}, notifications, "notifications in queued order")
+
+	// the notifications are kept across a restart
+	require.NoError(t, outbox.Disconnect())
+	outbox, err = NewBoltOutbox(path)
+	require.NoError(t, err)
+	defer func() { _ = outbox.Disconnect() }()
+
+	require.NoError(t, outbox.Delete(first.Filename))
+	require.NoError(t, outbox.Delete("/tmp/foo/unknown.tiff"))
+	notifications, err = outbox.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, []Notification{second}, notifications)
+
+	second.Error = "400 Bad Request"
+	require.NoError(t, outbox.PutDeadLetter(second))
+	notifications, err = outbox.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, notifications, "dead letter no longer sent")
+	deadLetters, err := outbox.GetDeadLetters()
+	require.NoError(t, err)
+	assert.Equal(t, []Notification{second}, deadLetters)
+}
@@ -0,0 +1,34 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+// Notification is a new file notification that could not be sent to the data organizer and is sent again later
+type Notification struct {
+	Filename string
+	// Queued is when the notification was added to the outbox in Unix nanoseconds, the notifications are sent in this order
+	Queued int64
+	// Attempts is the number of times sending the notification failed
+	Attempts int
+	// NextAttempt is when the notification is sent again in Unix nanoseconds
+	NextAttempt int64
+	// Error is the last error sending the notification, set for the dead letters
+	Error string `json:",omitempty"`
+}
+
+// Outbox stores the new file notifications waiting to be sent to the data organizer
+type Outbox interface {
+	// Put stores the notification, replacing the stored notification of the file
+	Put(notification Notification) error
+	// Delete removes the notification of the file
+	Delete(filename string) error
+	// GetAll returns the stored notifications in the order they were queued
+	GetAll() ([]Notification, error)
+	// PutDeadLetter moves the notification of the file to the dead letters, which are no longer sent
+	PutDeadLetter(notification Notification) error
+	// GetDeadLetters returns the dead letters in the order they were queued
+	GetDeadLetters() ([]Notification, error)
+	Disconnect() error
+}
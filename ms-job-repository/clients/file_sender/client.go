@@ -0,0 +1,72 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package file_sender
+
+import (
+	"aicsd/pkg"
+	"aicsd/pkg/auth"
+	"aicsd/pkg/werrors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type FileSenderClient struct {
+	baseUrl     string
+	httpTimeout time.Duration
+	jwtInfo     *auth.JWTInfo
+}
+
+// NewClient builds a new file sender gateway client using the passed in base url
+func NewClient(baseUrl string, httpTimeout time.Duration, info *auth.JWTInfo) Client {
+	client := FileSenderClient{
+		baseUrl:     baseUrl,
+		httpTimeout: httpTimeout,
+		jwtInfo:     info,
+	}
+	return &client
+}
+
+// RejectFile copies the archived input file of the job into the reject folder of the file sender gateway
+func (c *FileSenderClient) RejectFile(jobId string) error {
+	return c.reject(http.MethodPost, jobId)
+}
+
+// UnrejectFile removes the input file of the job from the reject folder of the file sender gateway
+func (c *FileSenderClient) UnrejectFile(jobId string) error {
+	return c.reject(http.MethodDelete, jobId)
+}
+
+// reject sends the request with the given method to the reject endpoint for the job
+func (c *FileSenderClient) reject(method string, jobId string) error {
+	rejectUrl := c.baseUrl + strings.Replace(pkg.EndpointRejectFile, "{"+pkg.JobIdKey+"}", jobId, 1)
+	req, err := http.NewRequest(method, rejectUrl, nil)
+	if err != nil {
+		return werrors.WrapMsg(err, "could not create request to reject file")
+	}
+	err = c.jwtInfo.AddAuthHeader(req)
+	if err != nil {
+		return werrors.WrapErr(err, pkg.ErrAuthHeader)
+	}
+	client := &http.Client{
+		Timeout: c.httpTimeout,
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not do request to reject file for job %s: %s", jobId, err.Error())
+	}
+	defer response.Body.Close()
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response body: %s", err.Error())
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("reject file returned not ok status %s: %s", response.Status, respBody)
+	}
+	return nil
+}
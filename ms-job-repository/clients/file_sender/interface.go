@@ -0,0 +1,11 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package file_sender
+
+type Client interface {
+	RejectFile(jobId string) error
+	UnrejectFile(jobId string) error
+}
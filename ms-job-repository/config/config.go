@@ -10,12 +10,26 @@ import (
 	"fmt"
 	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Configuration struct {
-	RedisHost         string
-	RedisPort         string
-	LocalizationFiles []string
+	RedisHost             string
+	RedisPort             string
+	PersistenceType       string
+	PersistenceFile       string
+	LocalizationFiles     []string
+	EventsPort            string
+	EventsHost            string
+	EventsAllowedOrigin   string
+	StatsWindow           time.Duration
+	RetentionInterval     time.Duration
+	RetentionAges         map[string]time.Duration
+	RetentionExportFolder string
+	RetentionRemoveFiles  bool
+	FileSenderBaseUrl     string
 }
 
 func New(service interfaces.ApplicationService) (*Configuration, error) {
@@ -30,6 +44,10 @@ func New(service interfaces.ApplicationService) (*Configuration, error) {
 	if err != nil {
 		return nil, err
 	}
+	config.PersistenceType, config.PersistenceFile, err = helpers.GetPersistenceFromAppSetting(service)
+	if err != nil {
+		return nil, err
+	}
 
 	config.LocalizationFiles, err = service.GetAppSettingStrings("LocalizationFiles")
 	if len(config.LocalizationFiles) == 0 {
@@ -40,5 +58,100 @@ func New(service interfaces.ApplicationService) (*Configuration, error) {
 			return nil, fmt.Errorf("localization file named %s not found", file)
 		}
 	}
+
+	config.EventsPort, err = helpers.GetAppSetting(service, "EventsPort", true)
+	if err != nil {
+		return nil, err
+	}
+	if config.EventsPort != "" {
+		if _, err := strconv.ParseUint(config.EventsPort, 10, 16); err != nil {
+			return nil, fmt.Errorf("could not parse port for events port, got %s: %s", config.EventsPort, err.Error())
+		}
+	}
+	config.EventsHost, err = helpers.GetAppSetting(service, "EventsHost", true)
+	if err != nil {
+		return nil, err
+	}
+	config.EventsAllowedOrigin, err = helpers.GetAppSetting(service, "EventsAllowedOrigin", true)
+	if err != nil {
+		return nil, err
+	}
+
+	statsWindow, err := helpers.GetAppSetting(service, "StatsWindow", false)
+	if err != nil {
+		return nil, err
+	}
+	config.StatsWindow, err = time.ParseDuration(statsWindow)
+	if err != nil || config.StatsWindow <= 0 {
+		return nil, fmt.Errorf("could not parse positive duration for stats window, got %s", statsWindow)
+	}
+
+	retentionInterval, err := helpers.GetAppSetting(service, "RetentionInterval", true)
+	if err != nil {
+		return nil, err
+	}
+	if retentionInterval != "" {
+		config.RetentionInterval, err = time.ParseDuration(retentionInterval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse duration for retention interval, got %s: %s", retentionInterval, err.Error())
+		}
+	}
+
+	retentionAges, err := helpers.GetAppSetting(service, "RetentionAges", true)
+	if err != nil {
+		return nil, err
+	}
+	config.RetentionAges, err = parseRetentionAges(retentionAges)
+	if err != nil {
+		return nil, err
+	}
+
+	config.RetentionExportFolder, err = helpers.GetAppSetting(service, "RetentionExportFolder", true)
+	if err != nil {
+		return nil, err
+	}
+
+	retentionRemoveFiles, err := helpers.GetAppSetting(service, "RetentionRemoveFiles", false)
+	if err != nil {
+		return nil, err
+	}
+	config.RetentionRemoveFiles, err = strconv.ParseBool(retentionRemoveFiles)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse bool for retention remove files, got %s: %s", retentionRemoveFiles, err.Error())
+	}
+
+	// the file sender gateway is optional, without it rejected jobs are not copied into the reject folder
+	fileSenderHost, err := helpers.GetAppSetting(service, "FileSenderHost", true)
+	if err != nil {
+		return nil, err
+	}
+	if fileSenderHost != "" {
+		config.FileSenderBaseUrl, err = helpers.GetUrlFromAppSetting(service, "FileSender", "http", false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
+
+// parseRetentionAges parses the comma separated list of job status and age pairs, e.g. Complete:720h,PipelineError:168h
+func parseRetentionAges(value string) (map[string]time.Duration, error) {
+	ages := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		status, age, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("could not parse retention age %s, expected status:duration", entry)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(age))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse duration for retention age %s: %s", entry, err.Error())
+		}
+		ages[strings.TrimSpace(status)] = duration
+	}
+	return ages, nil
+}
@@ -6,7 +6,9 @@
 package controller
 
 import (
+	"aicsd/ms-job-repository/clients/file_sender"
 	"aicsd/ms-job-repository/persist"
+	"aicsd/ms-job-repository/stats"
 	"aicsd/pkg"
 	"aicsd/pkg/helpers"
 	"aicsd/pkg/types"
@@ -17,17 +19,34 @@ import (
 	"fmt"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
 )
 
+const (
+	// defaultStatsWindow is the StatsWindow of a new controller
+	defaultStatsWindow = 24 * time.Hour
+	// maxPipelineRunAttempts is the number of times the pipeline details of a fanned out job are written
+	// before the concurrent updates of the other runs are reported as a conflict
+	maxPipelineRunAttempts = 5
+)
+
 type JobRepoController struct {
 	lc                logger.LoggingClient
 	persist           persist.Persistence
 	bundle            *i18n.Bundle
 	DependentServices wait.Services
+	// StatsWindow is the window of the job statistics when the request does not specify one
+	StatsWindow time.Duration
+	// FileSenderClient updates the reject folder of the file sender gateway on reviews, nil when it is not configured
+	FileSenderClient file_sender.Client
 }
 
 // New defines a client for the JobRepoController
@@ -37,6 +56,7 @@ func New(lc logger.LoggingClient, persist persist.Persistence, bundle *i18n.Bund
 		persist:           persist,
 		bundle:            bundle,
 		DependentServices: wait.Services{wait.ServiceConsul, wait.ServiceRedis},
+		StatsWindow:       defaultStatsWindow,
 	}
 }
 
@@ -58,6 +78,11 @@ func (c *JobRepoController) RegisterRoutes(service interfaces.ApplicationService
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "Update")
 	}
 
+	err = service.AddRoute(pkg.EndpointJobId, c.Patch, http.MethodPatch)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "Patch")
+	}
+
 	err = service.AddRoute(pkg.EndpointJobPipeline, c.UpdatePipeline, http.MethodPut)
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "UpdatePipeline")
@@ -68,6 +93,17 @@ func (c *JobRepoController) RegisterRoutes(service interfaces.ApplicationService
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetAll")
 	}
 
+	// the stats and review routes must be registered before the job id route which would otherwise match them
+	err = service.AddRoute(pkg.EndpointJobStats, c.GetStats, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetStats")
+	}
+
+	err = service.AddRoute(pkg.EndpointJobReview, c.GetReviewQueue, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetReviewQueue")
+	}
+
 	err = service.AddRoute(pkg.EndpointJobId, c.GetById, http.MethodGet)
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetById")
@@ -78,6 +114,21 @@ func (c *JobRepoController) RegisterRoutes(service interfaces.ApplicationService
 		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetByOwner")
 	}
 
+	err = service.AddRoute(pkg.EndpointJobHistory, c.GetHistory, http.MethodGet)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "GetHistory")
+	}
+
+	err = service.AddRoute(pkg.EndpointJobAccept, c.Accept, http.MethodPost)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "Accept")
+	}
+
+	err = service.AddRoute(pkg.EndpointJobReject, c.Reject, http.MethodPost)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRegisterRoutes, "Reject")
+	}
+
 	return nil
 }
 
@@ -112,12 +163,30 @@ func (c *JobRepoController) Create(writer http.ResponseWriter, request *http.Req
 	}
 }
 
-// GetAll is a request to retrieve all jobs
+// GetAll is a request to retrieve all jobs.
+// When query parameters are given only the matching jobs are returned a page at a time,
+// and the cursor for the next page is returned in the pkg.HeaderNextCursor header.
 func (c *JobRepoController) GetAll(writer http.ResponseWriter, request *http.Request) {
 	accept := request.Header.Get("Accept-Language")
 	c.lc.Debugf("accept language from headers= %s", accept)
 
-	job, err := c.persist.GetAll()
+	var job []types.Job
+	var err error
+	if len(request.URL.Query()) == 0 {
+		job, err = c.persist.GetAll()
+	} else {
+		var filter types.JobFilter
+		filter, err = parseJobFilter(request.URL.Query())
+		if err != nil {
+			helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+			return
+		}
+		var nextCursor string
+		job, nextCursor, err = c.persist.Query(filter)
+		if nextCursor != "" {
+			writer.Header().Set(pkg.HeaderNextCursor, nextCursor)
+		}
+	}
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer,
 			werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusInternalServerError)
@@ -167,6 +236,7 @@ func (c *JobRepoController) GetById(writer http.ResponseWriter, request *http.Re
 		helpers.HandleErrorMessage(c.lc, writer,
 			werrors.WrapErr(err, pkg.ErrMarshallingJob), http.StatusInternalServerError)
 	}
+	setETag(writer, job)
 	writer.Header().Set("Content-Type", "application/json")
 	_, err = writer.Write(jsonRsp)
 	if err != nil {
@@ -200,13 +270,79 @@ func (c *JobRepoController) GetByOwner(writer http.ResponseWriter, request *http
 	}
 }
 
-// Update is a whole update of the job object
+// GetHistory is a request to retrieve the updates made to the job for a specified id, oldest first
+func (c *JobRepoController) GetHistory(writer http.ResponseWriter, request *http.Request) {
+	id, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+
+	history, err := c.persist.GetHistory(id)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusInternalServerError)
+		return
+	}
+	jsonRsp, err := json.Marshal(history)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapMsg(err, "failed to marshal job history"), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// GetStats is a request to retrieve the statistics of the jobs updated within the window query parameter,
+// or the StatsWindow if it is not given
+func (c *JobRepoController) GetStats(writer http.ResponseWriter, request *http.Request) {
+	window := c.StatsWindow
+	if value := request.URL.Query().Get(pkg.QueryWindowKey); value != "" {
+		var err error
+		window, err = time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf(pkg.ErrFmtInvalidInput, value, "window as a positive duration"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	jobStats, err := stats.Collect(c.persist, time.Now().UTC(), window)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusInternalServerError)
+		return
+	}
+	jsonRsp, err := json.Marshal(jobStats)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapMsg(err, "failed to marshal job statistics"), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// Update is an update of the job fields given as a map of dot separated field paths to their values.
+// Fields that are unknown, maintained by the job repository or set to a value of the wrong type are rejected.
+// If the If-Match header is set the update is only applied if the job version still matches the ETag.
 func (c *JobRepoController) Update(writer http.ResponseWriter, request *http.Request) {
 	id, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
 		return
 	}
+	version, err := versionFromIfMatch(request)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
 	var jobFields map[string]interface{}
 
 	requestBody := make([]byte, request.ContentLength)
@@ -222,10 +358,10 @@ func (c *JobRepoController) Update(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	job, err := c.persist.Update(id, jobFields)
+	job, err := c.persist.Update(id, jobFields, callerFromRequest(request), version)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "failed to update job for id (%s)",
-			id), http.StatusNotFound)
+			id), updateErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -234,6 +370,7 @@ func (c *JobRepoController) Update(writer http.ResponseWriter, request *http.Req
 		helpers.HandleErrorMessage(c.lc, writer,
 			werrors.WrapErr(err, pkg.ErrMarshallingJob), http.StatusInternalServerError)
 	}
+	setETag(writer, job)
 	writer.Header().Set("Content-Type", "application/json")
 	_, err = writer.Write(jsonRsp)
 	if err != nil {
@@ -241,13 +378,19 @@ func (c *JobRepoController) Update(writer http.ResponseWriter, request *http.Req
 	}
 }
 
-// UpdatePipeline updates the job object's pipeline details
+// UpdatePipeline updates the job object's pipeline details, or the details of the task run when the job is fanned out.
+// If the If-Match header is set the update is only applied if the job version still matches the ETag.
 func (c *JobRepoController) UpdatePipeline(writer http.ResponseWriter, request *http.Request) {
 	jobId, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
 		return
 	}
+	version, err := versionFromIfMatch(request)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
 
 	taskId, err := helpers.GetByKeyFromRequest(request, pkg.TaskIdKey)
 	if err != nil {
@@ -262,7 +405,7 @@ func (c *JobRepoController) UpdatePipeline(writer http.ResponseWriter, request *
 		return
 	}
 
-	if job.PipelineDetails.TaskId != taskId {
+	if job.PipelineDetails.TaskId != taskId && job.PipelineRunIndex(taskId) < 0 {
 		helpers.HandleErrorMessage(c.lc, writer, errors.New("url taskid parameter does not match taskid for specified job"),
 			http.StatusBadRequest)
 		return
@@ -295,22 +438,59 @@ func (c *JobRepoController) UpdatePipeline(writer http.ResponseWriter, request *
 
 	// TaskId is passed in URL and is not expected to be in the JSON body, so use the one from the URL.
 	// OutputFileHost is previously set and is not expected to be in the JSON body, so use previous value
-	jobFields := make(map[string]interface{})
-	jobFields[types.JobPipelineStatus] = pipelineDetails.Status
-	jobFields[types.JobPipelineQCFlags] = pipelineDetails.QCFlags
-	jobFields[types.JobPipelineOutputFiles] = pipelineDetails.OutputFiles
-	jobFields[types.JobPipelineResults] = pipelineDetails.Results
+	if job.PipelineDetails.TaskId == taskId {
+		jobFields := make(map[string]interface{})
+		jobFields[types.JobPipelineStatus] = pipelineDetails.Status
+		jobFields[types.JobPipelineQCFlags] = pipelineDetails.QCFlags
+		jobFields[types.JobPipelineOutputFiles] = pipelineDetails.OutputFiles
+		jobFields[types.JobPipelineResults] = pipelineDetails.Results
 
-	_, err = c.persist.Update(jobId, jobFields)
+		job, err = c.persist.Update(jobId, jobFields, callerFromRequest(request), version)
+	} else {
+		pipelineDetails.TaskId = taskId
+		job, err = c.updatePipelineRun(job, pipelineDetails, callerFromRequest(request), version)
+	}
 	if err != nil {
 		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "failed to update job pipeline details for job id (%s)",
-			jobId), http.StatusInternalServerError)
+			jobId), updateErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
+	setETag(writer, job)
 	writer.WriteHeader(http.StatusOK)
 }
 
+// updatePipelineRun records the pipeline details of the task run of a fanned out job.
+// The runs are updated as a whole, so unless a version is requested the update is repeated on a fresh copy
+// of the job when the run of another task changed it concurrently.
+func (c *JobRepoController) updatePipelineRun(job types.Job, pipelineDetails types.PipelineInfo, caller string, version int64) (types.Job, error) {
+	for attempt := 1; ; attempt++ {
+		runIndex := job.PipelineRunIndex(pipelineDetails.TaskId)
+		if runIndex < 0 {
+			return types.Job{}, fmt.Errorf("job %s has no run for task %s", job.Id, pipelineDetails.TaskId)
+		}
+		runs := append([]types.PipelineInfo{}, job.PipelineRuns...)
+		pipelineDetails.OutputFileHost = runs[runIndex].OutputFileHost
+		runs[runIndex] = pipelineDetails
+
+		expectedVersion := version
+		if expectedVersion == 0 {
+			expectedVersion = job.Version
+		}
+		updated, err := c.persist.Update(job.Id, map[string]interface{}{types.JobPipelineRuns: runs}, caller, expectedVersion)
+		if err != pkg.ErrJobVersionMismatch || version != 0 {
+			return updated, err
+		}
+		if attempt == maxPipelineRunAttempts {
+			return types.Job{}, pkg.ErrJobUpdateConflict
+		}
+		job, err = c.persist.GetById(job.Id)
+		if err != nil {
+			return types.Job{}, err
+		}
+	}
+}
+
 // Delete is a request to remove a job for a specified id
 func (c *JobRepoController) Delete(writer http.ResponseWriter, request *http.Request) {
 	id, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
@@ -326,3 +506,113 @@ func (c *JobRepoController) Delete(writer http.ResponseWriter, request *http.Req
 	}
 	writer.WriteHeader(http.StatusOK)
 }
+
+// parseJobFilter builds the job filter from the GetAll query parameters.
+// Attributes are filtered with parameters of the form attr.{name}={value}.
+func parseJobFilter(query url.Values) (types.JobFilter, error) {
+	filter := types.JobFilter{
+		Status: query.Get(pkg.QueryStatusKey),
+		Owner:  query.Get(pkg.QueryOwnerKey),
+		TaskId: query.Get(pkg.QueryTaskIdKey),
+		Cursor: query.Get(pkg.QueryCursorKey),
+	}
+	var err error
+	if value := query.Get(pkg.QuerySinceKey); value != "" {
+		filter.Since, err = parseQueryTime(value)
+		if err != nil {
+			return filter, fmt.Errorf(pkg.ErrFmtInvalidInput, value, "since as nanoseconds or RFC3339 time")
+		}
+	}
+	if value := query.Get(pkg.QueryUntilKey); value != "" {
+		filter.Until, err = parseQueryTime(value)
+		if err != nil {
+			return filter, fmt.Errorf(pkg.ErrFmtInvalidInput, value, "until as nanoseconds or RFC3339 time")
+		}
+	}
+	if value := query.Get(pkg.QueryLimitKey); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit <= 0 {
+			return filter, fmt.Errorf(pkg.ErrFmtInvalidInput, value, "limit as a positive integer")
+		}
+	}
+	if filter.Cursor != "" {
+		err = persist.ValidateCursor(filter.Cursor)
+		if err != nil {
+			return filter, err
+		}
+	}
+	for key, values := range query {
+		if !strings.HasPrefix(key, pkg.QueryAttributesKey) {
+			continue
+		}
+		name := strings.TrimPrefix(key, pkg.QueryAttributesKey)
+		if name == "" || len(values) == 0 {
+			return filter, fmt.Errorf(pkg.ErrFmtInvalidInput, key, "attribute name and value")
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[name] = values[0]
+	}
+	return filter, nil
+}
+
+// parseQueryTime parses a time given either as nanoseconds like Job.LastUpdated or as an RFC3339 time
+func parseQueryTime(value string) (int64, error) {
+	nanoseconds, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return nanoseconds, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.UTC().UnixNano(), nil
+}
+
+// callerFromRequest identifies the caller of a request for the job history using the pkg.HeaderCaller header,
+// falling back to the remote address when the header is not set
+func callerFromRequest(request *http.Request) string {
+	caller := request.Header.Get(pkg.HeaderCaller)
+	if caller != "" {
+		return caller
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// versionFromIfMatch returns the job version from the If-Match header, or 0 if the header is not set or is *
+func versionFromIfMatch(request *http.Request) (int64, error) {
+	ifMatch := strings.TrimSpace(request.Header.Get(pkg.HeaderIfMatch))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf(pkg.ErrFmtInvalidInput, ifMatch, "If-Match as a job ETag")
+	}
+	return version, nil
+}
+
+// setETag sets the ETag header to the job version
+func setETag(writer http.ResponseWriter, job types.Job) {
+	writer.Header().Set(pkg.HeaderETag, fmt.Sprintf(`"%d"`, job.Version))
+}
+
+// updateErrorStatus returns the http status for an error returned from persist Update,
+// using the default status for errors that are not related to the job version or the job fields
+func updateErrorStatus(err error, defaultStatus int) int {
+	if _, ok := err.(*persist.InvalidFieldsError); ok {
+		return http.StatusBadRequest
+	}
+	switch err {
+	case pkg.ErrJobVersionMismatch:
+		return http.StatusPreconditionFailed
+	case pkg.ErrJobUpdateConflict:
+		return http.StatusConflict
+	}
+	return defaultStatus
+}
@@ -10,11 +10,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -145,6 +147,64 @@ func TestJobRepoController_GetAll(t *testing.T) {
 	}
 }
 
+func TestJobRepoController_GetAllQuery(t *testing.T) {
+	jobs := []types.Job{helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)}
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		Name               string
+		Query              string
+		ExpectedFilter     types.JobFilter
+		PersistMockCursor  string
+		PersistMockErr     error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path - status and owner", "status=Complete&owner=task-launcher",
+			types.JobFilter{Status: pkg.StatusComplete, Owner: pkg.OwnerTaskLauncher}, "", nil, http.StatusOK, ""},
+		{"happy path - task and attribute", "taskId=1&attr.LabName=TestLab",
+			types.JobFilter{TaskId: "1", Attributes: map[string]string{"LabName": "TestLab"}}, "", nil, http.StatusOK, ""},
+		{"happy path - time range and page", "since=" + since.Format(time.RFC3339) + "&until=1700000000000000000&limit=10",
+			types.JobFilter{Since: since.UnixNano(), Until: 1700000000000000000, Limit: 10}, "next", nil, http.StatusOK, ""},
+		{"invalid since", "since=yesterday", types.JobFilter{}, "", nil, http.StatusBadRequest, "yesterday"},
+		{"invalid limit", "limit=-1", types.JobFilter{}, "", nil, http.StatusBadRequest, "limit"},
+		{"invalid cursor", "cursor=bogus", types.JobFilter{}, "", nil, http.StatusBadRequest, "invalid cursor"},
+		{"error retrieving", "status=Complete", types.JobFilter{Status: pkg.StatusComplete}, "", pkg.ErrRetrieving, http.StatusInternalServerError, pkg.ErrRetrieving.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+
+			persistMock.On("Query", test.ExpectedFilter).Return(jobs, test.PersistMockCursor, test.PersistMockErr)
+
+			req := httptest.NewRequest("GET", "http://localhost?"+test.Query, nil)
+			w := httptest.NewRecorder()
+
+			jobRepoController.GetAll(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+
+			var actual []types.Job
+			err = json.Unmarshal(body, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, jobs, actual)
+			assert.Equal(t, test.PersistMockCursor, resp.Header.Get(pkg.HeaderNextCursor))
+			persistMock.AssertNotCalled(t, "GetAll")
+		})
+	}
+}
+
 func TestJobRepoController_GetById(t *testing.T) {
 	expected := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
 
@@ -256,6 +316,113 @@ func TestJobRepoController_GetByOwner(t *testing.T) {
 	}
 }
 
+func TestJobRepoController_GetHistory(t *testing.T) {
+	history := []types.JobHistoryEntry{{
+		Timestamp: 1,
+		Caller:    pkg.OwnerTaskLauncher,
+		OldOwner:  pkg.OwnerTaskLauncher,
+		NewOwner:  pkg.OwnerFileSenderGateway,
+		Changes:   []types.FieldChange{{Path: types.JobOwner, OldValue: pkg.OwnerTaskLauncher, NewValue: pkg.OwnerFileSenderGateway}},
+	}}
+
+	tests := []struct {
+		Name               string
+		Id                 string
+		PersistMockHistory []types.JobHistoryEntry
+		PersistMockErr     error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", "1", history, nil, http.StatusOK, ""},
+		{"happy path - no updates", "1", []types.JobHistoryEntry{}, nil, http.StatusOK, ""},
+		{"missing id", "nil", nil, nil, http.StatusBadRequest, "missing jobid in url"},
+		{"error retrieving", "1", nil, fmt.Errorf(pkg.ErrJobIdNotFound, "1"), http.StatusInternalServerError, pkg.ErrRetrieving.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+			persistMock.On("GetHistory", test.Id).Return(test.PersistMockHistory, test.PersistMockErr)
+
+			req := httptest.NewRequest("GET", "http://localhost", nil)
+			if test.Id != "nil" {
+				req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.Id})
+			}
+			w := httptest.NewRecorder()
+			jobRepoController.GetHistory(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var actual []types.JobHistoryEntry
+			err = json.Unmarshal(body, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, test.PersistMockHistory, actual)
+		})
+	}
+}
+
+func TestJobRepoController_GetStats(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	job.Status = pkg.StatusComplete
+
+	tests := []struct {
+		Name               string
+		Window             string
+		PersistMockErr     error
+		ExpectedWindow     time.Duration
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path - default window", "", nil, defaultStatsWindow, http.StatusOK, ""},
+		{"happy path - window", "1h", nil, time.Hour, http.StatusOK, ""},
+		{"invalid window", "bogus", nil, 0, http.StatusBadRequest, "window as a positive duration"},
+		{"negative window", "-1h", nil, 0, http.StatusBadRequest, "window as a positive duration"},
+		{"error retrieving", "", errors.New("query failed"), defaultStatsWindow, http.StatusInternalServerError, pkg.ErrRetrieving.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+			persistMock.On("Query", mock.MatchedBy(func(filter types.JobFilter) bool {
+				return time.Duration(filter.Until-filter.Since) == test.ExpectedWindow
+			})).Return([]types.Job{job}, "", test.PersistMockErr)
+
+			req := httptest.NewRequest("GET", "http://localhost?"+pkg.QueryWindowKey+"="+test.Window, nil)
+			w := httptest.NewRecorder()
+			jobRepoController.GetStats(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var actual types.JobStats
+			err = json.Unmarshal(body, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, 1, actual.Total)
+			assert.Equal(t, map[string]int{pkg.StatusComplete: 1}, actual.StatusCounts)
+			assert.Equal(t, test.ExpectedWindow, time.Duration(actual.Until-actual.Since))
+		})
+	}
+}
+
 func TestJobRepoController_Update(t *testing.T) {
 	job := helpers.CreateTestJob(pkg.JobRepository, fileHostname)
 	expected := make(map[string]interface{})
@@ -266,15 +433,23 @@ func TestJobRepoController_Update(t *testing.T) {
 		Name               string
 		Expected           map[string]interface{}
 		Id                 string
+		IfMatch            string
+		Version            int64
 		PersistMockErr     error
 		ExpectedStatusCode int
 		ExpectedErrorMsg   string
 	}{
-		{"happy path", expected, "1", nil, http.StatusOK, ""},
-		{"missing id", nil, "nil", nil, http.StatusBadRequest, "missing jobid in url"},
-		{"empty id", nil, "", nil, http.StatusBadRequest, "empty jobid in url"},
-		{"unmarshal failed", nil, "1", nil, http.StatusBadRequest, pkg.ErrUnmarshallingJob.Error()},
-		{"update error", expected, "1", errors.New("update failed"), http.StatusNotFound, "failed to update job for id"},
+		{"happy path", expected, "1", "", 0, nil, http.StatusOK, ""},
+		{"happy path - if match", expected, "1", `"3"`, 3, nil, http.StatusOK, ""},
+		{"happy path - if match any", expected, "1", "*", 0, nil, http.StatusOK, ""},
+		{"missing id", nil, "nil", "", 0, nil, http.StatusBadRequest, "missing jobid in url"},
+		{"empty id", nil, "", "", 0, nil, http.StatusBadRequest, "empty jobid in url"},
+		{"invalid if match", expected, "1", `"bogus"`, 0, nil, http.StatusBadRequest, "If-Match"},
+		{"unmarshal failed", nil, "1", "", 0, nil, http.StatusBadRequest, pkg.ErrUnmarshallingJob.Error()},
+		{"update error", expected, "1", "", 0, errors.New("update failed"), http.StatusNotFound, "failed to update job for id"},
+		{"version mismatch", expected, "1", `"2"`, 2, pkg.ErrJobVersionMismatch, http.StatusPreconditionFailed, pkg.ErrJobVersionMismatch.Error()},
+		{"update conflict", expected, "1", "", 0, pkg.ErrJobUpdateConflict, http.StatusConflict, pkg.ErrJobUpdateConflict.Error()},
+		{"invalid fields", expected, "1", "", 0, &persist.InvalidFieldsError{Fields: map[string]string{types.JobStatus: "bad status"}}, http.StatusBadRequest, "Status (bad status)"},
 	}
 
 	for _, test := range tests {
@@ -289,8 +464,12 @@ func TestJobRepoController_Update(t *testing.T) {
 			} else {
 				requestBody = nil
 			}
-			persistMock.On("Update", test.Id, test.Expected).Return(job, test.PersistMockErr)
+			persistMock.On("Update", test.Id, test.Expected, pkg.OwnerTaskLauncher, test.Version).Return(job, test.PersistMockErr)
 			req := httptest.NewRequest("PUT", "http://localhost", bytes.NewReader(requestBody))
+			req.Header.Set(pkg.HeaderCaller, pkg.OwnerTaskLauncher)
+			if test.IfMatch != "" {
+				req.Header.Set(pkg.HeaderIfMatch, test.IfMatch)
+			}
 			w := httptest.NewRecorder()
 			if test.Id != "nil" {
 				req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.Id})
@@ -309,6 +488,7 @@ func TestJobRepoController_Update(t *testing.T) {
 			var actualJob types.Job
 			err = json.Unmarshal(body, &actualJob)
 			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(`"%d"`, job.Version), resp.Header.Get(pkg.HeaderETag))
 			persistMock.AssertExpectations(t)
 		})
 	}
@@ -348,6 +528,7 @@ func TestJobRepoController_UpdatePipeline(t *testing.T) {
 		{"invalid Details", &expectedJob, &invalidDetailsBadStatus, expectedJob.Id, expectedJob.PipelineDetails.TaskId, nil, nil, http.StatusBadRequest, "invalid Status value"},
 		{"update error", &expectedJob, &validDetails, expectedJob.Id, expectedJob.PipelineDetails.TaskId, nil, errors.New("update failed"), http.StatusInternalServerError, "update failed"},
 		{"unmarshal error", &expectedJob, nil, expectedJob.Id, expectedJob.PipelineDetails.TaskId, nil, errors.New("update failed"), http.StatusInternalServerError, "failed to unmarshal request"},
+		{"version mismatch", &expectedJob, &validDetails, expectedJob.Id, expectedJob.PipelineDetails.TaskId, nil, pkg.ErrJobVersionMismatch, http.StatusPreconditionFailed, pkg.ErrJobVersionMismatch.Error()},
 	}
 
 	for _, test := range tests {
@@ -365,7 +546,7 @@ func TestJobRepoController_UpdatePipeline(t *testing.T) {
 				requestBody = nil
 			}
 			persistMock.On("GetById", mock.Anything).Return(existingJob, test.PersistGetMockErr)
-			persistMock.On("Update", mock.Anything, mock.Anything).Return(*test.ExpectedJob, test.PersistUpdateMockErr)
+			persistMock.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(*test.ExpectedJob, test.PersistUpdateMockErr)
 			req := httptest.NewRequest("PUT", "http://localhost", bytes.NewReader(requestBody))
 			w := httptest.NewRecorder()
 			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.JobId, pkg.TaskIdKey: test.TaskId})
@@ -387,6 +568,80 @@ func TestJobRepoController_UpdatePipeline(t *testing.T) {
 	}
 }
 
+func TestJobRepoController_UpdatePipelineRun(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.Version = 3
+	job.PipelineDetails = types.PipelineInfo{Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname}
+	job.PipelineRuns = []types.PipelineInfo{
+		{TaskId: "1", Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+		{TaskId: "2", Status: pkg.TaskStatusProcessing, OutputFileHost: fileHostname},
+	}
+	details := types.PipelineInfo{Status: pkg.TaskStatusComplete, Results: "count,5",
+		OutputFiles: []types.OutputFile{{DirName: "/tmp/files/output", Name: "out.tiff", Extension: "tiff"}}}
+	expectedRuns := []types.PipelineInfo{job.PipelineRuns[0], details}
+	expectedRuns[1].TaskId = "2"
+	expectedRuns[1].OutputFileHost = fileHostname
+	expectedFields := map[string]interface{}{types.JobPipelineRuns: expectedRuns}
+
+	tests := []struct {
+		Name               string
+		TaskId             string
+		IfMatch            string
+		Version            int64
+		UpdateErrs         []error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", "2", "", 3, []error{nil}, http.StatusOK, ""},
+		{"happy path - retry after concurrent update", "2", "", 3, []error{pkg.ErrJobVersionMismatch, nil}, http.StatusOK, ""},
+		{"unknown task", "3", "", 3, nil, http.StatusBadRequest, "url taskid parameter does not match taskid for specified job"},
+		{"version mismatch", "2", `"2"`, 2, []error{pkg.ErrJobVersionMismatch}, http.StatusPreconditionFailed, pkg.ErrJobVersionMismatch.Error()},
+		{"too many concurrent updates", "2", "", 3, []error{pkg.ErrJobVersionMismatch, pkg.ErrJobVersionMismatch, pkg.ErrJobVersionMismatch,
+			pkg.ErrJobVersionMismatch, pkg.ErrJobVersionMismatch}, http.StatusConflict, pkg.ErrJobUpdateConflict.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+
+			updated := job
+			updated.Version = 4
+			updated.PipelineRuns = expectedRuns
+			persistMock.On("GetById", job.Id).Return(job, nil)
+			for _, updateErr := range test.UpdateErrs {
+				persistMock.On("Update", job.Id, expectedFields, pkg.OwnerNone, test.Version).Return(updated, updateErr).Once()
+			}
+
+			requestBody, err := json.Marshal(details)
+			require.NoError(t, err)
+			req := httptest.NewRequest("PUT", "http://localhost", bytes.NewReader(requestBody))
+			req.Header.Set(pkg.HeaderCaller, pkg.OwnerNone)
+			if test.IfMatch != "" {
+				req.Header.Set(pkg.HeaderIfMatch, test.IfMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: job.Id, pkg.TaskIdKey: test.TaskId})
+			w := httptest.NewRecorder()
+
+			jobRepoController.UpdatePipeline(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			persistMock.AssertExpectations(t)
+			if test.ExpectedStatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			assert.Equal(t, `"4"`, resp.Header.Get(pkg.HeaderETag))
+		})
+	}
+}
+
 func TestJobRepoController_Delete(t *testing.T) {
 	tests := []struct {
 		Name               string // test name
@@ -0,0 +1,167 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats selected with the format query parameter
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// exportColumns is the header of the csv export, in the order written by csvRecords
+var exportColumns = []string{
+	"Id", "Owner", "Status", "LastUpdated", "Verification",
+	"InputFile.Hostname", "InputFile.DirName", "InputFile.Name", "InputFile.ArchiveName", "InputFile.Attributes",
+	"Pipeline", "Pipeline.TaskId", "Pipeline.TaskVersion", "Pipeline.Status", "Pipeline.QCFlags", "Pipeline.Results",
+	"Pipeline.OutputFiles", "ErrorDetails.Owner", "ErrorDetails.Error",
+}
+
+// jobEncoder writes a single job of the export
+type jobEncoder func(job types.Job) error
+
+// Export is a request to stream the jobs matching the GetAll query parameters, except for the cursor,
+// as newline delimited json or as csv with a flattened row for each pipeline of the job. The jobs are written
+// a page at a time as they are queried, least recently updated first, and the limit caps the number of jobs exported.
+// The export is served on the EventsPort, as the responses of the app service routes are buffered.
+func (c *JobRepoController) Export(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("method %s not allowed", request.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	accept := request.Header.Get(pkg.AcceptLanguage)
+	query := request.URL.Query()
+	filter, err := parseJobFilter(query)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+
+	var encode jobEncoder
+	var csvWriter *csv.Writer
+	format := query.Get(pkg.QueryFormatKey)
+	switch format {
+	case "", exportFormatNDJSON:
+		format = exportFormatNDJSON
+		encoder := json.NewEncoder(writer)
+		encode = func(job types.Job) error { return encoder.Encode(job) }
+		writer.Header().Set("Content-Type", "application/x-ndjson")
+	case exportFormatCSV:
+		csvWriter = csv.NewWriter(writer)
+		encode = func(job types.Job) error {
+			for _, record := range csvRecords(job) {
+				if err := csvWriter.Write(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		writer.Header().Set("Content-Type", "text/csv")
+	default:
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf(pkg.ErrFmtInvalidInput, format, "format as ndjson or csv"), http.StatusBadRequest)
+		return
+	}
+
+	// the first page is queried before the headers are sent so a failure can still be reported
+	limit := filter.Limit
+	filter.Limit = persist.MaxQueryLimit
+	filter.Cursor = ""
+	jobs, cursor, err := c.persist.Query(filter)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="jobs.%s"`, format))
+	writer.WriteHeader(http.StatusOK)
+	if csvWriter != nil {
+		err = csvWriter.Write(exportColumns)
+	}
+
+	exported := 0
+	limitReached := func() bool { return limit > 0 && exported == limit }
+	for err == nil {
+		for k := 0; k < len(jobs) && !limitReached() && err == nil; k++ {
+			translateErr := jobs[k].Translate(c.bundle, accept)
+			if translateErr != nil {
+				// Note: Soft error out on translation errors by logging issue with relevant job information.
+				c.lc.Errorf("job.Translate() failed with error %v for job %v", pkg.ErrTranslating, jobs[k])
+			}
+			err = encode(jobs[k])
+			exported++
+		}
+		if csvWriter != nil && err == nil {
+			csvWriter.Flush()
+			err = csvWriter.Error()
+		}
+		if err != nil || cursor == "" || limitReached() {
+			break
+		}
+		// send the page to the client before querying the next one
+		if flusher, ok := writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		filter.Cursor = cursor
+		jobs, cursor, err = c.persist.Query(filter)
+	}
+	if err != nil {
+		// the response has started so the export can only be cut short
+		c.lc.Errorf("job export stopped after %d jobs: %s", exported, err.Error())
+		return
+	}
+	c.lc.Debugf("exported %d jobs as %s", exported, format)
+}
+
+// csvRecords flattens the job into the exportColumns, with a row for the PipelineDetails followed by a row
+// for each of the PipelineRuns and PipelineStages
+func csvRecords(job types.Job) [][]string {
+	attributes := make([]string, 0, len(job.InputFile.Attributes))
+	for name, value := range job.InputFile.Attributes {
+		attributes = append(attributes, name+"="+value)
+	}
+	sort.Strings(attributes)
+	var errorOwner, errorMessage string
+	if job.ErrorDetails != nil {
+		errorOwner, errorMessage = job.ErrorDetails.Owner, job.ErrorDetails.Error
+	}
+	record := func(pipeline string, info types.PipelineInfo) []string {
+		outputFiles := make([]string, len(info.OutputFiles))
+		for i, file := range info.OutputFiles {
+			outputFiles[i] = filepath.Join(file.DirName, file.Name)
+		}
+		return []string{
+			job.Id, job.Owner, job.Status, time.Unix(0, job.LastUpdated).UTC().Format(time.RFC3339Nano), strconv.Itoa(job.Verification),
+			job.InputFile.Hostname, job.InputFile.DirName, job.InputFile.Name, job.InputFile.ArchiveName, strings.Join(attributes, ";"),
+			pipeline, info.TaskId, strconv.FormatInt(info.TaskVersion, 10), info.Status, info.QCFlags, info.Results,
+			strings.Join(outputFiles, ";"), errorOwner, errorMessage,
+		}
+	}
+
+	records := [][]string{record(types.JobPipelineDetails, job.PipelineDetails)}
+	for i, run := range job.PipelineRuns {
+		records = append(records, record(fmt.Sprintf("%s.%d", types.JobPipelineRuns, i), run))
+	}
+	for i, stage := range job.PipelineStages {
+		records = append(records, record(fmt.Sprintf("%s.%d", types.JobPipelineStages, i), stage))
+	}
+	return records
+}
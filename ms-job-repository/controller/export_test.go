@@ -0,0 +1,180 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/pkg/translation"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepoController_Export(t *testing.T) {
+	first := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	first.Status = pkg.StatusComplete
+	first.LastUpdated = 1700000000000000000
+	first.InputFile.Attributes = map[string]string{"LabName": "TestLab", "Operator": "Jane"}
+	first.PipelineDetails.QCFlags = "None"
+	first.PipelineDetails.Results = "CellCount,10"
+	first.PipelineDetails.OutputFiles = []types.OutputFile{{DirName: "/tmp/files/output", Name: "out1.tiff"}, {DirName: "/tmp/files/output", Name: "out2.tiff"}}
+	first.PipelineRuns = []types.PipelineInfo{
+		{TaskId: "1", TaskVersion: 2, Status: pkg.TaskStatusComplete, Results: "CellCount,10",
+			OutputFiles: []types.OutputFile{{DirName: "/tmp/files/output", Name: "out1.tiff"}}},
+		{TaskId: "3", TaskVersion: 1, Status: pkg.TaskStatusComplete, QCFlags: "None",
+			OutputFiles: []types.OutputFile{{DirName: "/tmp/files/output", Name: "out2.tiff"}}},
+	}
+	second := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	second.Id = "2"
+	second.Status = pkg.StatusPipelineError
+	second.LastUpdated = 1700000001000000000
+	second.ErrorDetails = pkg.CreateUserFacingError(pkg.OwnerTaskLauncher, pkg.ErrJobInvalid)
+	second.PipelineStages = []types.PipelineInfo{{TaskId: "4", TaskVersion: 5, Status: pkg.TaskStatusComplete, Results: "Segmented"}}
+
+	firstPage := types.JobFilter{TaskId: "1", Limit: persist.MaxQueryLimit}
+	secondPage := firstPage
+	secondPage.Cursor = "next"
+
+	tests := []struct {
+		Name                string
+		Method              string
+		Query               string
+		FirstPageErr        error
+		SecondPageErr       error
+		ExpectedStatusCode  int
+		ExpectedContentType string
+		ExpectedIds         []string
+		ExpectedErrorMsg    string
+	}{
+		{"happy path - ndjson", http.MethodGet, "taskId=1", nil, nil, http.StatusOK, "application/x-ndjson", []string{first.Id, second.Id}, ""},
+		{"happy path - csv", http.MethodGet, "taskId=1&format=csv", nil, nil, http.StatusOK, "text/csv", []string{first.Id, second.Id}, ""},
+		{"happy path - limit", http.MethodGet, "taskId=1&limit=1", nil, nil, http.StatusOK, "application/x-ndjson", []string{first.Id}, ""},
+		{"second page error", http.MethodGet, "taskId=1", nil, errors.New("query failed"), http.StatusOK, "application/x-ndjson", []string{first.Id}, ""},
+		{"invalid format", http.MethodGet, "taskId=1&format=xml", nil, nil, http.StatusBadRequest, "", nil, "xml"},
+		{"invalid filter", http.MethodGet, "since=yesterday", nil, nil, http.StatusBadRequest, "", nil, "yesterday"},
+		{"first page error", http.MethodGet, "taskId=1", errors.New("query failed"), nil, http.StatusInternalServerError, "", nil, pkg.ErrRetrieving.Error()},
+		{"method not allowed", http.MethodPost, "", nil, nil, http.StatusMethodNotAllowed, "", nil, "POST"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+
+			persistMock.On("Query", firstPage).Return([]types.Job{first}, "next", test.FirstPageErr)
+			persistMock.On("Query", secondPage).Return([]types.Job{second}, "", test.SecondPageErr)
+
+			req := httptest.NewRequest(test.Method, "http://localhost?"+test.Query, nil)
+			w := httptest.NewRecorder()
+
+			jobRepoController.Export(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			if test.ExpectedStatusCode != http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			assert.Equal(t, test.ExpectedContentType, resp.Header.Get("Content-Type"))
+
+			var ids []string
+			if test.ExpectedContentType == "text/csv" {
+				records, err := csv.NewReader(resp.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 6)
+				assert.Equal(t, exportColumns, records[0])
+				assert.Equal(t, []string{first.Id, pkg.OwnerNone, pkg.StatusComplete, "2023-11-14T22:13:20Z", "0",
+					fileHostname, first.InputFile.DirName, first.InputFile.Name, "", "LabName=TestLab;Operator=Jane",
+					types.JobPipelineDetails, first.PipelineDetails.TaskId, "0", first.PipelineDetails.Status, "None", "CellCount,10",
+					"/tmp/files/output/out1.tiff;/tmp/files/output/out2.tiff", "", ""}, records[1])
+				assert.Equal(t, []string{"PipelineRuns.0", "1", "2", pkg.TaskStatusComplete, "", "CellCount,10",
+					"/tmp/files/output/out1.tiff"}, records[2][10:17])
+				assert.Equal(t, []string{"PipelineRuns.1", "3", "1", pkg.TaskStatusComplete, "None", "",
+					"/tmp/files/output/out2.tiff"}, records[3][10:17])
+				assert.Equal(t, []string{pkg.OwnerTaskLauncher, pkg.ErrJobInvalid.Error()}, records[4][len(exportColumns)-2:])
+				assert.Equal(t, second.Id, records[5][0])
+				assert.Equal(t, []string{"PipelineStages.0", "4", "5", pkg.TaskStatusComplete, "", "Segmented", ""}, records[5][10:17])
+				for _, record := range records[1:] {
+					if len(ids) == 0 || ids[len(ids)-1] != record[0] {
+						ids = append(ids, record[0])
+					}
+				}
+			} else {
+				scanner := bufio.NewScanner(resp.Body)
+				for scanner.Scan() {
+					var job types.Job
+					require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(scanner.Text())), &job))
+					ids = append(ids, job.Id)
+				}
+			}
+			assert.Equal(t, test.ExpectedIds, ids)
+		})
+	}
+}
+
+// flushRecorder records the body sent to the client at each flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.String())
+	r.ResponseRecorder.Flush()
+}
+
+func TestJobRepoController_ExportStreamsPages(t *testing.T) {
+	first := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	second := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	second.Id = "2"
+	firstPage := types.JobFilter{Limit: persist.MaxQueryLimit}
+	secondPage := firstPage
+	secondPage.Cursor = "next"
+
+	for _, format := range []string{exportFormatNDJSON, exportFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+			w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			persistMock.On("Query", firstPage).Return([]types.Job{first}, "next", nil)
+			persistMock.On("Query", secondPage).Run(func(mock.Arguments) {
+				require.Len(t, w.flushed, 1, "first page sent before the second page is queried")
+				assert.Contains(t, w.flushed[0], first.Id)
+			}).Return([]types.Job{second}, "", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost?format="+format, nil)
+			jobRepoController.Export(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			persistMock.AssertExpectations(t)
+			assert.Contains(t, w.Body.String(), second.Id)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/werrors"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// mergePatchContentType is the media type of an RFC 7396 JSON merge patch
+const mergePatchContentType = "application/merge-patch+json"
+
+// Patch is a request to apply an RFC 7396 JSON merge patch to the job.
+// Only the fields changed by the patch are updated and they are validated the same way as for Update.
+// The patch is applied to the current version of the job, so a concurrent update is reported as a conflict
+// unless the If-Match header is set, in which case the patch is only applied if the job version still matches the ETag.
+func (c *JobRepoController) Patch(writer http.ResponseWriter, request *http.Request) {
+	id, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	version, err := versionFromIfMatch(request)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf(pkg.ErrFmtInvalidInput, request.Header.Get("Content-Type"),
+			"Content-Type "+mergePatchContentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(request.Body)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, pkg.ErrFmtProcessingReq, request.URL.String()), http.StatusInternalServerError)
+		return
+	}
+
+	job, err := c.persist.GetById(id)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusNotFound)
+		return
+	}
+	if version != 0 && version != job.Version {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(pkg.ErrJobVersionMismatch, "failed to patch job for id (%s)", id),
+			http.StatusPreconditionFailed)
+		return
+	}
+
+	jobFields, err := persist.MergePatchFields(job, patch)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	if len(jobFields) > 0 {
+		job, err = c.persist.Update(id, jobFields, callerFromRequest(request), job.Version)
+		if err == pkg.ErrJobVersionMismatch && version == 0 {
+			// the job changed since it was patched without the caller asking for a specific version
+			err = pkg.ErrJobUpdateConflict
+		}
+		if err != nil {
+			helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "failed to patch job for id (%s)",
+				id), updateErrorStatus(err, http.StatusInternalServerError))
+			return
+		}
+	}
+
+	jsonRsp, err := json.Marshal(job)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrMarshallingJob), http.StatusInternalServerError)
+		return
+	}
+	setETag(writer, job)
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
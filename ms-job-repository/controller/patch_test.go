@@ -0,0 +1,104 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/pkg/translation"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepoController_Patch(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.Version = 3
+	patched := job
+	patched.Version = 4
+	patched.Status = pkg.StatusComplete
+	statusPatch := `{"Status": "Complete"}`
+	statusFields := map[string]interface{}{types.JobStatus: pkg.StatusComplete}
+
+	tests := []struct {
+		Name               string
+		Patch              string
+		ContentType        string
+		IfMatch            string
+		GetErr             error
+		ExpectedFields     map[string]interface{}
+		UpdateErr          error
+		ExpectedStatusCode int
+		ExpectedETag       string
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", statusPatch, mergePatchContentType, "", nil, statusFields, nil, http.StatusOK, `"4"`, ""},
+		{"happy path - json", statusPatch, "application/json; charset=utf-8", `"3"`, nil, statusFields, nil, http.StatusOK, `"4"`, ""},
+		{"happy path - nested", `{"PipelineDetails": {"Results": "count,5", "QCFlags": null}}`, mergePatchContentType, "", nil,
+			map[string]interface{}{types.JobPipelineResults: "count,5", types.JobPipelineQCFlags: nil}, nil, http.StatusOK, `"4"`, ""},
+		{"happy path - no change", `{"Owner": "` + pkg.OwnerTaskLauncher + `"}`, mergePatchContentType, "", nil, nil, nil, http.StatusOK, `"3"`, ""},
+		{"unsupported content type", statusPatch, "text/plain", "", nil, nil, nil, http.StatusUnsupportedMediaType, "", "text/plain"},
+		{"job not found", statusPatch, mergePatchContentType, "", errors.New("not found"), nil, nil, http.StatusNotFound, "", pkg.ErrRetrieving.Error()},
+		{"version mismatch", statusPatch, mergePatchContentType, `"2"`, nil, nil, nil, http.StatusPreconditionFailed, "", pkg.ErrJobVersionMismatch.Error()},
+		{"invalid patch", `["Status"]`, mergePatchContentType, "", nil, nil, nil, http.StatusBadRequest, "", "invalid merge patch"},
+		{"invalid fields", `{"Status": "Bogus"}`, mergePatchContentType, "", nil, map[string]interface{}{types.JobStatus: "Bogus"},
+			&persist.InvalidFieldsError{Fields: map[string]string{types.JobStatus: "bad status"}}, http.StatusBadRequest, "", "Status (bad status)"},
+		{"concurrent update", statusPatch, mergePatchContentType, "", nil, statusFields, pkg.ErrJobVersionMismatch, http.StatusConflict, "", pkg.ErrJobUpdateConflict.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+
+			persistMock.On("GetById", job.Id).Return(job, test.GetErr)
+			persistMock.On("Update", job.Id, mock.Anything, pkg.OwnerNone, job.Version).Return(patched, test.UpdateErr)
+
+			req := httptest.NewRequest(http.MethodPatch, "http://localhost", strings.NewReader(test.Patch))
+			req.Header.Set("Content-Type", test.ContentType)
+			req.Header.Set(pkg.HeaderCaller, pkg.OwnerNone)
+			if test.IfMatch != "" {
+				req.Header.Set(pkg.HeaderIfMatch, test.IfMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: job.Id})
+			w := httptest.NewRecorder()
+
+			jobRepoController.Patch(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedFields != nil {
+				persistMock.AssertCalled(t, "Update", job.Id, test.ExpectedFields, pkg.OwnerNone, job.Version)
+			} else {
+				persistMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			assert.Equal(t, test.ExpectedETag, resp.Header.Get(pkg.HeaderETag))
+		})
+	}
+}
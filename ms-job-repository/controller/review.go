@@ -0,0 +1,185 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetReviewQueue is a request to retrieve the completed jobs that are pending a manual review, oldest first.
+// The jobs can be narrowed down with the same query parameters as GetAll, except for the status and cursor,
+// and the limit caps the number of jobs returned.
+func (c *JobRepoController) GetReviewQueue(writer http.ResponseWriter, request *http.Request) {
+	accept := request.Header.Get(pkg.AcceptLanguage)
+	filter, err := parseJobFilter(request.URL.Query())
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := c.pendingReviews(filter)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusInternalServerError)
+		return
+	}
+
+	for k := range jobs {
+		err := jobs[k].Translate(c.bundle, accept)
+		if err != nil {
+			// Note: Soft error out on translation errors by logging issue with relevant job information.
+			c.lc.Errorf("job.Translate() failed with error %v for job %v", pkg.ErrTranslating, jobs[k])
+		}
+	}
+
+	jsonRsp, err := json.Marshal(jobs)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrMarshallingJob), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// Accept is a request to record the acceptance of the job results by a reviewer
+func (c *JobRepoController) Accept(writer http.ResponseWriter, request *http.Request) {
+	c.review(writer, request, pkg.VerificationAccepted)
+}
+
+// Reject is a request to record the rejection of the job results by a reviewer.
+// The input file of a rejected job is copied into the reject folder by the file sender gateway.
+func (c *JobRepoController) Reject(writer http.ResponseWriter, request *http.Request) {
+	c.review(writer, request, pkg.VerificationRejected)
+}
+
+// review records the verification of a completed job along with the reviewer, reason and time of the review.
+// The file sender gateway is asked to copy the input file into the reject folder when the job is rejected,
+// and to remove it again when a rejected job is accepted. If it fails the recorded review is still returned,
+// with the failure in the Warning header.
+// If the If-Match header is set the review is only recorded if the job version still matches the ETag.
+func (c *JobRepoController) review(writer http.ResponseWriter, request *http.Request, verification int) {
+	id, err := helpers.GetByKeyFromRequest(request, pkg.JobIdKey)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+	version, err := versionFromIfMatch(request)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, err, http.StatusBadRequest)
+		return
+	}
+
+	var review types.Review
+	err = json.NewDecoder(request.Body).Decode(&review)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, pkg.ErrFmtProcessingReq, request.URL.String()), http.StatusBadRequest)
+		return
+	}
+	review.Reviewer = strings.TrimSpace(review.Reviewer)
+	review.Reason = strings.TrimSpace(review.Reason)
+	if review.Reviewer == "" {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf(pkg.ErrFmtInvalidInput, "an empty Reviewer", "the name of the reviewer"), http.StatusBadRequest)
+		return
+	}
+	if verification == pkg.VerificationRejected && review.Reason == "" {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf(pkg.ErrFmtInvalidInput, "an empty Reason", "the reason for the rejection"), http.StatusBadRequest)
+		return
+	}
+
+	job, err := c.persist.GetById(id)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapErr(err, pkg.ErrRetrieving), http.StatusNotFound)
+		return
+	}
+	if job.Status != pkg.StatusComplete {
+		helpers.HandleErrorMessage(c.lc, writer, fmt.Errorf("job %s must be %s to be reviewed, got status %s", id, pkg.StatusComplete, job.Status), http.StatusConflict)
+		return
+	}
+
+	review.Timestamp = time.Now().UTC().UnixNano()
+	jobFields := map[string]interface{}{
+		types.JobVerification: verification,
+		types.JobReview:       review,
+	}
+	updated, err := c.persist.Update(id, jobFields, callerFromRequest(request), version)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer, werrors.WrapMsgf(err, "failed to update job verification for id (%s)",
+			id), updateErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	c.lc.Debugf("job %s verification set to %d by %s", id, verification, review.Reviewer)
+
+	if c.FileSenderClient != nil {
+		if verification == pkg.VerificationRejected {
+			err = c.FileSenderClient.RejectFile(id)
+		} else if job.Verification == pkg.VerificationRejected {
+			err = c.FileSenderClient.UnrejectFile(id)
+		}
+		if err != nil {
+			// the verification is already recorded, so the reviewed job is still returned along with a warning,
+			// and the review can be repeated to retry the reject folder update
+			err = werrors.WrapMsgf(err, "failed to update the reject folder for job %s", id)
+			c.lc.Warn(err.Error())
+			writer.Header().Set(pkg.HeaderWarning, fmt.Sprintf("199 - %q", err.Error()))
+		}
+	}
+
+	jsonRsp, err := json.Marshal(updated)
+	if err != nil {
+		helpers.HandleErrorMessage(c.lc, writer,
+			werrors.WrapErr(err, pkg.ErrMarshallingJob), http.StatusInternalServerError)
+		return
+	}
+	setETag(writer, updated)
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(jsonRsp)
+	if err != nil {
+		c.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+	}
+}
+
+// pendingReviews pages through the completed jobs matching the filter and returns those pending a review,
+// up to the filter limit when it is set
+func (c *JobRepoController) pendingReviews(filter types.JobFilter) ([]types.Job, error) {
+	limit := filter.Limit
+	filter.Status = pkg.StatusComplete
+	filter.Limit = persist.MaxQueryLimit
+	filter.Cursor = ""
+
+	jobs := []types.Job{}
+	for {
+		page, cursor, err := c.persist.Query(filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range page {
+			if job.Verification != pkg.VerificationPending {
+				continue
+			}
+			jobs = append(jobs, job)
+			if limit > 0 && len(jobs) == limit {
+				return jobs, nil
+			}
+		}
+		if cursor == "" {
+			return jobs, nil
+		}
+		filter.Cursor = cursor
+	}
+}
@@ -0,0 +1,189 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package controller
+
+import (
+	"aicsd/pkg/translation"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	fileSenderMocks "aicsd/ms-job-repository/clients/file_sender/mocks"
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepoController_GetReviewQueue(t *testing.T) {
+	pending := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	pending.Status = pkg.StatusComplete
+	accepted := pending
+	accepted.Id = "2"
+	accepted.Verification = pkg.VerificationAccepted
+	otherPending := pending
+	otherPending.Id = "3"
+
+	firstPage := types.JobFilter{Status: pkg.StatusComplete, TaskId: "1", Limit: persist.MaxQueryLimit}
+	secondPage := firstPage
+	secondPage.Cursor = "next"
+
+	tests := []struct {
+		Name               string
+		Query              string
+		PersistMockErr     error
+		Expected           []types.Job
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path", "taskId=1", nil, []types.Job{pending, otherPending}, http.StatusOK, ""},
+		{"happy path - limit", "taskId=1&limit=1", nil, []types.Job{pending}, http.StatusOK, ""},
+		{"invalid limit", "limit=0", nil, nil, http.StatusBadRequest, "limit"},
+		{"error retrieving", "taskId=1", errors.New("query failed"), nil, http.StatusInternalServerError, pkg.ErrRetrieving.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+
+			persistMock.On("Query", firstPage).Return([]types.Job{pending, accepted}, "next", test.PersistMockErr)
+			persistMock.On("Query", secondPage).Return([]types.Job{otherPending}, "", nil)
+
+			req := httptest.NewRequest("GET", "http://localhost?"+test.Query, nil)
+			w := httptest.NewRecorder()
+
+			jobRepoController.GetReviewQueue(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var actual []types.Job
+			err = json.Unmarshal(body, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestJobRepoController_Review(t *testing.T) {
+	complete := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	complete.Status = pkg.StatusComplete
+	complete.Version = 4
+	rejected := complete
+	rejected.Verification = pkg.VerificationRejected
+	incomplete := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	incomplete.Status = pkg.StatusIncomplete
+	review := types.Review{Reviewer: "QA Operator", Reason: "missed a colony"}
+
+	tests := []struct {
+		Name               string
+		Verification       int
+		Review             *types.Review
+		Job                types.Job
+		IfMatch            string
+		Version            int64
+		UpdateErr          error
+		FileSenderCall     string
+		FileSenderErr      error
+		ExpectedStatusCode int
+		ExpectedErrorMsg   string
+	}{
+		{"happy path - accept", pkg.VerificationAccepted, &types.Review{Reviewer: "QA Operator"}, complete, "", 0, nil, "", nil, http.StatusOK, ""},
+		{"happy path - reject", pkg.VerificationRejected, &review, complete, `"4"`, 4, nil, "RejectFile", nil, http.StatusOK, ""},
+		{"happy path - accept rejected", pkg.VerificationAccepted, &review, rejected, "", 0, nil, "UnrejectFile", nil, http.StatusOK, ""},
+		{"invalid body", pkg.VerificationAccepted, nil, complete, "", 0, nil, "", nil, http.StatusBadRequest, "failed to process request"},
+		{"missing reviewer", pkg.VerificationAccepted, &types.Review{Reason: "looks good"}, complete, "", 0, nil, "", nil, http.StatusBadRequest, "Reviewer"},
+		{"missing reject reason", pkg.VerificationRejected, &types.Review{Reviewer: "QA Operator"}, complete, "", 0, nil, "", nil, http.StatusBadRequest, "Reason"},
+		{"job not complete", pkg.VerificationAccepted, &review, incomplete, "", 0, nil, "", nil, http.StatusConflict, "must be Complete"},
+		{"version mismatch", pkg.VerificationRejected, &review, complete, `"3"`, 3, pkg.ErrJobVersionMismatch, "", nil, http.StatusPreconditionFailed, pkg.ErrJobVersionMismatch.Error()},
+		{"reject file error", pkg.VerificationRejected, &review, complete, "", 0, nil, "RejectFile", errors.New("gateway down"), http.StatusOK, "failed to update the reject folder"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			fileSenderMock := fileSenderMocks.Client{}
+			testLocalizationBundle, err := translation.NewBundler(localizationFiles)
+			require.NoError(t, err)
+			jobRepoController := New(logger.MockLogger{}, &persistMock, testLocalizationBundle)
+			jobRepoController.FileSenderClient = &fileSenderMock
+
+			updated := test.Job
+			updated.Version = test.Job.Version + 1
+			updated.Verification = test.Verification
+			persistMock.On("GetById", test.Job.Id).Return(test.Job, nil)
+			persistMock.On("Update", test.Job.Id, mock.MatchedBy(func(jobFields map[string]interface{}) bool {
+				recorded, ok := jobFields[types.JobReview].(types.Review)
+				return jobFields[types.JobVerification] == test.Verification && ok &&
+					recorded.Reviewer == test.Review.Reviewer && recorded.Reason == test.Review.Reason && recorded.Timestamp > 0
+			}), pkg.OwnerNone, test.Version).Return(updated, test.UpdateErr)
+			if test.FileSenderCall != "" {
+				fileSenderMock.On(test.FileSenderCall, test.Job.Id).Return(test.FileSenderErr)
+			}
+
+			var requestBody []byte
+			if test.Review != nil {
+				requestBody, _ = json.Marshal(test.Review)
+			}
+			req := httptest.NewRequest("POST", "http://localhost", bytes.NewReader(requestBody))
+			req.Header.Set(pkg.HeaderCaller, pkg.OwnerNone)
+			if test.IfMatch != "" {
+				req.Header.Set(pkg.HeaderIfMatch, test.IfMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{pkg.JobIdKey: test.Job.Id})
+			w := httptest.NewRecorder()
+
+			if test.Verification == pkg.VerificationRejected {
+				jobRepoController.Reject(w, req)
+			} else {
+				jobRepoController.Accept(w, req)
+			}
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			require.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			fileSenderMock.AssertExpectations(t)
+			if test.ExpectedStatusCode != http.StatusOK {
+				require.Contains(t, string(body), test.ExpectedErrorMsg)
+				return
+			}
+			var actual types.Job
+			err = json.Unmarshal(body, &actual)
+			require.NoError(t, err)
+			assert.Equal(t, test.Verification, actual.Verification)
+			assert.Equal(t, `"5"`, resp.Header.Get(pkg.HeaderETag))
+			if test.ExpectedErrorMsg != "" {
+				assert.Contains(t, resp.Header.Get(pkg.HeaderWarning), test.ExpectedErrorMsg)
+			} else {
+				assert.Empty(t, resp.Header.Get(pkg.HeaderWarning))
+			}
+			persistMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package events
+
+import (
+	"sync"
+
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+)
+
+// Job event types, used as the event name of the Server-Sent Events
+const (
+	TypeCreated = "created"
+	TypeUpdated = "updated"
+	TypeDeleted = "deleted"
+)
+
+// subscriberBuffer is the number of events queued for a subscriber before it is considered too slow and dropped
+const subscriberBuffer = 64
+
+// Event is a change made to a job. For deleted jobs the Job is the job as it was before the delete.
+type Event struct {
+	Type string
+	Job  types.Job
+}
+
+// Subscription receives the events matching its filter until it is unsubscribed or dropped
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	filter types.JobFilter
+}
+
+// Broker fans out the job events to the subscribers
+type Broker struct {
+	lc          logger.LoggingClient
+	mutex       sync.Mutex
+	subscribers map[*Subscription]bool
+}
+
+// NewBroker creates a Broker with no subscribers
+func NewBroker(lc logger.LoggingClient) *Broker {
+	return &Broker{
+		lc:          lc,
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// Subscribe returns a subscription to the events for the jobs matching the filter.
+// Only the Status, Owner and TaskId of the filter are applied.
+func (b *Broker) Subscribe(filter types.JobFilter) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	subscription := &Subscription{
+		Events: events,
+		events: events,
+		filter: types.JobFilter{Status: filter.Status, Owner: filter.Owner, TaskId: filter.TaskId},
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers[subscription] = true
+	return subscription
+}
+
+// Unsubscribe stops the events to the subscription and closes its Events channel
+func (b *Broker) Unsubscribe(subscription *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.remove(subscription)
+}
+
+// Publish sends the event to the subscribers whose filter matches the job.
+// A subscriber that is not keeping up is dropped, closing its Events channel, rather than blocking the publisher.
+func (b *Broker) Publish(event Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for subscription := range b.subscribers {
+		if !subscription.filter.Matches(event.Job) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			b.lc.Warnf("dropping job event subscriber that has %d undelivered events", subscriberBuffer)
+			b.remove(subscription)
+		}
+	}
+}
+
+// remove deletes the subscription and closes its channel, the mutex must be held
+func (b *Broker) remove(subscription *Subscription) {
+	if b.subscribers[subscription] {
+		delete(b.subscribers, subscription)
+		close(subscription.events)
+	}
+}
@@ -0,0 +1,255 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/translation"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fileHostname = "gateway"
+
+var localizationFiles = []string{"../../pkg/translation/dictionary/en.json", "../../pkg/translation/dictionary/zh.json"}
+
+func TestBroker_Publish(t *testing.T) {
+	dataOrgJob := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
+	taskLauncherJob := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	taskLauncherJob.Id = "2"
+
+	broker := NewBroker(logger.MockLogger{})
+	all := broker.Subscribe(types.JobFilter{})
+	taskLauncher := broker.Subscribe(types.JobFilter{Owner: pkg.OwnerTaskLauncher})
+	broker.Publish(Event{Type: TypeCreated, Job: dataOrgJob})
+	broker.Publish(Event{Type: TypeUpdated, Job: taskLauncherJob})
+
+	assert.Equal(t, Event{Type: TypeCreated, Job: dataOrgJob}, <-all.Events)
+	assert.Equal(t, Event{Type: TypeUpdated, Job: taskLauncherJob}, <-all.Events)
+	assert.Equal(t, Event{Type: TypeUpdated, Job: taskLauncherJob}, <-taskLauncher.Events)
+	assert.Empty(t, taskLauncher.Events)
+
+	broker.Unsubscribe(taskLauncher)
+	_, ok := <-taskLauncher.Events
+	assert.False(t, ok)
+	// unsubscribing twice is a no-op
+	broker.Unsubscribe(taskLauncher)
+
+	// a subscriber that does not keep up is dropped
+	for i := 0; i <= subscriberBuffer; i++ {
+		broker.Publish(Event{Type: TypeUpdated, Job: dataOrgJob})
+	}
+	received := 0
+	for range all.Events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestPublishingPersistence(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
+	jobFields := map[string]interface{}{types.JobStatus: pkg.StatusComplete}
+	tests := []struct {
+		Name          string
+		Call          func(persistence persist.Persistence) error
+		ExpectedEvent *Event
+	}{
+		{"create", func(persistence persist.Persistence) error {
+			_, _, err := persistence.Create(job)
+			return err
+		}, &Event{Type: TypeCreated, Job: job}},
+		{"create existing", func(persistence persist.Persistence) error {
+			_, _, err := persistence.Create(types.Job{Id: "existing"})
+			return err
+		}, nil},
+		{"update", func(persistence persist.Persistence) error {
+			_, err := persistence.Update(job.Id, jobFields, pkg.OwnerDataOrg, 0)
+			return err
+		}, &Event{Type: TypeUpdated, Job: job}},
+		{"update error", func(persistence persist.Persistence) error {
+			_, err := persistence.Update("unknown", jobFields, pkg.OwnerDataOrg, 0)
+			return err
+		}, nil},
+		{"delete", func(persistence persist.Persistence) error {
+			return persistence.Delete(job.Id)
+		}, &Event{Type: TypeDeleted, Job: job}},
+		{"delete error", func(persistence persist.Persistence) error {
+			return persistence.Delete("unknown")
+		}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("Create", job).Return(persist.StatusCreated, job, nil)
+			persistMock.On("Create", types.Job{Id: "existing"}).Return(persist.StatusExists, job, nil)
+			persistMock.On("Update", job.Id, jobFields, pkg.OwnerDataOrg, int64(0)).Return(job, nil)
+			persistMock.On("Update", "unknown", jobFields, pkg.OwnerDataOrg, int64(0)).Return(types.Job{}, errors.New("not found"))
+			persistMock.On("GetById", job.Id).Return(job, nil)
+			persistMock.On("GetById", "unknown").Return(types.Job{}, errors.New("not found"))
+			persistMock.On("Delete", job.Id).Return(nil)
+			persistMock.On("Delete", "unknown").Return(errors.New("not found"))
+
+			broker := NewBroker(logger.MockLogger{})
+			subscription := broker.Subscribe(types.JobFilter{})
+			err := test.Call(NewPublishingPersistence(&persistMock, broker))
+			if test.ExpectedEvent == nil {
+				assert.Empty(t, subscription.Events)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, subscription.Events, 1)
+			assert.Equal(t, *test.ExpectedEvent, <-subscription.Events)
+		})
+	}
+}
+
+func TestStream_ServeHTTP(t *testing.T) {
+	bundle, err := translation.NewBundler(localizationFiles)
+	require.NoError(t, err)
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	job.Status = pkg.StatusComplete
+	otherJob := helpers.CreateTestJob(pkg.OwnerDataOrg, fileHostname)
+	otherJob.Id = "2"
+
+	tests := []struct {
+		Name          string
+		Query         string
+		Language      string
+		ExpectedOwner string
+	}{
+		{"happy path", "", "", pkg.OwnerTaskLauncher},
+		{"filtered", "?" + pkg.QueryOwnerKey + "=" + pkg.OwnerTaskLauncher + "&" + pkg.QueryStatusKey + "=" + pkg.StatusComplete, "", pkg.OwnerTaskLauncher},
+		{"translated", "", "zh", "t任务启动器"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			broker := NewBroker(logger.MockLogger{})
+			server := httptest.NewServer(NewStream(logger.MockLogger{}, broker, bundle))
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+test.Query, nil)
+			require.NoError(t, err)
+			request.Header.Set("Accept-Language", test.Language)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+			// the subscription is made before the headers are sent
+			if test.Query != "" {
+				broker.Publish(Event{Type: TypeUpdated, Job: otherJob})
+			}
+			broker.Publish(Event{Type: TypeUpdated, Job: job})
+
+			reader := bufio.NewReader(response.Body)
+			eventLine, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, "event: "+TypeUpdated+"\n", eventLine)
+			dataLine, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(dataLine, "data: "))
+			var received types.Job
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &received))
+			assert.Equal(t, job.Id, received.Id)
+			assert.Equal(t, test.ExpectedOwner, received.Owner)
+			// the published job is not modified by the translation
+			assert.Equal(t, pkg.OwnerTaskLauncher, job.Owner)
+		})
+	}
+}
+
+func TestStream_ServeHTTPKeepAlive(t *testing.T) {
+	broker := NewBroker(logger.MockLogger{})
+	stream := NewStream(logger.MockLogger{}, broker, nil)
+	stream.keepAliveInterval = time.Millisecond
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	line, err := bufio.NewReader(response.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": keep-alive\n", line)
+
+	response, err = http.Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestNewServer(t *testing.T) {
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		Name                string
+		Token               string
+		AllowedOrigin       string
+		Method              string
+		Authorization       string
+		ExpectedStatusCode  int
+		ExpectedAllowOrigin string
+	}{
+		{"token", "secret", "", http.MethodGet, "Bearer secret", http.StatusOK, ""},
+		{"missing token", "secret", "", http.MethodGet, "", http.StatusUnauthorized, ""},
+		{"invalid token", "secret", "", http.MethodGet, "Bearer guess", http.StatusUnauthorized, ""},
+		{"allowed origin", "secret", "http://localhost:4200", http.MethodGet, "Bearer secret", http.StatusOK, "http://localhost:4200"},
+		{"preflight", "secret", "http://localhost:4200", http.MethodOptions, "", http.StatusNoContent, "http://localhost:4200"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			server, err := NewServer(logger.MockLogger{}, "localhost", "59794", ok, test.Token, test.AllowedOrigin)
+			require.NoError(t, err)
+			assert.Equal(t, "localhost:59794", server.Addr)
+
+			req := httptest.NewRequest(test.Method, "http://localhost"+pkg.EndpointJobEvents, nil)
+			if test.Authorization != "" {
+				req.Header.Set("Authorization", test.Authorization)
+			}
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.ExpectedStatusCode, resp.StatusCode, "invalid status code")
+			assert.Equal(t, test.ExpectedAllowOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestNewServerNoToken(t *testing.T) {
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	server, err := NewServer(logger.MockLogger{}, "localhost", "59794", ok, "", "http://localhost:4200")
+	require.ErrorIs(t, err, ErrNoToken)
+	assert.Nil(t, server)
+}
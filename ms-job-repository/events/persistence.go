@@ -0,0 +1,53 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package events
+
+import (
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg/types"
+)
+
+// publishingPersistence publishes an event to the broker for every job successfully created, updated or deleted
+// through the wrapped persistence, so the events cover the controller as well as the retention sweeper
+type publishingPersistence struct {
+	persist.Persistence
+	broker *Broker
+}
+
+// NewPublishingPersistence wraps the persistence to publish the job changes to the broker
+func NewPublishingPersistence(persistence persist.Persistence, broker *Broker) persist.Persistence {
+	return publishingPersistence{Persistence: persistence, broker: broker}
+}
+
+func (p publishingPersistence) Create(job types.Job) (string, types.Job, error) {
+	status, created, err := p.Persistence.Create(job)
+	if err == nil && status == persist.StatusCreated {
+		p.broker.Publish(Event{Type: TypeCreated, Job: created})
+	}
+	return status, created, err
+}
+
+func (p publishingPersistence) Update(id string, values map[string]interface{}, caller string, version int64) (types.Job, error) {
+	updated, err := p.Persistence.Update(id, values, caller, version)
+	if err == nil {
+		p.broker.Publish(Event{Type: TypeUpdated, Job: updated})
+	}
+	return updated, err
+}
+
+func (p publishingPersistence) Delete(id string) error {
+	// get the job first so subscribers filtering on the job fields receive the delete
+	job, getErr := p.Persistence.GetById(id)
+	err := p.Persistence.Delete(id)
+	if err != nil {
+		return err
+	}
+	if getErr != nil {
+		job = types.Job{Id: id}
+	}
+	p.broker.Publish(Event{Type: TypeDeleted, Job: job})
+	return nil
+}
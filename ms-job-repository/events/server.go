@@ -0,0 +1,65 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package events
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+
+	"aicsd/pkg/helpers"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+)
+
+const (
+	// SecretPath is the path of the secret holding the token required on the events port
+	SecretPath = "events"
+	// SecretTokenKey is the key of the token in the events secret
+	SecretTokenKey = "token"
+)
+
+var (
+	// ErrUnauthorized is returned when a request on the events port does not carry the events token
+	ErrUnauthorized = errors.New("missing or invalid bearer token")
+	// ErrNoToken is returned when the events port is started without a token
+	ErrNoToken = errors.New("the token of the events secret is empty")
+)
+
+// NewServer creates the server of the events port listening on the host and port. The events port is not behind the
+// API gateway of the service port, so the requests must carry the token as a bearer token and the server is not
+// created without one. The allowed origin is sent to the browsers when one is set, other origins are not allowed
+// to read the responses.
+func NewServer(lc logger.LoggingClient, host string, port string, handler http.Handler, token string, allowedOrigin string) (*http.Server, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	return &http.Server{
+		Addr:    net.JoinHostPort(host, port),
+		Handler: protect(lc, handler, token, allowedOrigin),
+	}, nil
+}
+
+// protect checks the bearer token of the requests and answers the CORS preflight requests before the handler is called
+func protect(lc logger.LoggingClient, handler http.Handler, token string, allowedOrigin string) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if allowedOrigin != "" {
+			writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			if request.Method == http.MethodOptions {
+				writer.Header().Set("Access-Control-Allow-Methods", http.MethodGet)
+				writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Accept, Accept-Language, Last-Event-ID")
+				writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(request.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			helpers.HandleErrorMessage(lc, writer, ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
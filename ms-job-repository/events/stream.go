@@ -0,0 +1,123 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// keepAliveInterval is how often a comment is sent on an idle stream so proxies do not close the connection
+const keepAliveInterval = 30 * time.Second
+
+// Stream serves the job events as Server-Sent Events. Each event is named after the event type
+// and its data is the json job, translated according to the Accept-Language header.
+// The stream is served outside of the app service routes as those time out after the service RequestTimeout.
+type Stream struct {
+	lc                logger.LoggingClient
+	broker            *Broker
+	bundle            *i18n.Bundle
+	keepAliveInterval time.Duration
+}
+
+// NewStream creates the http handler streaming the events published to the broker
+func NewStream(lc logger.LoggingClient, broker *Broker, bundle *i18n.Bundle) *Stream {
+	return &Stream{
+		lc:                lc,
+		broker:            broker,
+		bundle:            bundle,
+		keepAliveInterval: keepAliveInterval,
+	}
+}
+
+// ServeHTTP streams the events for the jobs matching the owner, status and taskId query parameters
+// until the client disconnects
+func (s *Stream) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		helpers.HandleErrorMessage(s.lc, writer, fmt.Errorf("method %s not allowed", request.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		helpers.HandleErrorMessage(s.lc, writer, errors.New("streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+	query := request.URL.Query()
+	filter := types.JobFilter{
+		Status: query.Get(pkg.QueryStatusKey),
+		Owner:  query.Get(pkg.QueryOwnerKey),
+		TaskId: query.Get(pkg.QueryTaskIdKey),
+	}
+	accept := request.Header.Get("Accept-Language")
+
+	subscription := s.broker.Subscribe(filter)
+	defer s.broker.Unsubscribe(subscription)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// the subscriber was dropped, the client reconnects and reloads the jobs
+				return
+			}
+			err = s.writeEvent(writer, event, accept)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(writer, ": keep-alive\n\n")
+		}
+		if err != nil {
+			s.lc.Errorf(werrors.WrapErr(err, pkg.ErrWritingHttpResp).Error())
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event with its job translated to the accept language
+func (s *Stream) writeEvent(writer http.ResponseWriter, event Event, accept string) error {
+	// the job is shared with the other subscribers so translate a copy of it
+	data, err := json.Marshal(event.Job)
+	if err != nil {
+		return werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+	var job types.Job
+	err = json.Unmarshal(data, &job)
+	if err != nil {
+		return werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+	err = job.Translate(s.bundle, accept)
+	if err != nil {
+		// Note: Soft error out on translation errors by logging issue with relevant job information.
+		s.lc.Errorf("job.Translate() failed with error %v for job %v", pkg.ErrTranslating, job)
+	}
+	data, err = json.Marshal(job)
+	if err != nil {
+		return werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
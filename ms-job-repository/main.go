@@ -8,17 +8,25 @@ package main
 import (
 	"aicsd/pkg/translation"
 	"aicsd/pkg/wait"
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"sync"
 
+	"aicsd/ms-job-repository/clients/file_sender"
 	"aicsd/ms-job-repository/config"
 	"aicsd/ms-job-repository/controller"
+	"aicsd/ms-job-repository/events"
 	"aicsd/ms-job-repository/persist"
+	"aicsd/ms-job-repository/retention"
 	"aicsd/pkg"
 	"aicsd/pkg/clients/redis"
 	"aicsd/pkg/werrors"
 
 	appsdk "github.com/edgexfoundry/app-functions-sdk-go/v2/pkg"
+	"github.com/edgexfoundry/app-functions-sdk-go/v2/pkg/interfaces"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
 func main() {
@@ -39,20 +47,41 @@ func main() {
 		os.Exit(-1)
 	}
 
-	secrets, err := service.GetSecret(pkg.DatabasePath, "username", "password")
-	if err != nil {
-		lc.Errorf("failed to GetSecret for database %s: %s", pkg.DatabasePath, err.Error())
-		os.Exit(-1)
-	}
+	var persistence persist.Persistence
+	if configuration.PersistenceType == pkg.PersistenceTypeBolt {
+		persistence, err = persist.NewBoltDB(lc, configuration.PersistenceFile)
+		if err != nil {
+			lc.Errorf("failed to open bolt db: %s", err.Error())
+			os.Exit(-1)
+		}
+	} else {
+		secrets, err := service.GetSecret(pkg.DatabasePath, "username", "password")
+		if err != nil {
+			lc.Errorf("failed to GetSecret for database %s: %s", pkg.DatabasePath, err.Error())
+			os.Exit(-1)
+		}
 
-	redisClient := redis.NewClient(configuration.RedisHost, configuration.RedisPort, service.RequestTimeout(), secrets)
-	persistence, err := persist.NewRedisDB(lc, redisClient)
-	if err != nil {
-		lc.Errorf("failed to connect to redis db: %s", err.Error())
-		os.Exit(-1)
+		redisClient := redis.NewClient(configuration.RedisHost, configuration.RedisPort, service.RequestTimeout(), secrets)
+		persistence, err = persist.NewRedisDB(lc, redisClient)
+		if err != nil {
+			lc.Errorf("failed to connect to redis db: %s", err.Error())
+			os.Exit(-1)
+		}
 	}
 
+	// publish the job changes made through the persistence to the event stream subscribers
+	broker := events.NewBroker(lc)
+	persistence = events.NewPublishingPersistence(persistence, broker)
+
 	dataRepoController := controller.New(lc, persistence, bundle)
+	dataRepoController.StatsWindow = configuration.StatsWindow
+	if configuration.FileSenderBaseUrl != "" {
+		dataRepoController.FileSenderClient = file_sender.NewClient(configuration.FileSenderBaseUrl, service.RequestTimeout(), nil)
+	}
+	if configuration.PersistenceType == pkg.PersistenceTypeBolt {
+		// the embedded database does not need the redis service
+		dataRepoController.DependentServices = dataRepoController.DependentServices.Without(wait.ServiceRedis)
+	}
 
 	err = dataRepoController.RegisterRoutes(service)
 	if err != nil {
@@ -65,12 +94,46 @@ func main() {
 		os.Exit(-1)
 	}
 
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	if configuration.RetentionInterval > 0 && len(configuration.RetentionAges) > 0 {
+		sweeper, err := retention.New(lc, persistence, configuration.RetentionAges,
+			configuration.RetentionExportFolder, configuration.RetentionRemoveFiles)
+		if err != nil {
+			lc.Errorf("failed to create retention sweeper: %s", err.Error())
+			os.Exit(-1)
+		}
+		wg.Add(1)
+		go sweeper.Run(ctx, wg, configuration.RetentionInterval)
+	}
+
+	var eventsServer *http.Server
+	if configuration.EventsPort != "" {
+		eventsServer, err = newEventsServer(service, configuration, broker, bundle, dataRepoController)
+		if err != nil {
+			lc.Errorf("the events port is not started: %s", err.Error())
+		} else {
+			go func() {
+				err := eventsServer.ListenAndServe()
+				if err != nil && err != http.ErrServerClosed {
+					lc.Errorf("job streaming server failed: %s", err.Error())
+				}
+			}()
+		}
+	}
+
 	err = service.MakeItRun()
 	if err != nil {
 		lc.Errorf(werrors.WrapErr(err, pkg.ErrRunningService).Error())
 		os.Exit(-1)
 	}
 
+	cancelFunc()
+	wg.Wait()
+	if eventsServer != nil {
+		_ = eventsServer.Close()
+	}
+
 	// Do any required cleanup here
 	err = persistence.Disconnect()
 	if err != nil {
@@ -78,3 +141,20 @@ func main() {
 	}
 	os.Exit(0)
 }
+
+// newEventsServer creates the server of the event stream and the export. They are served on their own port as the
+// responses of the app service routes are buffered and time out after the RequestTimeout. That port is not behind the
+// API gateway, so the requests must carry the token of the events secret.
+func newEventsServer(service interfaces.ApplicationService, configuration *config.Configuration, broker *events.Broker,
+	bundle *i18n.Bundle, dataRepoController *controller.JobRepoController) (*http.Server, error) {
+	secrets, err := service.GetSecret(events.SecretPath, events.SecretTokenKey)
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, "failed to GetSecret for events %s", events.SecretPath)
+	}
+	lc := service.LoggingClient()
+	router := http.NewServeMux()
+	router.Handle(pkg.EndpointJobEvents, events.NewStream(lc, broker, bundle))
+	router.HandleFunc(pkg.EndpointJobExport, dataRepoController.Export)
+	return events.NewServer(lc, configuration.EventsHost, configuration.EventsPort, router,
+		secrets[events.SecretTokenKey], configuration.EventsAllowedOrigin)
+}
@@ -0,0 +1,384 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout is how long to wait for the file lock held by another process using the database file
+const boltOpenTimeout = 5 * time.Second
+
+var (
+	bucketJob         = []byte("job")
+	bucketInputFile   = []byte("input_file")
+	bucketLastUpdated = []byte("last_updated")
+	bucketHistory     = []byte("history")
+)
+
+// BoltDB is a Persistence that stores the jobs in an embedded bbolt database file,
+// for deployments that do not run a Redis server.
+type BoltDB struct {
+	lc logger.LoggingClient
+	db *bolt.DB
+}
+
+// NewBoltDB returns a persistence interface that uses a bbolt database stored in the given file.
+// The file is created if it does not exist.
+func NewBoltDB(lc logger.LoggingClient, path string) (Persistence, error) {
+	db, err := bolt.Open(path, pkg.FilePermissions, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, "failed to open bolt db %s", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bucketJob, bucketInputFile, bucketLastUpdated, bucketHistory} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, werrors.WrapMsgf(err, "failed to create buckets in bolt db %s", path)
+	}
+	return BoltDB{lc: lc, db: db}, nil
+}
+
+// Create returns a status (StatusExists or StatusCreated), the job, and an error (if one occurred)
+// It stores the job in the job bucket under {job.Id}, the {job.Id} in the input_file bucket under
+// hostname:dirname:filename and the job position in the last_updated bucket used by Query.
+// As for the RedisDB, the input file entry always references the originating system.
+func (bdb BoltDB) Create(job types.Job) (string, types.Job, error) {
+	// validate parameters
+	if job.Id != "" {
+		return StatusNone, types.Job{}, errors.New("id already specified")
+	}
+
+	isValid, _ := helpers.ApplyJsonLogicToJob(job, JobValidator)
+	if !isValid {
+		return StatusNone, types.Job{}, pkg.ErrJobInvalid
+	}
+
+	status := StatusCreated
+	err := bdb.db.Update(func(tx *bolt.Tx) error {
+		inputFileKey := []byte(strings.Join([]string{job.InputFile.Hostname, job.InputFile.DirName, job.InputFile.Name}, ":"))
+		if existingId := tx.Bucket(bucketInputFile).Get(inputFileKey); existingId != nil {
+			existingJob, err := getBoltJob(tx, string(existingId))
+			if err != nil {
+				return err
+			}
+			status = StatusExists
+			job = existingJob
+			return nil
+		}
+
+		job.Id = uuid.NewString()
+		job.Version = 1
+		job.SetLastUpdated()
+		job.Transitions = nil
+		job.RecordTransition()
+		job.ErrorDetails = pkg.CreateUserFacingError("", nil)
+		err := putBoltJob(tx, nil, &job)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketInputFile).Put(inputFileKey, []byte(job.Id))
+	})
+	if err != nil {
+		return StatusNone, types.Job{}, werrors.WrapErr(err, pkg.ErrJobCreation)
+	}
+	return status, job, nil
+}
+
+// Update retrieves the job id and modifies the entry using the given values and records the
+// changed fields in the job history. When version is not 0 the update is only applied if the job
+// is still at that version, otherwise pkg.ErrJobVersionMismatch is returned.
+// bbolt serializes write transactions so concurrent updates never conflict.
+func (bdb BoltDB) Update(id string, jobFields map[string]interface{}, caller string, version int64) (types.Job, error) {
+	// check id exists - not empty
+	if id == "" {
+		return types.Job{}, pkg.ErrJobIdEmpty
+	}
+	if len(jobFields) == 0 {
+		return types.Job{}, errors.New("no job fields provided to update")
+	}
+	err := ValidateJobFields(jobFields)
+	if err != nil {
+		return types.Job{}, err
+	}
+
+	var updateJob types.Job
+	err = bdb.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketJob).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf(pkg.ErrJobIdNotFound, id)
+		}
+		update, err := applyJobFields(data, jobFields, caller, version)
+		if err != nil {
+			return err
+		}
+		updateJob = update.newJob
+		err = putBoltJob(tx, &update.oldJob, &updateJob)
+		if err != nil {
+			return werrors.WrapErr(err, pkg.ErrUpdating)
+		}
+		history, err := tx.Bucket(bucketHistory).CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return werrors.WrapErr(err, pkg.ErrUpdating)
+		}
+		sequence, err := history.NextSequence()
+		if err != nil {
+			return werrors.WrapErr(err, pkg.ErrUpdating)
+		}
+		err = history.Put(uint64Key(sequence), update.history)
+		if err != nil {
+			return werrors.WrapErr(err, pkg.ErrUpdating)
+		}
+		return nil
+	})
+	if err != nil {
+		return types.Job{}, err
+	}
+	return updateJob, nil
+}
+
+// Delete deletes all entries associated with the given id
+func (bdb BoltDB) Delete(id string) error {
+	if id == "" {
+		return pkg.ErrJobIdEmpty
+	}
+	return bdb.db.Update(func(tx *bolt.Tx) error {
+		job, err := getBoltJob(tx, id)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(bucketJob).Delete([]byte(id))
+		if err != nil {
+			return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
+		}
+		err = tx.Bucket(bucketLastUpdated).Delete(lastUpdatedKey(job))
+		if err != nil {
+			return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
+		}
+		inputFiles := tx.Bucket(bucketInputFile)
+		err = inputFiles.ForEach(func(key, value []byte) error {
+			if string(value) == id {
+				return inputFiles.Delete(key)
+			}
+			return nil
+		})
+		if err != nil {
+			return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
+		}
+		err = tx.Bucket(bucketHistory).DeleteBucket([]byte(id))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
+		}
+		return nil
+	})
+}
+
+// GetAll retrieves all the jobs from the bolt db
+func (bdb BoltDB) GetAll() ([]types.Job, error) {
+	return bdb.getJobs(func(types.Job) bool { return true })
+}
+
+// GetById retrieves the job from the bolt db matching the id passed in
+func (bdb BoltDB) GetById(id string) (types.Job, error) {
+	if id == "" {
+		return types.Job{}, pkg.ErrJobIdEmpty
+	}
+	var job types.Job
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		var err error
+		job, err = getBoltJob(tx, id)
+		return err
+	})
+	return job, err
+}
+
+// GetByOwner retrieves the jobs from the bolt db matching the owner passed in
+func (bdb BoltDB) GetByOwner(owner string) ([]types.Job, error) {
+	if owner != pkg.OwnerNone && owner != pkg.OwnerFileWatcher && owner != pkg.OwnerDataOrg &&
+		owner != pkg.OwnerFileSenderOem && owner != pkg.OwnerFileRecvGateway && owner != pkg.OwnerTaskLauncher &&
+		owner != pkg.OwnerFileSenderGateway && owner != pkg.OwnerFileRecvOem {
+		return nil, fmt.Errorf(pkg.ErrFmtInvalidInput, owner, "proper owner name")
+	}
+	return bdb.getJobs(func(job types.Job) bool { return job.Owner == owner })
+}
+
+// GetHistory retrieves the updates made to the job matching the id passed in, oldest first
+func (bdb BoltDB) GetHistory(id string) ([]types.JobHistoryEntry, error) {
+	if id == "" {
+		return nil, pkg.ErrJobIdEmpty
+	}
+	history := []types.JobHistoryEntry{}
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketJob).Get([]byte(id)) == nil {
+			return fmt.Errorf(pkg.ErrJobIdNotFound, id)
+		}
+		entries := tx.Bucket(bucketHistory).Bucket([]byte(id))
+		if entries == nil {
+			return nil
+		}
+		return entries.ForEach(func(_, entryJson []byte) error {
+			var entry types.JobHistoryEntry
+			err := json.Unmarshal(entryJson, &entry)
+			if err != nil {
+				return werrors.WrapMsg(err, "failed to unmarshal job history")
+			}
+			history = append(history, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Query retrieves the jobs matching the filter ordered by last updated time, then by id.
+// It returns at most filter.Limit jobs and a cursor to retrieve the next page,
+// the cursor is empty when there are no more jobs. The cursors are interchangeable with the RedisDB ones.
+func (bdb BoltDB) Query(filter types.JobFilter) ([]types.Job, string, error) {
+	limit := queryLimit(filter.Limit)
+	var start queryPosition
+	var err error
+	if filter.Cursor != "" {
+		start, err = decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if start.Score < scoreFromTime(filter.Since) {
+		start = queryPosition{Score: scoreFromTime(filter.Since)}
+	}
+
+	jobs := make([]types.Job, 0, limit)
+	cursor := ""
+	err = bdb.db.View(func(tx *bolt.Tx) error {
+		jobBucket := tx.Bucket(bucketJob)
+		ordered := tx.Bucket(bucketLastUpdated).Cursor()
+		var last queryPosition
+		for key, _ := ordered.Seek(uint64Key(uint64(start.Score))); key != nil; key, _ = ordered.Next() {
+			position := queryPosition{Score: int64(binary.BigEndian.Uint64(key[:8])), Id: string(key[8:])}
+			if filter.Until != 0 && position.Score > scoreFromTime(filter.Until) {
+				break
+			}
+			if filter.Cursor != "" && !position.after(start) {
+				continue
+			}
+			var job types.Job
+			err := json.Unmarshal(jobBucket.Get([]byte(position.Id)), &job)
+			if err != nil {
+				return werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+			}
+			if !filter.Matches(job) {
+				continue
+			}
+			if len(jobs) == limit {
+				// there is at least one more job so return a cursor for the next page
+				cursor = encodeCursor(last)
+				return nil
+			}
+			jobs = append(jobs, job)
+			last = position
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return jobs, cursor, nil
+}
+
+func (bdb BoltDB) Disconnect() error {
+	return bdb.db.Close()
+}
+
+// getJobs is a helper function to retrieve the jobs for which the include function returns true
+func (bdb BoltDB) getJobs(include func(types.Job) bool) ([]types.Job, error) {
+	jobs := []types.Job{}
+	err := bdb.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketJob).ForEach(func(_, jobJson []byte) error {
+			job := types.Job{}
+			err := json.Unmarshal(jobJson, &job)
+			if err != nil {
+				return werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+			}
+			if include(job) {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return []types.Job{}, err
+	}
+	return jobs, nil
+}
+
+// getBoltJob is a helper function to retrieve and unmarshal a single job within a transaction
+func getBoltJob(tx *bolt.Tx, id string) (types.Job, error) {
+	job := types.Job{}
+	data := tx.Bucket(bucketJob).Get([]byte(id))
+	if data == nil {
+		return job, fmt.Errorf(pkg.ErrJobIdNotFound, id)
+	}
+	err := json.Unmarshal(data, &job)
+	if err != nil {
+		return job, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+	return job, nil
+}
+
+// putBoltJob is a helper function to store the new job and move its last_updated entry from the old job
+func putBoltJob(tx *bolt.Tx, oldJob *types.Job, newJob *types.Job) error {
+	data, err := json.Marshal(newJob)
+	if err != nil {
+		return werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+	err = tx.Bucket(bucketJob).Put([]byte(newJob.Id), data)
+	if err != nil {
+		return err
+	}
+	ordered := tx.Bucket(bucketLastUpdated)
+	if oldJob != nil {
+		err = ordered.Delete(lastUpdatedKey(*oldJob))
+		if err != nil {
+			return err
+		}
+	}
+	return ordered.Put(lastUpdatedKey(*newJob), nil)
+}
+
+// lastUpdatedKey returns the key of the job in the last_updated bucket, the big endian score followed by
+// the job id, so that iterating the bucket walks the jobs in the same order as the RedisDB query index
+func lastUpdatedKey(job types.Job) []byte {
+	return append(uint64Key(uint64(scoreFromTime(job.LastUpdated))), job.Id...)
+}
+
+// uint64Key returns the big endian encoding of the value so keys sort numerically
+func uint64Key(value uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, value)
+	return key
+}
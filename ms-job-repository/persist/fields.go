@@ -0,0 +1,255 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+)
+
+// repositoryFields are the job fields maintained by the job repository that can not be updated
+var repositoryFields = []string{"Id", "Version", "LastUpdated", "Transitions"}
+
+var (
+	jobOwners = []string{pkg.OwnerNone, pkg.OwnerFileWatcher, pkg.OwnerDataOrg, pkg.OwnerFileSenderOem,
+		pkg.OwnerFileRecvGateway, pkg.OwnerTaskLauncher, pkg.OwnerFileSenderGateway, pkg.OwnerFileRecvOem, pkg.JobRepository}
+	jobStatuses = []string{pkg.StatusComplete, pkg.StatusIncomplete, pkg.StatusNoPipeline, pkg.StatusPipelineError,
+		pkg.StatusTransmissionFailed, pkg.StatusFileError}
+	jobVerifications = []string{fmt.Sprint(pkg.VerificationPending), fmt.Sprint(pkg.VerificationAccepted),
+		fmt.Sprint(pkg.VerificationRejected)}
+	pipelineStatuses = []string{"", pkg.TaskStatusComplete, pkg.TaskStatusProcessing, pkg.TaskStatusFailed,
+		pkg.TaskStatusFileNotFound, pkg.TaskStatusTimedOut}
+	fileStatuses = []string{"", pkg.FileStatusComplete, pkg.FileStatusIncomplete, pkg.FileStatusTransmissionFailed,
+		pkg.FileStatusArchiveFailed, pkg.FileStatusWriteFailed, pkg.FileStatusInvalid}
+)
+
+// allowedValues are the values accepted for the job fields with a fixed set of values, by field path.
+// The fields of the elements of a list use the path of the list.
+var allowedValues = map[string][]string{
+	types.JobOwner:                                  jobOwners,
+	types.JobStatus:                                 jobStatuses,
+	types.JobVerification:                           jobVerifications,
+	types.JobPipelineStatus:                         pipelineStatuses,
+	types.JobErrorDetailsOwner:                      append([]string{""}, jobOwners...),
+	types.JobPipelineOutputFiles + ".Owner":         append([]string{""}, jobOwners...),
+	types.JobPipelineOutputFiles + ".Status":        fileStatuses,
+	types.JobPipelineRuns + ".Status":               pipelineStatuses,
+	types.JobPipelineRuns + ".OutputFiles.Owner":    append([]string{""}, jobOwners...),
+	types.JobPipelineRuns + ".OutputFiles.Status":   fileStatuses,
+	types.JobPipelineStages + ".Status":             pipelineStatuses,
+	types.JobPipelineStages + ".OutputFiles.Owner":  append([]string{""}, jobOwners...),
+	types.JobPipelineStages + ".OutputFiles.Status": fileStatuses,
+}
+
+// InvalidFieldsError is returned by Update when job fields do not match the job schema
+type InvalidFieldsError struct {
+	// Fields holds the reason each invalid field path was rejected
+	Fields map[string]string
+}
+
+func (e *InvalidFieldsError) Error() string {
+	paths := make([]string, 0, len(e.Fields))
+	for path := range e.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for i, path := range paths {
+		paths[i] = fmt.Sprintf("%s (%s)", path, e.Fields[path])
+	}
+	return "invalid job fields: " + strings.Join(paths, "; ")
+}
+
+// ValidateJobFields checks that every field path names a field of the job that can be updated,
+// that its value can be unmarshalled into the type of the field and that fields with a fixed set of values,
+// such as the Status, are set to one of them. It returns an InvalidFieldsError listing the offending paths.
+func ValidateJobFields(jobFields map[string]interface{}) error {
+	invalid := make(map[string]string)
+	for path, value := range jobFields {
+		if reason := validateJobField(path, value); reason != "" {
+			invalid[path] = reason
+		}
+	}
+	if len(invalid) > 0 {
+		return &InvalidFieldsError{Fields: invalid}
+	}
+	return nil
+}
+
+// MergePatchFields applies the RFC 7396 JSON merge patch to the job and returns the paths and values of the fields
+// it changed, descending into the nested structures so that only the changed fields are updated
+func MergePatchFields(job types.Job, patch []byte) (map[string]interface{}, error) {
+	var patchDoc interface{}
+	err := json.Unmarshal(patch, &patchDoc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %s", err.Error())
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid merge patch: expected a json object")
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var jobDoc map[string]interface{}
+	err = json.Unmarshal(data, &jobDoc)
+	if err != nil {
+		return nil, err
+	}
+	// the merge patch is applied to a fresh copy of the job document as it modifies the target
+	var target map[string]interface{}
+	_ = json.Unmarshal(data, &target)
+	patched := mergePatch(target, patchDoc).(map[string]interface{})
+
+	jobFields := make(map[string]interface{})
+	diffJobFields("", jobDoc, patched, jobFields)
+	return jobFields, nil
+}
+
+// validateJobField returns the reason the field path and value are invalid, or an empty string if they are valid
+func validateJobField(path string, value interface{}) string {
+	for _, field := range repositoryFields {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return "maintained by the job repository"
+		}
+	}
+	fieldType, ok := jobFieldType(path)
+	if !ok {
+		return "unknown field"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("could not marshal value: %s", err.Error())
+	}
+	typed := reflect.New(fieldType)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(typed.Interface())
+	if err != nil {
+		return fmt.Sprintf("expected a value of type %s: %s", fieldType, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return checkAllowedValues(path, typed.Elem())
+}
+
+// jobFieldType returns the type of the job field at the dot separated path.
+// Paths can name entries of the maps of the job, such as InputFile.Attributes.{name}.
+func jobFieldType(path string) (reflect.Type, bool) {
+	fieldType := reflect.TypeOf(types.Job{})
+	for _, name := range strings.Split(path, ".") {
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			field, ok := fieldType.FieldByName(name)
+			if !ok || !field.IsExported() {
+				return nil, false
+			}
+			fieldType = field.Type
+		case reflect.Map:
+			fieldType = fieldType.Elem()
+		default:
+			return nil, false
+		}
+	}
+	return fieldType, true
+}
+
+// checkAllowedValues checks the value and its nested fields against the allowedValues of their paths
+func checkAllowedValues(path string, value reflect.Value) string {
+	if allowed, ok := allowedValues[path]; ok && value.Kind() != reflect.Ptr && value.Kind() != reflect.Slice {
+		actual := fmt.Sprint(value.Interface())
+		found := false
+		for _, allowedValue := range allowed {
+			if actual == allowedValue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("got %q, expected one of %q", actual, allowed)
+		}
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return checkAllowedValues(path, value.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if reason := checkAllowedValues(path, value.Index(i)); reason != "" {
+				return reason
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !value.Type().Field(i).IsExported() {
+				continue
+			}
+			if reason := checkAllowedValues(path+"."+value.Type().Field(i).Name, value.Field(i)); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// mergePatch applies the RFC 7396 merge patch to the target document and returns the result
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// diffJobFields adds the paths and new values of the fields that differ between the job documents to jobFields.
+// Nested objects are compared field by field when they are structures of the job, and as a whole otherwise.
+func diffJobFields(prefix string, oldDoc map[string]interface{}, newDoc map[string]interface{}, jobFields map[string]interface{}) {
+	names := make(map[string]bool)
+	for name := range oldDoc {
+		names[name] = true
+	}
+	for name := range newDoc {
+		names[name] = true
+	}
+	for name := range names {
+		oldValue, newValue := oldDoc[name], newDoc[name]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		path := prefix + name
+		oldObject, oldIsObject := oldValue.(map[string]interface{})
+		newObject, newIsObject := newValue.(map[string]interface{})
+		if fieldType, ok := jobFieldType(path); ok && oldIsObject && newIsObject {
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				diffJobFields(path+".", oldObject, newObject, jobFields)
+				continue
+			}
+		}
+		jobFields[path] = newValue
+	}
+}
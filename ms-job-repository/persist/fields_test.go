@@ -0,0 +1,105 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJobFields(t *testing.T) {
+	outputFiles := []types.OutputFile{helpers.CreateTestFile("./test/out.tiff", pkg.FileStatusIncomplete, pkg.OwnerTaskLauncher, nil)}
+	// values decoded from a request body are generic json values
+	var decodedOutputFiles interface{}
+	data, err := json.Marshal(outputFiles)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &decodedOutputFiles))
+
+	tests := []struct {
+		Name            string
+		JobFields       map[string]interface{}
+		ExpectedInvalid map[string]string
+	}{
+		{"happy path", map[string]interface{}{types.JobOwner: pkg.OwnerNone, types.JobStatus: pkg.StatusComplete,
+			types.JobPipelineStatus: pkg.TaskStatusComplete, types.JobVerification: float64(pkg.VerificationAccepted)}, nil},
+		{"happy path - output files", map[string]interface{}{types.JobPipelineOutputFiles: outputFiles}, nil},
+		{"happy path - decoded output files", map[string]interface{}{types.JobPipelineOutputFiles: decodedOutputFiles}, nil},
+		{"happy path - attribute", map[string]interface{}{"InputFile.Attributes.LabName": "lab"}, nil},
+		{"happy path - clear error details", map[string]interface{}{"ErrorDetails": nil}, nil},
+		{"unknown field", map[string]interface{}{"Bogus": "bogus", "InputFile.Bogus": "bogus"},
+			map[string]string{"Bogus": "unknown field", "InputFile.Bogus": "unknown field"}},
+		{"repository field", map[string]interface{}{"Version": 2, "Transitions.Owner": "bogus"},
+			map[string]string{"Version": "maintained by the job repository", "Transitions.Owner": "maintained by the job repository"}},
+		{"wrong type", map[string]interface{}{types.JobStatus: 5, types.JobPipelineOutputFiles: "out.tiff"},
+			map[string]string{types.JobStatus: "expected a value of type string", types.JobPipelineOutputFiles: "expected a value of type []types.OutputFile"}},
+		{"unknown nested field", map[string]interface{}{types.JobPipelineOutputFiles: []interface{}{map[string]interface{}{"Bogus": "bogus"}}},
+			map[string]string{types.JobPipelineOutputFiles: "unknown field"}},
+		{"unknown status", map[string]interface{}{types.JobStatus: "Bogus", types.JobVerification: 7},
+			map[string]string{types.JobStatus: `got "Bogus"`, types.JobVerification: `got "7"`}},
+		{"unknown output file status", map[string]interface{}{types.JobPipelineOutputFiles: []interface{}{map[string]interface{}{"Status": "Bogus"}}},
+			map[string]string{types.JobPipelineOutputFiles: `got "Bogus"`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := ValidateJobFields(test.JobFields)
+			if test.ExpectedInvalid == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			invalidFieldsErr, ok := err.(*InvalidFieldsError)
+			require.True(t, ok, "expected an InvalidFieldsError")
+			require.Len(t, invalidFieldsErr.Fields, len(test.ExpectedInvalid))
+			for path, reason := range test.ExpectedInvalid {
+				assert.Contains(t, invalidFieldsErr.Fields[path], reason)
+				assert.Contains(t, err.Error(), path)
+			}
+		})
+	}
+}
+
+func TestMergePatchFields(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, Hostname)
+	job.InputFile.Attributes = map[string]string{"LabName": "lab", "Operator": "operator"}
+	job.ErrorDetails = pkg.CreateUserFacingError(pkg.OwnerTaskLauncher, pkg.ErrJobInvalid)
+
+	tests := []struct {
+		Name        string
+		Patch       string
+		Expected    map[string]interface{}
+		ExpectedErr string
+	}{
+		{"happy path", `{"Status": "Complete"}`, map[string]interface{}{types.JobStatus: pkg.StatusComplete}, ""},
+		{"happy path - nested", `{"PipelineDetails": {"Status": "PipelineFailed", "Results": "count,5"}, "InputFile": {"Attributes": {"Operator": null}}}`,
+			map[string]interface{}{types.JobPipelineStatus: pkg.TaskStatusFailed, types.JobPipelineResults: "count,5",
+				"InputFile.Attributes": map[string]interface{}{"LabName": "lab"}}, ""},
+		{"happy path - remove", `{"ErrorDetails": null}`, map[string]interface{}{"ErrorDetails": nil}, ""},
+		{"happy path - unchanged", `{"Owner": "` + pkg.OwnerTaskLauncher + `"}`, map[string]interface{}{}, ""},
+		{"happy path - unknown field", `{"Bogus": {"Bogus": true}}`, map[string]interface{}{"Bogus": map[string]interface{}{"Bogus": true}}, ""},
+		{"invalid json", `{"Status"`, nil, "invalid merge patch"},
+		{"not an object", `"Complete"`, nil, "expected a json object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			jobFields, err := MergePatchFields(job, []byte(test.Patch))
+			if test.ExpectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, jobFields)
+		})
+	}
+}
@@ -17,10 +17,12 @@ const (
 
 type Persistence interface {
 	Create(job types.Job) (string, types.Job, error)
-	Update(id string, values map[string]interface{}) (types.Job, error)
+	Update(id string, values map[string]interface{}, caller string, version int64) (types.Job, error)
 	Delete(id string) error
 	GetAll() ([]types.Job, error)
 	GetById(id string) (types.Job, error)
 	GetByOwner(owner string) ([]types.Job, error)
+	GetHistory(id string) ([]types.JobHistoryEntry, error)
+	Query(filter types.JobFilter) ([]types.Job, string, error)
 	Disconnect() error
 }
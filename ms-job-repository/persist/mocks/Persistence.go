@@ -148,23 +148,82 @@ func (_m *Persistence) GetByOwner(owner string) ([]types.Job, error) {
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: id, values
-func (_m *Persistence) Update(id string, values map[string]interface{}) (types.Job, error) {
-	ret := _m.Called(id, values)
+// GetHistory provides a mock function with given fields: id
+func (_m *Persistence) GetHistory(id string) ([]types.JobHistoryEntry, error) {
+	ret := _m.Called(id)
+
+	var r0 []types.JobHistoryEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]types.JobHistoryEntry, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) []types.JobHistoryEntry); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.JobHistoryEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Query provides a mock function with given fields: filter
+func (_m *Persistence) Query(filter types.JobFilter) ([]types.Job, string, error) {
+	ret := _m.Called(filter)
+
+	var r0 []types.Job
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(types.JobFilter) ([]types.Job, string, error)); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(types.JobFilter) []types.Job); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(types.JobFilter) string); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(types.JobFilter) error); ok {
+		r2 = rf(filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Update provides a mock function with given fields: id, values, caller, version
+func (_m *Persistence) Update(id string, values map[string]interface{}, caller string, version int64) (types.Job, error) {
+	ret := _m.Called(id, values, caller, version)
 
 	var r0 types.Job
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, map[string]interface{}) (types.Job, error)); ok {
-		return rf(id, values)
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}, string, int64) (types.Job, error)); ok {
+		return rf(id, values, caller, version)
 	}
-	if rf, ok := ret.Get(0).(func(string, map[string]interface{}) types.Job); ok {
-		r0 = rf(id, values)
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}, string, int64) types.Job); ok {
+		r0 = rf(id, values, caller, version)
 	} else {
 		r0 = ret.Get(0).(types.Job)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, map[string]interface{}) error); ok {
-		r1 = rf(id, values)
+	if rf, ok := ret.Get(1).(func(string, map[string]interface{}, string, int64) error); ok {
+		r1 = rf(id, values, caller, version)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,293 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aicsd/pkg"
+	"aicsd/pkg/clients/redis"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file run against every Persistence backend to verify they behave the same way.
+// The Redis backend uses an in memory Redis server so no database needs to be running.
+
+// backends returns a constructor for each Persistence backend
+func backends() map[string]func(t *testing.T) Persistence {
+	return map[string]func(t *testing.T) Persistence{
+		pkg.PersistenceTypeRedis: func(t *testing.T) Persistence {
+			server := miniredis.RunT(t)
+			persistence, err := NewRedisDB(logger.MockLogger{}, redis.NewClient(server.Host(), server.Port(), time.Second, nil))
+			require.NoError(t, err)
+			return persistence
+		},
+		pkg.PersistenceTypeBolt: func(t *testing.T) Persistence {
+			persistence, err := NewBoltDB(logger.MockLogger{}, filepath.Join(t.TempDir(), "jobs.db"))
+			require.NoError(t, err)
+			return persistence
+		},
+	}
+}
+
+// runForEachBackend runs the test against a new, empty persistence of every backend
+func runForEachBackend(t *testing.T, test func(t *testing.T, persistence Persistence)) {
+	for name, newPersistence := range backends() {
+		t.Run(name, func(t *testing.T) {
+			persistence := newPersistence(t)
+			defer func() { _ = persistence.Disconnect() }()
+			test(t, persistence)
+		})
+	}
+}
+
+// createBackendJob creates a job for the input file name and returns it
+func createBackendJob(t *testing.T, persistence Persistence, name string, owner string) types.Job {
+	job := helpers.CreateTestJob(owner, Hostname)
+	job.Id = ""
+	job.InputFile.Name = name
+	status, created, err := persistence.Create(job)
+	require.NoError(t, err)
+	require.Equal(t, StatusCreated, status)
+	return created
+}
+
+func TestPersistence_Create(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		created := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+		assert.NotEmpty(t, created.Id)
+		assert.Equal(t, int64(1), created.Version)
+
+		duplicate := helpers.CreateTestJob(pkg.OwnerDataOrg, Hostname)
+		duplicate.Id = ""
+		duplicate.InputFile.Name = "test-image.tiff"
+		status, existing, err := persistence.Create(duplicate)
+		require.NoError(t, err)
+		assert.Equal(t, StatusExists, status)
+		assert.Equal(t, created, existing)
+
+		_, _, err = persistence.Create(helpers.CreateTestJob(pkg.OwnerDataOrg, Hostname))
+		require.Error(t, err)
+
+		invalid := helpers.CreateTestJob(pkg.OwnerDataOrg, "")
+		invalid.Id = ""
+		_, _, err = persistence.Create(invalid)
+		assert.Equal(t, pkg.ErrJobInvalid, err)
+	})
+}
+
+func TestPersistence_GetById(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		created := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+
+		job, err := persistence.GetById(created.Id)
+		require.NoError(t, err)
+		assert.Equal(t, created, job)
+
+		_, err = persistence.GetById("unknown")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "job id not found for unknown")
+
+		_, err = persistence.GetById("")
+		assert.Equal(t, pkg.ErrJobIdEmpty, err)
+	})
+}
+
+func TestPersistence_GetAllAndGetByOwner(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		jobs, err := persistence.GetAll()
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		first := createBackendJob(t, persistence, "first.tiff", pkg.OwnerDataOrg)
+		second := createBackendJob(t, persistence, "second.tiff", pkg.OwnerTaskLauncher)
+
+		jobs, err = persistence.GetAll()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []types.Job{first, second}, jobs)
+
+		jobs, err = persistence.GetByOwner(pkg.OwnerTaskLauncher)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Job{second}, jobs)
+
+		jobs, err = persistence.GetByOwner(pkg.OwnerFileSenderOem)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		_, err = persistence.GetByOwner("invalid")
+		require.Error(t, err)
+	})
+}
+
+func TestPersistence_Update(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		created := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+
+		updated, err := persistence.Update(created.Id, map[string]interface{}{
+			types.JobOwner:           pkg.OwnerTaskLauncher,
+			types.JobPipelineResults: "count,5",
+		}, pkg.OwnerDataOrg, created.Version)
+		require.NoError(t, err)
+		assert.Equal(t, pkg.OwnerTaskLauncher, updated.Owner)
+		assert.Equal(t, "count,5", updated.PipelineDetails.Results)
+		assert.Equal(t, int64(2), updated.Version)
+		require.Len(t, updated.Transitions, 2)
+		assert.Equal(t, types.Transition{Owner: created.Owner, Status: created.Status, Timestamp: created.LastUpdated}, updated.Transitions[0])
+		assert.Equal(t, types.Transition{Owner: pkg.OwnerTaskLauncher, Status: updated.Status, Timestamp: updated.LastUpdated}, updated.Transitions[1])
+
+		job, err := persistence.GetById(created.Id)
+		require.NoError(t, err)
+		assert.Equal(t, updated, job)
+		jobs, err := persistence.GetByOwner(pkg.OwnerDataOrg)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+		jobs, err = persistence.GetByOwner(pkg.OwnerTaskLauncher)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Job{updated}, jobs)
+
+		review := types.Review{Reviewer: "reviewer", Reason: "reason", Timestamp: 1}
+		reviewed, err := persistence.Update(created.Id, map[string]interface{}{
+			types.JobVerification: pkg.VerificationRejected,
+			types.JobReview:       review,
+		}, pkg.OwnerNone, 0)
+		require.NoError(t, err)
+		assert.Equal(t, pkg.VerificationRejected, reviewed.Verification)
+		assert.Equal(t, &review, reviewed.Review)
+
+		_, err = persistence.Update(created.Id, map[string]interface{}{types.JobOwner: pkg.OwnerNone}, pkg.OwnerDataOrg, created.Version)
+		assert.Equal(t, pkg.ErrJobVersionMismatch, err)
+
+		_, err = persistence.Update(created.Id, map[string]interface{}{"Unknown": "value"}, pkg.OwnerDataOrg, 0)
+		assert.IsType(t, &InvalidFieldsError{}, err)
+
+		_, err = persistence.Update(created.Id, map[string]interface{}{types.JobStatus: "Unknown"}, pkg.OwnerDataOrg, 0)
+		assert.IsType(t, &InvalidFieldsError{}, err)
+
+		_, err = persistence.Update("unknown", map[string]interface{}{types.JobOwner: pkg.OwnerNone}, pkg.OwnerDataOrg, 0)
+		require.Error(t, err)
+
+		_, err = persistence.Update(created.Id, map[string]interface{}{}, pkg.OwnerDataOrg, 0)
+		require.Error(t, err)
+
+		_, err = persistence.Update("", map[string]interface{}{types.JobOwner: pkg.OwnerNone}, pkg.OwnerDataOrg, 0)
+		assert.Equal(t, pkg.ErrJobIdEmpty, err)
+	})
+}
+
+func TestPersistence_GetHistory(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		created := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+
+		history, err := persistence.GetHistory(created.Id)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+
+		first, err := persistence.Update(created.Id, map[string]interface{}{types.JobOwner: pkg.OwnerTaskLauncher}, pkg.OwnerDataOrg, 0)
+		require.NoError(t, err)
+		second, err := persistence.Update(created.Id, map[string]interface{}{types.JobStatus: pkg.StatusComplete}, pkg.OwnerTaskLauncher, 0)
+		require.NoError(t, err)
+
+		history, err = persistence.GetHistory(created.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []types.JobHistoryEntry{
+			{
+				Timestamp: first.LastUpdated,
+				Caller:    pkg.OwnerDataOrg,
+				OldOwner:  pkg.OwnerDataOrg,
+				NewOwner:  pkg.OwnerTaskLauncher,
+				Changes:   []types.FieldChange{{Path: types.JobOwner, OldValue: pkg.OwnerDataOrg, NewValue: pkg.OwnerTaskLauncher}},
+			},
+			{
+				Timestamp: second.LastUpdated,
+				Caller:    pkg.OwnerTaskLauncher,
+				OldOwner:  pkg.OwnerTaskLauncher,
+				NewOwner:  pkg.OwnerTaskLauncher,
+				Changes:   []types.FieldChange{{Path: types.JobStatus, OldValue: created.Status, NewValue: pkg.StatusComplete}},
+			},
+		}, history)
+
+		_, err = persistence.GetHistory("unknown")
+		require.Error(t, err)
+	})
+}
+
+func TestPersistence_Delete(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		created := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+		_, err := persistence.Update(created.Id, map[string]interface{}{types.JobStatus: pkg.StatusComplete}, pkg.OwnerDataOrg, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, persistence.Delete(created.Id))
+
+		_, err = persistence.GetById(created.Id)
+		require.Error(t, err)
+		_, err = persistence.GetHistory(created.Id)
+		require.Error(t, err)
+		jobs, cursor, err := persistence.Query(types.JobFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+		assert.Empty(t, cursor)
+
+		// the input file is no longer referenced so the same file creates a new job
+		recreated := createBackendJob(t, persistence, "test-image.tiff", pkg.OwnerDataOrg)
+		assert.NotEqual(t, created.Id, recreated.Id)
+
+		require.Error(t, persistence.Delete(created.Id))
+		assert.Equal(t, pkg.ErrJobIdEmpty, persistence.Delete(""))
+	})
+}
+
+func TestPersistence_Query(t *testing.T) {
+	runForEachBackend(t, func(t *testing.T, persistence Persistence) {
+		var jobs []types.Job
+		for _, name := range []string{"first.tiff", "second.tiff", "third.tiff", "fourth.tiff"} {
+			jobs = append(jobs, createBackendJob(t, persistence, name, pkg.OwnerDataOrg))
+			// separate the last updated scores so the query order is the creation order
+			time.Sleep(2 * time.Millisecond)
+		}
+		completed, err := persistence.Update(jobs[1].Id, map[string]interface{}{types.JobStatus: pkg.StatusComplete}, pkg.OwnerDataOrg, 0)
+		require.NoError(t, err)
+		jobs = []types.Job{jobs[0], jobs[2], jobs[3], completed}
+
+		// page through all the jobs
+		var paged []types.Job
+		filter := types.JobFilter{Limit: 3}
+		for {
+			page, cursor, err := persistence.Query(filter)
+			require.NoError(t, err)
+			paged = append(paged, page...)
+			if cursor == "" {
+				break
+			}
+			filter.Cursor = cursor
+		}
+		assert.Equal(t, jobs, paged)
+
+		result, cursor, err := persistence.Query(types.JobFilter{Status: pkg.StatusComplete})
+		require.NoError(t, err)
+		assert.Equal(t, []types.Job{completed}, result)
+		assert.Empty(t, cursor)
+
+		result, _, err = persistence.Query(types.JobFilter{Since: jobs[1].LastUpdated, Until: jobs[2].LastUpdated})
+		require.NoError(t, err)
+		assert.Equal(t, jobs[1:3], result)
+
+		result, _, err = persistence.Query(types.JobFilter{Owner: pkg.OwnerTaskLauncher})
+		require.NoError(t, err)
+		assert.Empty(t, result)
+
+		_, _, err = persistence.Query(types.JobFilter{Cursor: "invalid cursor"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,83 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultQueryLimit is the page size used when a query does not specify a limit
+	DefaultQueryLimit = 100
+	// MaxQueryLimit is the largest page size a query may request
+	MaxQueryLimit   = 1000
+	cursorSeparator = "|"
+)
+
+// queryPosition is the position of a job in the query ordering.
+// Jobs are ordered by their last updated time in milliseconds, then by their id.
+type queryPosition struct {
+	Score int64
+	Id    string
+}
+
+// after reports whether the position is strictly past the other position
+func (p queryPosition) after(other queryPosition) bool {
+	if p.Score != other.Score {
+		return p.Score > other.Score
+	}
+	return p.Id > other.Id
+}
+
+// scoreFromTime converts the nanosecond job LastUpdated value to the millisecond score used to order jobs.
+// Milliseconds are used so the value is exactly representable as a redis sorted set score.
+func scoreFromTime(lastUpdated int64) int64 {
+	return lastUpdated / int64(time.Millisecond)
+}
+
+// encodeCursor returns the opaque cursor that resumes a query after the given position
+func encodeCursor(position queryPosition) string {
+	raw := strconv.FormatInt(position.Score, 10) + cursorSeparator + position.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor created by encodeCursor
+func decodeCursor(cursor string) (queryPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return queryPosition{}, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	parts := strings.SplitN(string(raw), cursorSeparator, 2)
+	if len(parts) != 2 {
+		return queryPosition{}, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	score, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return queryPosition{}, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	return queryPosition{Score: score, Id: parts[1]}, nil
+}
+
+// ValidateCursor returns an error if the cursor was not created by a previous query
+func ValidateCursor(cursor string) error {
+	_, err := decodeCursor(cursor)
+	return err
+}
+
+// queryLimit returns the page size to use for the requested limit
+func queryLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		return MaxQueryLimit
+	}
+	return limit
+}
@@ -9,7 +9,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"strconv"
+	"time"
 
 	"aicsd/pkg"
 	"aicsd/pkg/clients/redis"
@@ -31,6 +32,18 @@ const (
 					] }`
 )
 
+const (
+	// deleteReplyCount is the number of replies from Delete that must each be 1 for the delete to succeed
+	deleteReplyCount = 4
+	// maxTransactionAttempts is the number of times a transaction is attempted before giving up
+	maxTransactionAttempts = 5
+	// transactionRetryDelay is multiplied by the attempt number to get the delay before retrying a transaction
+	transactionRetryDelay = 10 * time.Millisecond
+)
+
+// errTransactionAborted is returned when a transaction is aborted because a WATCHed key was modified
+var errTransactionAborted = errors.New("transaction aborted")
+
 type RedisDB struct {
 	lc          logger.LoggingClient
 	redisClient redis.DBClient
@@ -48,13 +61,18 @@ func NewRedisDB(lc logger.LoggingClient, redisClient redis.DBClient) (Persistenc
 		return nil, err
 	}
 	defer func() { _ = conn.Close() }()
+	err = persistence.indexExistingJobs(conn)
+	if err != nil {
+		return nil, err
+	}
 	return persistence, nil
 }
 
 // Create returns a status (StatusExists or StatusCreated), the job, and an error (if one occurred)
 // It creates a hash entry in redis db under the key job, the field {job.Id}, and the value str(job_object),
 // a hash entry with the key input_file, the field hostname:dirname:filename, and value {job.Id},
-// a hash entry with the key job|owner:{job.Owner}, the field {job.Id}, and an empty string
+// a hash entry with the key job|owner:{job.Owner}, the field {job.Id}, and an empty string,
+// the query index entries (see sendIndexUpdates)
 // and a set entry with the key lock:job:{job.Id} and value empty string.
 // Note that the hash entry for the input file will always reference the originating system and will NOT be
 // updated as the file moves across systems. This is for quick referencing for the creation of jobs
@@ -96,7 +114,10 @@ func (rdb RedisDB) Create(job types.Job) (string, types.Job, error) {
 
 	// job doesn't exist so set the job id and set in redis db
 	job.Id = uuid.NewString()
+	job.Version = 1
 	job.SetLastUpdated()
+	job.Transitions = nil
+	job.RecordTransition()
 	job.ErrorDetails = pkg.CreateUserFacingError("", nil)
 	jsonJob, err := json.Marshal(job)
 	if err != nil {
@@ -114,6 +135,7 @@ func (rdb RedisDB) Create(job types.Job) (string, types.Job, error) {
 	_ = conn.Send(redis.HSET, redis.KeyInputFile, inputFileInfo, job.Id)
 	// owner:{owner_name}, job_id, empty string
 	_ = conn.Send(redis.HSET, ownerKey, job.Id, "")
+	sendIndexUpdates(conn, job.Id, nil, &job)
 	_, err = conn.Do(redis.EXEC)
 	if err != nil {
 		return StatusNone, types.Job{}, werrors.WrapErr(err, pkg.ErrJobCreation)
@@ -121,8 +143,14 @@ func (rdb RedisDB) Create(job types.Job) (string, types.Job, error) {
 	return StatusCreated, job, nil
 }
 
-// Update retrieves the job id and modifies the entry using the given values
-func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Job, error) {
+// Update retrieves the job id and modifies the entry using the given values.
+// It appends an entry to the job history list with the key job|history:{job.Id} recording the
+// changed fields and the caller that made the update.
+// When version is not 0 the update is only applied if the job is still at that version, otherwise
+// pkg.ErrJobVersionMismatch is returned. The job version is incremented on every update.
+// The update is retried if the job is modified concurrently, and pkg.ErrJobUpdateConflict is
+// returned if it can not be applied within maxTransactionAttempts.
+func (rdb RedisDB) Update(id string, jobFields map[string]interface{}, caller string, version int64) (types.Job, error) {
 	// check id exists - not empty
 	if id == "" {
 		return types.Job{}, pkg.ErrJobIdEmpty
@@ -130,6 +158,10 @@ func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Jo
 	if len(jobFields) == 0 {
 		return types.Job{}, errors.New("no job fields provided to update")
 	}
+	err := ValidateJobFields(jobFields)
+	if err != nil {
+		return types.Job{}, err
+	}
 	// get a connection to redis db
 	conn := rdb.redisClient.GetConnection()
 	defer func() { _ = conn.Close() }()
@@ -142,11 +174,27 @@ func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Jo
 	if !exists {
 		return types.Job{}, fmt.Errorf(pkg.ErrJobIdNotFound, id)
 	}
+	for attempt := 1; ; attempt++ {
+		job, err := rdb.update(conn, id, jobFields, caller, version)
+		if err != errTransactionAborted {
+			return job, err
+		}
+		if attempt == maxTransactionAttempts {
+			rdb.lc.Errorf("update of job %s aborted %d times by concurrent updates", id, attempt)
+			return types.Job{}, pkg.ErrJobUpdateConflict
+		}
+		rdb.lc.Debugf("update of job %s aborted by a concurrent update, retrying", id)
+		time.Sleep(transactionRetryDelay * time.Duration(attempt))
+	}
+}
+
+// update applies a single attempt of Update within a redis transaction watching the job lock key.
+// It returns errTransactionAborted if the transaction was aborted by a concurrent update.
+func (rdb RedisDB) update(conn redigo.Conn, id string, jobFields map[string]interface{}, caller string, version int64) (types.Job, error) {
 	// use redis WATCH to lock the database
 	lockKey := redis.CreateKey(redis.KeyLock, redis.KeyJob, id)
-	// TODO: add retry loop around WATCH if too many concurrent requests occur
 	// WATCH commands always return ok, so ignore value
-	_, err = conn.Do(redis.WATCH, lockKey)
+	_, err := conn.Do(redis.WATCH, lockKey)
 	if err != nil {
 		return types.Job{}, werrors.WrapMsgf(err, pkg.ErrFmtRedisWatchFailed, id)
 	}
@@ -159,38 +207,12 @@ func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Jo
 		return types.Job{}, werrors.WrapErr(err, pkg.ErrRetrieving)
 	}
 
-	// apply map to update the job
-	var jobMap map[string]interface{}
-	err = json.Unmarshal(data, &jobMap)
-	if err != nil {
-		return types.Job{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
-	}
-	// get the old owner to remove it if owner changed
-	oldOwner := jobMap[types.JobOwner].(string)
-
-	for key, value := range jobFields {
-		keys := strings.Split(key, ".")
-		err = updateHelper(&jobMap, keys, value)
-		if err != nil {
-			return types.Job{}, err
-		}
-	}
-
-	// marshal the new job back to a []byte
-	var updateJob types.Job
-	data, err = json.Marshal(jobMap)
+	update, err := applyJobFields(data, jobFields, caller, version)
 	if err != nil {
-		return types.Job{}, werrors.WrapErr(err, pkg.ErrMarshallingJob)
+		return types.Job{}, err
 	}
-
-	// unmarshal the job to verify that jobFields were set properly
-	err = json.Unmarshal(data, &updateJob)
-	if err != nil {
-		return types.Job{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
-	}
-
-	// set the last updated and marshal the job back
-	updateJob.SetLastUpdated()
+	oldJob, updateJob := update.oldJob, update.newJob
+	oldOwner := oldJob.Owner
 	data, err = json.Marshal(updateJob)
 	if err != nil {
 		return types.Job{}, werrors.WrapErr(err, pkg.ErrMarshallingJob)
@@ -206,8 +228,14 @@ func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Jo
 		conn.Send(redis.HDEL, oldOwnerKey, id)
 		conn.Send(redis.HSET, updateOwnerKey, id, "")
 	}
+	sendIndexUpdates(conn, id, &oldJob, &updateJob)
+	conn.Send(redis.RPUSH, redis.CreateKey(redis.KeyHistory, id), update.history)
 	conn.Send(redis.SET, lockKey, "")
 	reply, err := redigo.Values(conn.Do(redis.EXEC))
+	if err == redigo.ErrNil {
+		// a nil reply means the lock key was modified after WATCH
+		return types.Job{}, errTransactionAborted
+	}
 	if err != nil {
 		return types.Job{}, werrors.WrapErr(err, pkg.ErrUpdating)
 	}
@@ -218,7 +246,9 @@ func (rdb RedisDB) Update(id string, jobFields map[string]interface{}) (types.Jo
 	return updateJob, nil
 }
 
-// Delete deletes all entries associated with the given id
+// Delete deletes all entries associated with the given id.
+// The delete is retried if the job is modified concurrently, and pkg.ErrJobUpdateConflict is
+// returned if it can not be applied within maxTransactionAttempts.
 func (rdb RedisDB) Delete(id string) error {
 	if id == "" {
 		return pkg.ErrJobIdEmpty
@@ -227,8 +257,35 @@ func (rdb RedisDB) Delete(id string) error {
 	conn := rdb.redisClient.GetConnection()
 	defer func() { _ = conn.Close() }()
 
+	for attempt := 1; ; attempt++ {
+		err := rdb.delete(conn, id)
+		if err != errTransactionAborted {
+			return err
+		}
+		if attempt == maxTransactionAttempts {
+			rdb.lc.Errorf("delete of job %s aborted %d times by concurrent updates", id, attempt)
+			return pkg.ErrJobUpdateConflict
+		}
+		rdb.lc.Debugf("delete of job %s aborted by a concurrent update, retrying", id)
+		time.Sleep(transactionRetryDelay * time.Duration(attempt))
+	}
+}
+
+// delete applies a single attempt of Delete within a redis transaction watching the job lock key.
+// It returns errTransactionAborted if the transaction was aborted by a concurrent update.
+func (rdb RedisDB) delete(conn redigo.Conn, id string) error {
+	// use redis WATCH to lock the database before reading the job, so that an update in between aborts the delete
+	lockKey := redis.CreateKey(redis.KeyLock, redis.KeyJob, id)
+	// WATCH always returns ok, so ignore it.
+	_, err := conn.Do(redis.WATCH, lockKey)
+	if err != nil {
+		return werrors.WrapMsgf(err, pkg.ErrFmtRedisWatchFailed, id)
+	}
 	// get the job to determine owner to delete
-	job, err := rdb.GetById(id)
+	job, err := rdb.getJob(conn, id)
+	if err == redigo.ErrNil {
+		return werrors.WrapMsgf(err, pkg.ErrJobIdNotFound, id)
+	}
 	if err != nil {
 		return err
 	}
@@ -238,24 +295,28 @@ func (rdb RedisDB) Delete(id string) error {
 	if err != nil {
 		return err
 	}
-	// use redis WATCH to lock the database
-	lockKey := redis.CreateKey(redis.KeyLock, redis.KeyJob, id)
-	// TODO: add retry loop around WATCH if too many concurrent requests occur
-	// WATCH always returns ok, so ignore it.
-	_, err = conn.Do(redis.WATCH, lockKey)
-	if err != nil {
-		return werrors.WrapMsgf(err, pkg.ErrFmtRedisWatchFailed, id)
-	}
 	_ = conn.Send(redis.MULTI)
 	_ = conn.Send(redis.HDEL, redis.KeyJob, id)
 	_ = conn.Send(redis.HDEL, ownerKey, id)
 	_ = conn.Send(redis.HDEL, redis.KeyInputFile, inputFileField)
 	_ = conn.Send(redis.DEL, lockKey)
+	sendIndexUpdates(conn, id, &job, nil)
+	_ = conn.Send(redis.DEL, redis.CreateKey(redis.KeyHistory, id))
 	reply, err := redigo.Ints(conn.Do(redis.EXEC))
+	if err == redigo.ErrNil {
+		// a nil reply means the lock key was modified after WATCH
+		return errTransactionAborted
+	}
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
 	}
-	err = verifyReply(reply, 1)
+	// only the job, owner, input file and lock entries are verified as the query indexes
+	// of jobs persisted before the indexes were introduced may be incomplete
+	// and jobs that were never updated have no history
+	if len(reply) < deleteReplyCount {
+		return fmt.Errorf(pkg.ErrFmtJobDelete, id)
+	}
+	err = verifyReply(reply[:deleteReplyCount], 1)
 	if err != nil {
 		return werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, id)
 	}
@@ -356,6 +417,147 @@ func (rdb RedisDB) GetByOwner(owner string) ([]types.Job, error) {
 	return jobs, nil
 }
 
+// GetHistory retrieves the updates made to the job matching the id passed in, oldest first
+func (rdb RedisDB) GetHistory(id string) ([]types.JobHistoryEntry, error) {
+	if id == "" {
+		return nil, pkg.ErrJobIdEmpty
+	}
+	// get a connection to redis db
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	exists, err := redigo.Bool(conn.Do(redis.HEXISTS, redis.KeyJob, id))
+	if err != nil {
+		return nil, werrors.WrapMsgf(err, pkg.ErrFmtRetrieving, id)
+	}
+	if !exists {
+		return nil, fmt.Errorf(pkg.ErrJobIdNotFound, id)
+	}
+	result, err := redigo.ByteSlices(conn.Do(redis.LRANGE, redis.CreateKey(redis.KeyHistory, id), 0, -1))
+	if err != nil && err != redigo.ErrNil {
+		return nil, werrors.WrapErr(err, pkg.ErrRetrieving)
+	}
+	history := make([]types.JobHistoryEntry, len(result))
+	for i, entryJson := range result {
+		err = json.Unmarshal(entryJson, &history[i])
+		if err != nil {
+			return nil, werrors.WrapMsg(err, "failed to unmarshal job history")
+		}
+	}
+	return history, nil
+}
+
+// Query retrieves the jobs matching the filter ordered by last updated time, then by id.
+// It returns at most filter.Limit jobs and a cursor to retrieve the next page,
+// the cursor is empty when there are no more jobs.
+func (rdb RedisDB) Query(filter types.JobFilter) ([]types.Job, string, error) {
+	limit := queryLimit(filter.Limit)
+	var start queryPosition
+	var err error
+	if filter.Cursor != "" {
+		start, err = decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// get a connection to redis db
+	conn := rdb.redisClient.GetConnection()
+	defer func() { _ = conn.Close() }()
+
+	// narrow down the candidates using the equality indexes
+	var indexKeys []string
+	if filter.Status != "" {
+		indexKeys = append(indexKeys, redis.CreateKey(redis.KeyStatus, filter.Status))
+	}
+	if filter.Owner != "" {
+		indexKeys = append(indexKeys, redis.CreateKey(redis.KeyOwner, filter.Owner))
+	}
+	if filter.TaskId != "" {
+		indexKeys = append(indexKeys, redis.CreateKey(redis.KeyTaskId, filter.TaskId))
+	}
+	for name, value := range filter.Attributes {
+		indexKeys = append(indexKeys, redis.CreateKey(redis.KeyAttribute, name, value))
+	}
+	var candidates map[string]bool
+	for _, key := range indexKeys {
+		ids, err := redigo.Strings(conn.Do(redis.HKEYS, key))
+		if err != nil && err != redigo.ErrNil {
+			return nil, "", werrors.WrapErr(err, pkg.ErrRetrieving)
+		}
+		matches := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if candidates == nil || candidates[id] {
+				matches[id] = true
+			}
+		}
+		candidates = matches
+		if len(candidates) == 0 {
+			return []types.Job{}, "", nil
+		}
+	}
+
+	// walk the last updated index in order starting from the cursor
+	rangeMin := "-inf"
+	rangeMax := "+inf"
+	if filter.Since != 0 || filter.Cursor != "" {
+		minScore := scoreFromTime(filter.Since)
+		if start.Score > minScore {
+			minScore = start.Score
+		}
+		rangeMin = strconv.FormatInt(minScore, 10)
+	}
+	if filter.Until != 0 {
+		rangeMax = strconv.FormatInt(scoreFromTime(filter.Until), 10)
+	}
+	// the index is read in chunks so a page only reads the entries up to the last matching job
+	chunkSize := limit + 1
+	jobs := make([]types.Job, 0, limit)
+	var last queryPosition
+	for offset := 0; ; offset += chunkSize {
+		ordered, err := redigo.Strings(conn.Do(redis.ZRANGEBYSCORE, redis.KeyLastUpdated, rangeMin, rangeMax,
+			redis.WITHSCORES, redis.LIMIT, offset, chunkSize))
+		if err != nil && err != redigo.ErrNil {
+			return nil, "", werrors.WrapErr(err, pkg.ErrRetrieving)
+		}
+		for i := 0; i+1 < len(ordered); i += 2 {
+			score, err := strconv.ParseInt(ordered[i+1], 10, 64)
+			if err != nil {
+				return nil, "", werrors.WrapMsgf(err, "invalid last updated score for job %s", ordered[i])
+			}
+			position := queryPosition{Score: score, Id: ordered[i]}
+			if filter.Cursor != "" && !position.after(start) {
+				continue
+			}
+			if candidates != nil && !candidates[position.Id] {
+				continue
+			}
+			job, err := rdb.getJob(conn, position.Id)
+			if err == redigo.ErrNil {
+				// the job was deleted after the index was read
+				continue
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if !filter.Matches(job) {
+				continue
+			}
+			if len(jobs) == limit {
+				// there is at least one more job so return a cursor for the next page
+				return jobs, encodeCursor(last), nil
+			}
+			jobs = append(jobs, job)
+			last = position
+		}
+		if len(ordered) < 2*chunkSize {
+			// the end of the index was reached
+			break
+		}
+	}
+	return jobs, "", nil
+}
+
 func (rdb RedisDB) Disconnect() error {
 	return rdb.redisClient.Disconnect()
 }
@@ -375,37 +577,108 @@ func (rdb RedisDB) getFromHashByJobId(conn redigo.Conn, key string, value string
 	return "", fmt.Errorf(pkg.ErrJobIdNotFound, value)
 }
 
-// verifyReply is a helper function to check that each value of the slice is equal to the expected value
-func verifyReply(reply []int, expected int) error {
-	for _, val := range reply {
-		if val != expected {
-			return fmt.Errorf("unexpected value in reply, got %d expected %d", val, expected)
+// getJob is a helper function to retrieve and unmarshal a single job, it returns redigo.ErrNil if the job does not exist
+func (rdb RedisDB) getJob(conn redigo.Conn, id string) (types.Job, error) {
+	job := types.Job{}
+	data, err := redigo.Bytes(conn.Do(redis.HGET, redis.KeyJob, id))
+	if err == redigo.ErrNil {
+		return job, err
+	}
+	if err != nil {
+		return job, werrors.WrapErr(err, pkg.ErrRetrieving)
+	}
+	err = json.Unmarshal(data, &job)
+	if err != nil {
+		return job, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+	return job, nil
+}
+
+// indexExistingJobs populates the query indexes for the jobs persisted before the indexes were introduced.
+// The last updated index is used as the marker that the indexes have already been built.
+func (rdb RedisDB) indexExistingJobs(conn redigo.Conn) error {
+	exists, err := redigo.Bool(conn.Do(redis.EXISTS, redis.KeyLastUpdated))
+	if err != nil {
+		return werrors.WrapMsg(err, "failed to check for job query indexes")
+	}
+	if exists {
+		return nil
+	}
+	result, err := redigo.ByteSlices(conn.Do(redis.HVALS, redis.KeyJob))
+	if err != nil && err != redigo.ErrNil {
+		return werrors.WrapMsg(err, "failed to retrieve jobs to index")
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	_ = conn.Send(redis.MULTI)
+	for _, jobJson := range result {
+		job := types.Job{}
+		err = json.Unmarshal(jobJson, &job)
+		if err != nil {
+			rdb.lc.Errorf("skipping query indexes for job: %s", werrors.WrapErr(err, pkg.ErrUnmarshallingJob).Error())
+			continue
 		}
+		sendIndexUpdates(conn, job.Id, nil, &job)
+	}
+	_, err = conn.Do(redis.EXEC)
+	if err != nil {
+		return werrors.WrapMsg(err, "failed to build job query indexes")
 	}
+	rdb.lc.Infof("built query indexes for %d existing jobs", len(result))
 	return nil
 }
 
-// updateHelper is a recursive function that updates the value in the data passed in using the given keys to index the data
-func updateHelper(data *map[string]interface{}, keys []string, value interface{}) error {
-	if len(keys) > 1 {
-		subMap, ok := (*data)[keys[0]]
-		if !ok {
-			return fmt.Errorf("entry %s does not exist", strings.Join(keys, "."))
+// indexKeysForJob returns the hash keys of the status, task id and attribute indexes that reference the job.
+// The owner index is not included as it is maintained separately.
+func indexKeysForJob(job *types.Job) map[string]bool {
+	keys := make(map[string]bool)
+	if job == nil {
+		return keys
+	}
+	if job.Status != "" {
+		keys[redis.CreateKey(redis.KeyStatus, job.Status)] = true
+	}
+	for _, taskId := range job.TaskIds() {
+		keys[redis.CreateKey(redis.KeyTaskId, taskId)] = true
+	}
+	for name, value := range job.InputFile.Attributes {
+		keys[redis.CreateKey(redis.KeyAttribute, name, value)] = true
+	}
+	return keys
+}
+
+// sendIndexUpdates queues the commands that move the query index entries of the job with the given id
+// from the old job to the new job. A nil old job adds the entries and a nil new job removes them.
+// The indexes are hash keys job|status:{status}, job|task:{task id} and job|attribute:{name}:{value}
+// with the field {job.Id} and an empty string, and the sorted set job|last_updated with the member {job.Id}
+// scored by the last updated time in milliseconds.
+func sendIndexUpdates(conn redigo.Conn, id string, oldJob *types.Job, newJob *types.Job) {
+	oldKeys := indexKeysForJob(oldJob)
+	newKeys := indexKeysForJob(newJob)
+	for key := range oldKeys {
+		if !newKeys[key] {
+			_ = conn.Send(redis.HDEL, key, id)
 		}
-		actualMap, ok := subMap.(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("expected sub structure for key %s", keys[0])
+	}
+	for key := range newKeys {
+		if !oldKeys[key] {
+			_ = conn.Send(redis.HSET, key, id, "")
 		}
-		err := updateHelper(&actualMap, keys[1:], value)
-		return err
 	}
-
-	_, ok := (*data)[keys[0]]
-	if !ok {
-		return fmt.Errorf("entry %s does not exist", keys[0])
+	if newJob == nil {
+		_ = conn.Send(redis.ZREM, redis.KeyLastUpdated, id)
+		return
 	}
+	_ = conn.Send(redis.ZADD, redis.KeyLastUpdated, scoreFromTime(newJob.LastUpdated), id)
+}
 
-	(*data)[keys[0]] = value
-
+// verifyReply is a helper function to check that each value of the slice is equal to the expected value
+func verifyReply(reply []int, expected int) error {
+	for _, val := range reply {
+		if val != expected {
+			return fmt.Errorf("unexpected value in reply, got %d expected %d", val, expected)
+		}
+	}
 	return nil
 }
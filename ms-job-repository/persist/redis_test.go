@@ -34,6 +34,7 @@ func TestRedisDB_New(t *testing.T) {
 	mockConn := mocks.Conn{}
 	mockRedisClient.On("TestConnection").Return(&mockConn, nil)
 	mockConn.On("Close").Return(nil)
+	mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 	_, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
 	require.NoError(t, err)
 }
@@ -73,6 +74,7 @@ func TestRedisDB_Create(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 			mockConn.On("Do", redis.HGET, redis.KeyInputFile, mock.Anything).Return(test.ConnJobKey, test.ConnInputFileErr)
@@ -82,6 +84,8 @@ func TestRedisDB_Create(t *testing.T) {
 			mockConn.On("Send", redis.HSET, redis.KeyJob, mock.Anything, mock.Anything).Return(nil)
 			mockConn.On("Send", redis.HSET, redis.KeyInputFile, mock.Anything, mock.Anything).Return(nil)
 			mockConn.On("Send", redis.HSET, redis.CreateKey(redis.KeyOwner, pkg.OwnerDataOrg), mock.Anything, "").Return(nil)
+			mockConn.On("Send", redis.HSET, mock.Anything, mock.Anything, "").Return(nil).Maybe()
+			mockConn.On("Send", redis.ZADD, redis.KeyLastUpdated, mock.Anything, mock.Anything).Return(nil).Maybe()
 			mockConn.On("Do", redis.EXEC).Return(nil, test.ConnExecErr)
 			// create redisdb persistence instance
 			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
@@ -159,11 +163,11 @@ func TestRedisDB_Update(t *testing.T) {
 		{"job get failed", validJob.Id, changeStatusFields, []uint8("1"), nil, jobStr, redigo.ErrPoolExhausted, nil, false, expectedReply, nil, pkg.ErrRetrieving},
 		{"job watch error", validJob.Id, changeStatusFields, []uint8("1"), nil, jobStr, nil, redigo.ErrPoolExhausted, false, expectedReply, nil, fmt.Errorf(pkg.ErrFmtRedisWatchFailed, validJob.Id)},
 		{"job unmarshal failed", validJob.Id, changeStatusFields, []uint8("1"), nil, []uint8("bogus"), nil, nil, false, expectedReply, nil, pkg.ErrUnmarshallingJob},
-		{"job update helper bogus key", validJob.Id, bogusFields, []uint8("1"), nil, jobStr, nil, nil, false, expectedReply, nil, errors.New("entry bogus does not exist")},
-		{"job update helper nested bogus key", validJob.Id, nestBogusFields, []uint8("1"), nil, jobStr, nil, nil, false, expectedReply, nil, errors.New("entry bogus.bogus does not exist")},
+		{"job update helper bogus key", validJob.Id, bogusFields, []uint8("1"), nil, jobStr, nil, nil, false, expectedReply, nil, errors.New("bogus (unknown field)")},
+		{"job update helper nested bogus key", validJob.Id, nestBogusFields, []uint8("1"), nil, jobStr, nil, nil, false, expectedReply, nil, errors.New("bogus.bogus (unknown field)")},
 		{"change owner bad reply", validJob.Id, changeOwnerFields, []uint8("1"), nil, jobStr, nil, nil, true, badReply, nil, errors.New("unexpected value in reply")},
 		{"change owner reply fail", validJob.Id, changeOwnerFields, []uint8("1"), nil, jobStr, nil, nil, true, expectedReply, redigo.ErrPoolExhausted, pkg.ErrUpdating},
-		{"job fields is not marshal-able", validJob.Id, bogusLastUpdatedFields, []uint8("1"), nil, jobStr, nil, nil, true, nil, nil, pkg.ErrUnmarshallingJob},
+		{"job fields repository field", validJob.Id, bogusLastUpdatedFields, []uint8("1"), nil, jobStr, nil, nil, true, nil, nil, errors.New("LastUpdated (maintained by the job repository)")},
 	}
 
 	for _, test := range tests {
@@ -172,6 +176,7 @@ func TestRedisDB_Update(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 
@@ -187,13 +192,17 @@ func TestRedisDB_Update(t *testing.T) {
 				mockConn.On("Send", redis.HDEL, mock.Anything, test.Id).Return(nil)
 				mockConn.On("Send", redis.HSET, mock.Anything, test.Id, "").Return(nil)
 			}
+			mockConn.On("Send", redis.HDEL, mock.Anything, test.Id).Return(nil).Maybe()
+			mockConn.On("Send", redis.HSET, mock.Anything, test.Id, "").Return(nil).Maybe()
+			mockConn.On("Send", redis.ZADD, redis.KeyLastUpdated, mock.Anything, test.Id).Return(nil).Maybe()
+			mockConn.On("Send", redis.RPUSH, redis.CreateKey(redis.KeyHistory, test.Id), mock.Anything).Return(nil)
 			mockConn.On("Send", redis.SET, lockKey, "").Return(nil)
 			mockConn.On("Do", redis.EXEC).Return(test.MockExecReply, test.MockExecErr)
 
 			// create redisdb persistence instance
 			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
 			require.NoError(t, err)
-			actualJob, actualErr := persistence.Update(test.Id, test.JobFields)
+			actualJob, actualErr := persistence.Update(test.Id, test.JobFields, pkg.OwnerTaskLauncher, 0)
 			if test.ExpectedErr != nil {
 				require.NotNil(t, actualErr)
 				assert.Contains(t, actualErr.Error(), test.ExpectedErr.Error())
@@ -207,6 +216,62 @@ func TestRedisDB_Update(t *testing.T) {
 	}
 }
 
+func TestRedisDB_UpdateVersion(t *testing.T) {
+	validJob := helpers.CreateTestJob(pkg.OwnerDataOrg, Hostname)
+	validJob.Id = uuid.NewString()
+	validJob.Version = 3
+	jobStr, err := json.Marshal(validJob)
+	require.NoError(t, err)
+	jobFields := map[string]interface{}{types.JobStatus: pkg.StatusComplete}
+
+	tests := []struct {
+		Name            string
+		Version         int64
+		AbortedExecs    int
+		ExpectedVersion int64
+		ExpectedErr     error
+	}{
+		{"happy path - no version", 0, 0, 4, nil},
+		{"happy path - matching version", 3, 0, 4, nil},
+		{"happy path - retry after abort", 3, maxTransactionAttempts - 1, 4, nil},
+		{"version mismatch", 2, 0, 0, pkg.ErrJobVersionMismatch},
+		{"aborted too many times", 0, maxTransactionAttempts, 0, pkg.ErrJobUpdateConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			// set mocks
+			mockRedisClient := mocks.DBClient{}
+			mockConn := mocks.Conn{}
+			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockRedisClient.On("GetConnection").Return(&mockConn)
+			mockConn.On("Close").Return(nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
+			mockConn.On("Do", redis.HEXISTS, redis.KeyJob, validJob.Id).Return(int64(1), nil)
+			mockConn.On("Do", redis.HGET, redis.KeyJob, validJob.Id).Return(jobStr, nil)
+			mockConn.On("Do", redis.WATCH, mock.Anything).Return(nil, nil)
+			mockConn.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockConn.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockConn.On("Send", redis.MULTI).Return(nil)
+			if test.AbortedExecs > 0 {
+				mockConn.On("Do", redis.EXEC).Return(nil, nil).Times(test.AbortedExecs)
+			}
+			mockConn.On("Do", redis.EXEC).Return([]interface{}{"OK"}, nil)
+
+			// create redisdb persistence instance
+			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
+			require.NoError(t, err)
+			actualJob, actualErr := persistence.Update(validJob.Id, jobFields, pkg.OwnerTaskLauncher, test.Version)
+			if test.ExpectedErr != nil {
+				require.Equal(t, test.ExpectedErr, actualErr)
+				return
+			}
+			require.NoError(t, actualErr)
+			assert.Equal(t, test.ExpectedVersion, actualJob.Version)
+		})
+	}
+}
+
 func TestRedisDB_Delete(t *testing.T) {
 	var expectedSingleHash, expectedMultiHash, expectedBadHash, expectedReply, badReply []interface{}
 	job := helpers.CreateTestJob(pkg.OwnerFileSenderGateway, Hostname)
@@ -264,6 +329,7 @@ func TestRedisDB_Delete(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 			mockConn.On("Do", redis.HGET, redis.KeyJob, test.Id).Return(jobStr, test.GetJobErr)
@@ -274,6 +340,8 @@ func TestRedisDB_Delete(t *testing.T) {
 			mockConn.On("Send", redis.HDEL, ownerKey, test.Id).Return(nil)
 			mockConn.On("Send", redis.HDEL, redis.KeyInputFile, mock.Anything).Return(nil)
 			mockConn.On("Send", redis.DEL, mock.Anything).Return(nil)
+			mockConn.On("Send", redis.HDEL, mock.Anything, test.Id).Return(nil).Maybe()
+			mockConn.On("Send", redis.ZREM, redis.KeyLastUpdated, test.Id).Return(nil).Maybe()
 			mockConn.On("Do", redis.EXEC).Return(test.ExecReply, test.ExecErr)
 
 			// create redisdb persistence instance
@@ -287,6 +355,14 @@ func TestRedisDB_Delete(t *testing.T) {
 			require.NoError(t, actualErr)
 			mockRedisClient.AssertExpectations(t)
 			mockConn.AssertExpectations(t)
+			// the job is read after the lock key is watched so a concurrent update aborts the delete
+			var order []string
+			for _, call := range mockConn.Calls {
+				if call.Method == "Do" && (call.Arguments.Get(0) == redis.WATCH || call.Arguments.Get(0) == redis.HGET) {
+					order = append(order, call.Arguments.Get(0).(string))
+				}
+			}
+			assert.Equal(t, []string{redis.WATCH, redis.HGET}, order)
 		})
 	}
 }
@@ -331,6 +407,7 @@ func TestRedisDB_GetAll(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 			mockConn.On("Do", redis.HVALS, redis.KeyJob).Return(expectedJobs, test.ConnJobErr)
@@ -376,6 +453,7 @@ func TestRedisDB_GetById(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 			mockConn.On("Do", redis.HGET, redis.KeyJob, test.Id).Return(test.Job, test.ConnJobErr)
@@ -438,6 +516,7 @@ func TestRedisDB_GetByOwner(t *testing.T) {
 			mockRedisClient := mocks.DBClient{}
 			mockConn := mocks.Conn{}
 			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
 			mockRedisClient.On("GetConnection").Return(&mockConn)
 			mockConn.On("Close").Return(nil)
 			// build and set mocks for HGET
@@ -473,6 +552,224 @@ func TestRedisDB_GetByOwner(t *testing.T) {
 	}
 }
 
+func TestRedisDB_NewIndexesExistingJobs(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerDataOrg, Hostname)
+	expectedJobs, err := createInterfaceFromJobs([]types.Job{job})
+	require.NoError(t, err)
+
+	mockRedisClient := mocks.DBClient{}
+	mockConn := mocks.Conn{}
+	mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+	mockConn.On("Close").Return(nil)
+	mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(0), nil)
+	mockConn.On("Do", redis.HVALS, redis.KeyJob).Return(expectedJobs, nil)
+	mockConn.On("Send", redis.MULTI).Return(nil)
+	mockConn.On("Send", redis.HSET, redis.CreateKey(redis.KeyTaskId, job.PipelineDetails.TaskId), job.Id, "").Return(nil)
+	for name, value := range job.InputFile.Attributes {
+		mockConn.On("Send", redis.HSET, redis.CreateKey(redis.KeyAttribute, name, value), job.Id, "").Return(nil)
+	}
+	mockConn.On("Send", redis.ZADD, redis.KeyLastUpdated, scoreFromTime(job.LastUpdated), job.Id).Return(nil)
+	mockConn.On("Do", redis.EXEC).Return(nil, nil)
+
+	_, err = NewRedisDB(logger.MockLogger{}, &mockRedisClient)
+	require.NoError(t, err)
+	mockConn.AssertExpectations(t)
+}
+
+func TestRedisDB_GetHistory(t *testing.T) {
+	id := uuid.NewString()
+	entry := types.JobHistoryEntry{
+		Timestamp: 1,
+		Caller:    pkg.OwnerDataOrg,
+		OldOwner:  pkg.OwnerDataOrg,
+		NewOwner:  pkg.OwnerTaskLauncher,
+		Changes:   []types.FieldChange{{Path: types.JobOwner, OldValue: pkg.OwnerDataOrg, NewValue: pkg.OwnerTaskLauncher}},
+	}
+	entryStr, err := json.Marshal(entry)
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name            string
+		Id              string
+		MockExists      interface{}
+		MockExistsErr   error
+		MockHistory     []interface{}
+		MockHistoryErr  error
+		ExpectedHistory []types.JobHistoryEntry
+		ExpectedErr     error
+	}{
+		{"happy path", id, int64(1), nil, []interface{}{entryStr}, nil, []types.JobHistoryEntry{entry}, nil},
+		{"happy path - never updated", id, int64(1), nil, []interface{}{}, nil, []types.JobHistoryEntry{}, nil},
+		{"empty id", "", nil, nil, nil, nil, nil, pkg.ErrJobIdEmpty},
+		{"job does not exist", id, int64(0), nil, nil, nil, nil, fmt.Errorf(pkg.ErrJobIdNotFound, id)},
+		{"job exists connection error", id, nil, redigo.ErrPoolExhausted, nil, nil, nil, fmt.Errorf(pkg.ErrFmtRetrieving, id)},
+		{"history connection error", id, int64(1), nil, nil, redigo.ErrPoolExhausted, nil, pkg.ErrRetrieving},
+		{"history unmarshal failed", id, int64(1), nil, []interface{}{[]uint8("bogus")}, nil, nil, errors.New("failed to unmarshal job history")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			// set mocks
+			mockRedisClient := mocks.DBClient{}
+			mockConn := mocks.Conn{}
+			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockRedisClient.On("GetConnection").Return(&mockConn)
+			mockConn.On("Close").Return(nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
+			mockConn.On("Do", redis.HEXISTS, redis.KeyJob, test.Id).Return(test.MockExists, test.MockExistsErr)
+			mockConn.On("Do", redis.LRANGE, redis.CreateKey(redis.KeyHistory, test.Id), 0, -1).Return(test.MockHistory, test.MockHistoryErr)
+
+			// create redisdb persistence instance
+			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
+			require.NoError(t, err)
+			actualHistory, actualErr := persistence.GetHistory(test.Id)
+			if test.ExpectedErr != nil {
+				require.Error(t, actualErr)
+				assert.Contains(t, actualErr.Error(), test.ExpectedErr.Error())
+				return
+			}
+			require.NoError(t, actualErr)
+			assert.Equal(t, test.ExpectedHistory, actualHistory)
+		})
+	}
+}
+
+func TestRedisDB_createHistoryEntry(t *testing.T) {
+	job := helpers.CreateTestJob(pkg.OwnerTaskLauncher, Hostname)
+	jobStr, err := json.Marshal(job)
+	require.NoError(t, err)
+
+	oldValues := map[string]interface{}{
+		types.JobOwner:          pkg.OwnerDataOrg,
+		types.JobPipelineStatus: pkg.TaskStatusProcessing,
+		types.JobInputFileHost:  Hostname,
+	}
+	entry, err := createHistoryEntry(oldValues, jobStr, pkg.OwnerDataOrg, job, pkg.OwnerDataOrg)
+	require.NoError(t, err)
+	assert.Equal(t, job.LastUpdated, entry.Timestamp)
+	assert.Equal(t, pkg.OwnerDataOrg, entry.Caller)
+	assert.Equal(t, pkg.OwnerDataOrg, entry.OldOwner)
+	assert.Equal(t, pkg.OwnerTaskLauncher, entry.NewOwner)
+	// the unchanged hostname is not recorded and the changes are sorted by path
+	assert.Equal(t, []types.FieldChange{
+		{Path: types.JobOwner, OldValue: pkg.OwnerDataOrg, NewValue: pkg.OwnerTaskLauncher},
+		{Path: types.JobPipelineStatus, OldValue: pkg.TaskStatusProcessing, NewValue: pkg.TaskStatusComplete},
+	}, entry.Changes)
+}
+
+func TestRedisDB_Query(t *testing.T) {
+	first := helpers.CreateTestJob(pkg.OwnerDataOrg, Hostname)
+	first.LastUpdated = 1_000_000_000
+	first.Status = pkg.StatusIncomplete
+	second := helpers.CreateTestJob(pkg.OwnerTaskLauncher, Hostname)
+	second.Id = "2"
+	second.LastUpdated = 2_000_000_000
+	second.Status = pkg.StatusComplete
+	third := helpers.CreateTestJob(pkg.OwnerTaskLauncher, Hostname)
+	third.Id = "3"
+	third.LastUpdated = 2_000_000_000
+	third.Status = pkg.StatusComplete
+	third.InputFile.Attributes["LabName"] = "OtherLab"
+	allJobs := []types.Job{first, second, third}
+
+	// the last updated index entries as returned by ZRANGEBYSCORE WITHSCORES
+	var ordered []interface{}
+	for _, job := range allJobs {
+		ordered = append(ordered, []uint8(job.Id), []uint8(fmt.Sprint(scoreFromTime(job.LastUpdated))))
+	}
+	statusKey := redis.CreateKey(redis.KeyStatus, pkg.StatusComplete)
+	labKey := redis.CreateKey(redis.KeyAttribute, "LabName", first.InputFile.Attributes["LabName"])
+	secondCursor := encodeCursor(queryPosition{Score: scoreFromTime(second.LastUpdated), Id: second.Id})
+
+	tests := []struct {
+		Name           string
+		Filter         types.JobFilter
+		Indexes        map[string][]interface{}
+		RangeMin       string
+		RangeErr       error
+		ExpectedRanges int
+		ExpectedIds    []string
+		ExpectedCursor string
+		ExpectedErr    error
+	}{
+		{"happy path - no filter", types.JobFilter{}, nil, "-inf", nil, 1, []string{"1", "2", "3"}, "", nil},
+		{"happy path - status", types.JobFilter{Status: pkg.StatusComplete},
+			map[string][]interface{}{statusKey: {[]uint8("2"), []uint8("3")}}, "-inf", nil, 1, []string{"2", "3"}, "", nil},
+		{"happy path - status and attribute", types.JobFilter{Status: pkg.StatusComplete, Attributes: map[string]string{"LabName": first.InputFile.Attributes["LabName"]}},
+			map[string][]interface{}{statusKey: {[]uint8("2"), []uint8("3")}, labKey: {[]uint8("1"), []uint8("2")}}, "-inf", nil, 1, []string{"2"}, "", nil},
+		{"happy path - no index matches", types.JobFilter{Status: pkg.StatusComplete},
+			map[string][]interface{}{statusKey: {}}, "-inf", nil, 0, []string{}, "", nil},
+		{"happy path - since", types.JobFilter{Since: 1_500_000_000}, nil, "1500", nil, 1, []string{"2", "3"}, "", nil},
+		{"happy path - first page", types.JobFilter{Limit: 2}, nil, "-inf", nil, 1, []string{"1", "2"}, secondCursor, nil},
+		{"happy path - next page", types.JobFilter{Limit: 2, Cursor: secondCursor}, nil, "2000", nil, 1, []string{"3"}, "", nil},
+		{"happy path - page read in chunks", types.JobFilter{Limit: 1, Status: pkg.StatusComplete},
+			map[string][]interface{}{statusKey: {[]uint8("2"), []uint8("3")}}, "-inf", nil, 2, []string{"2"}, secondCursor, nil},
+		{"happy path - page stops reading the index", types.JobFilter{Limit: 1}, nil, "-inf", nil, 1, []string{"1"},
+			encodeCursor(queryPosition{Score: scoreFromTime(first.LastUpdated), Id: first.Id}), nil},
+		{"invalid cursor", types.JobFilter{Cursor: "bogus"}, nil, "-inf", nil, 0, nil, "", errors.New("invalid cursor")},
+		{"range connection error", types.JobFilter{}, nil, "-inf", redigo.ErrPoolExhausted, 1, nil, "", pkg.ErrRetrieving},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			// set mocks
+			mockRedisClient := mocks.DBClient{}
+			mockConn := mocks.Conn{}
+			mockRedisClient.On("TestConnection").Return(&mockConn, nil)
+			mockRedisClient.On("GetConnection").Return(&mockConn)
+			mockConn.On("Close").Return(nil)
+			mockConn.On("Do", redis.EXISTS, redis.KeyLastUpdated).Return(int64(1), nil)
+			for key, ids := range test.Indexes {
+				mockConn.On("Do", redis.HKEYS, key).Return(ids, nil)
+			}
+			// return the entries from the range minimum selected by LIMIT offset count
+			ranges := 0
+			limited := func(_ string, args ...interface{}) interface{} {
+				ranges++
+				var entries []interface{}
+				for i := 0; i < len(ordered); i += 2 {
+					if test.RangeMin == "-inf" || string(ordered[i+1].([]uint8)) >= test.RangeMin {
+						entries = append(entries, ordered[i], ordered[i+1])
+					}
+				}
+				offset, count := 2*args[5].(int), 2*args[6].(int)
+				if offset > len(entries) {
+					offset = len(entries)
+				}
+				if offset+count > len(entries) {
+					count = len(entries) - offset
+				}
+				return entries[offset : offset+count]
+			}
+			mockConn.On("Do", redis.ZRANGEBYSCORE, redis.KeyLastUpdated, test.RangeMin, "+inf", redis.WITHSCORES,
+				redis.LIMIT, mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(limited, test.RangeErr)
+			for _, job := range allJobs {
+				jobStr, err := json.Marshal(job)
+				require.NoError(t, err)
+				mockConn.On("Do", redis.HGET, redis.KeyJob, job.Id).Return(jobStr, nil)
+			}
+
+			// create redisdb persistence instance
+			persistence, err := NewRedisDB(logger.MockLogger{}, &mockRedisClient)
+			require.NoError(t, err)
+			actualJobs, actualCursor, actualErr := persistence.Query(test.Filter)
+			if test.ExpectedErr != nil {
+				require.Error(t, actualErr)
+				assert.Contains(t, actualErr.Error(), test.ExpectedErr.Error())
+				return
+			}
+			require.NoError(t, actualErr)
+			assert.Equal(t, test.ExpectedRanges, ranges)
+			actualIds := make([]string, len(actualJobs))
+			for i, job := range actualJobs {
+				actualIds[i] = job.Id
+			}
+			assert.Equal(t, test.ExpectedIds, actualIds)
+			assert.Equal(t, test.ExpectedCursor, actualCursor)
+		})
+	}
+}
+
 func TestRedisDB_updateHelper(t *testing.T) {
 	var value interface{}
 	var jobMap map[string]interface{}
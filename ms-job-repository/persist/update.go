@@ -0,0 +1,161 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// jobUpdate is an update applied to a stored job, shared by the persistence backends so they update jobs the same way
+type jobUpdate struct {
+	// oldJob is the stored job before the update
+	oldJob types.Job
+	// newJob is the job after the update
+	newJob types.Job
+	// history is the marshalled job history entry recording the changed fields
+	history []byte
+}
+
+// applyJobFields applies the field path values of an update to the stored job data. When version is not 0 the
+// update is only applied if the stored job is still at that version, otherwise pkg.ErrJobVersionMismatch is returned.
+// The updated job gets the last updated time, the next version and any owner or status transition.
+func applyJobFields(data []byte, jobFields map[string]interface{}, caller string, version int64) (jobUpdate, error) {
+	var update jobUpdate
+	err := json.Unmarshal(data, &update.oldJob)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+	if version != 0 && update.oldJob.Version != version {
+		return jobUpdate{}, pkg.ErrJobVersionMismatch
+	}
+	// marshal the stored job again so the fields added to the job since it was stored can be updated
+	data, err = json.Marshal(update.oldJob)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+
+	// apply map to update the job
+	var jobMap map[string]interface{}
+	err = json.Unmarshal(data, &jobMap)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+
+	// get the old values of the fields for the job history before they are updated
+	oldValues := make(map[string]interface{}, len(jobFields))
+	for key := range jobFields {
+		oldValues[key], _ = valueAtPath(jobMap, strings.Split(key, "."))
+	}
+	for key, value := range jobFields {
+		err = updateHelper(&jobMap, strings.Split(key, "."), value)
+		if err != nil {
+			return jobUpdate{}, err
+		}
+	}
+
+	// round trip through json to verify that jobFields were set properly
+	data, err = json.Marshal(jobMap)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+	err = json.Unmarshal(data, &update.newJob)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+
+	// set the last updated and next version and record any owner or status transition
+	update.newJob.SetLastUpdated()
+	update.newJob.Version = update.oldJob.Version + 1
+	// the transitions are maintained by the repository and their timestamps lose precision in the generic map
+	update.newJob.Transitions = update.oldJob.Transitions
+	update.newJob.RecordTransition()
+	data, err = json.Marshal(update.newJob)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapErr(err, pkg.ErrMarshallingJob)
+	}
+
+	historyEntry, err := createHistoryEntry(oldValues, data, update.oldJob.Owner, update.newJob, caller)
+	if err != nil {
+		return jobUpdate{}, err
+	}
+	update.history, err = json.Marshal(historyEntry)
+	if err != nil {
+		return jobUpdate{}, werrors.WrapMsg(err, "failed to marshal job history")
+	}
+	return update, nil
+}
+
+// createHistoryEntry builds the job history entry for an update given the field values before the update
+// and the marshalled job after the update. Only the fields whose value changed are recorded.
+func createHistoryEntry(oldValues map[string]interface{}, updatedData []byte, oldOwner string, updateJob types.Job, caller string) (types.JobHistoryEntry, error) {
+	var updatedMap map[string]interface{}
+	err := json.Unmarshal(updatedData, &updatedMap)
+	if err != nil {
+		return types.JobHistoryEntry{}, werrors.WrapErr(err, pkg.ErrUnmarshallingJob)
+	}
+	entry := types.JobHistoryEntry{
+		Timestamp: updateJob.LastUpdated,
+		Caller:    caller,
+		OldOwner:  oldOwner,
+		NewOwner:  updateJob.Owner,
+		Changes:   []types.FieldChange{},
+	}
+	for path, oldValue := range oldValues {
+		newValue, _ := valueAtPath(updatedMap, strings.Split(path, "."))
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		entry.Changes = append(entry.Changes, types.FieldChange{Path: path, OldValue: oldValue, NewValue: newValue})
+	}
+	sort.Slice(entry.Changes, func(i, j int) bool { return entry.Changes[i].Path < entry.Changes[j].Path })
+	return entry, nil
+}
+
+// valueAtPath returns the value in the data found by following the keys, and false if there is no such value
+func valueAtPath(data map[string]interface{}, keys []string) (interface{}, bool) {
+	value, ok := data[keys[0]]
+	if !ok || len(keys) == 1 {
+		return value, ok
+	}
+	subMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return valueAtPath(subMap, keys[1:])
+}
+
+// updateHelper is a recursive function that updates the value in the data passed in using the given keys to index the data
+func updateHelper(data *map[string]interface{}, keys []string, value interface{}) error {
+	if len(keys) > 1 {
+		subMap, ok := (*data)[keys[0]]
+		if !ok {
+			return fmt.Errorf("entry %s does not exist", strings.Join(keys, "."))
+		}
+		actualMap, ok := subMap.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected sub structure for key %s", keys[0])
+		}
+		err := updateHelper(&actualMap, keys[1:], value)
+		return err
+	}
+
+	_, ok := (*data)[keys[0]]
+	if !ok {
+		return fmt.Errorf("entry %s does not exist", keys[0])
+	}
+
+	(*data)[keys[0]] = value
+
+	return nil
+}
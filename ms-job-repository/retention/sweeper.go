@@ -0,0 +1,189 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+)
+
+// TerminalStatuses are the job statuses that can be purged since jobs in them are no longer processed
+var TerminalStatuses = []string{pkg.StatusComplete, pkg.StatusNoPipeline, pkg.StatusPipelineError, pkg.StatusFileError}
+
+const exportFileFmt = "jobs-%d.json"
+
+// Sweeper periodically purges the jobs in a terminal status that have not been updated within the retention age
+// for the status. Purged jobs are optionally exported to a file and their archive and viewable files removed.
+type Sweeper struct {
+	lc           logger.LoggingClient
+	persist      persist.Persistence
+	ages         map[string]time.Duration
+	exportFolder string
+	removeFiles  bool
+}
+
+// New creates a Sweeper for the given retention ages per job status, it returns an error if a status is not terminal
+func New(lc logger.LoggingClient, persist persist.Persistence, ages map[string]time.Duration, exportFolder string, removeFiles bool) (*Sweeper, error) {
+	for status, age := range ages {
+		if !isTerminal(status) {
+			return nil, fmt.Errorf(pkg.ErrFmtInvalidInput, status, fmt.Sprintf("one of the terminal job statuses %v", TerminalStatuses))
+		}
+		if age <= 0 {
+			return nil, fmt.Errorf(pkg.ErrFmtInvalidInput, age.String(), "positive retention age for status "+status)
+		}
+	}
+	if exportFolder != "" {
+		err := os.MkdirAll(exportFolder, pkg.FolderPermissions)
+		if err != nil {
+			return nil, werrors.WrapMsgf(err, "failed to create retention export folder %s", exportFolder)
+		}
+	}
+	return &Sweeper{
+		lc:           lc,
+		persist:      persist,
+		ages:         ages,
+		exportFolder: exportFolder,
+		removeFiles:  removeFiles,
+	}, nil
+}
+
+// Run purges the jobs every interval until the context is cancelled
+func (s *Sweeper) Run(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.Sweep(time.Now().UTC())
+			if err != nil {
+				s.lc.Errorf("retention sweep failed after purging %d jobs: %s", purged, err.Error())
+				continue
+			}
+			if purged > 0 {
+				s.lc.Infof("retention sweep purged %d jobs", purged)
+			}
+		}
+	}
+}
+
+// Sweep purges the jobs that are past their retention age at the given time and returns the number of jobs purged.
+// Jobs are deleted through the persistence so the owner and input file indexes are kept consistent.
+func (s *Sweeper) Sweep(now time.Time) (int, error) {
+	var exportFile *os.File
+	var encoder *json.Encoder
+	if s.exportFolder != "" {
+		exportName := filepath.Join(s.exportFolder, fmt.Sprintf(exportFileFmt, now.UnixNano()))
+		var err error
+		exportFile, err = os.OpenFile(exportName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, pkg.FilePermissions)
+		if err != nil {
+			return 0, werrors.WrapMsgf(err, "failed to open retention export file %s", exportName)
+		}
+		defer s.closeExport(exportFile)
+		encoder = json.NewEncoder(exportFile)
+	}
+
+	// sort the statuses so the sweep order is predictable
+	statuses := make([]string, 0, len(s.ages))
+	for status := range s.ages {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	purged := 0
+	for _, status := range statuses {
+		filter := types.JobFilter{
+			Status: status,
+			Until:  now.Add(-s.ages[status]).UnixNano(),
+			Limit:  persist.MaxQueryLimit,
+		}
+		for {
+			jobs, cursor, err := s.persist.Query(filter)
+			if err != nil {
+				return purged, werrors.WrapMsgf(err, "failed to query %s jobs to purge", status)
+			}
+			for _, job := range jobs {
+				if encoder != nil {
+					err = encoder.Encode(job)
+					if err != nil {
+						return purged, werrors.WrapMsgf(err, "failed to export job %s", job.Id)
+					}
+				}
+				err = s.persist.Delete(job.Id)
+				if err != nil {
+					return purged, werrors.WrapMsgf(err, pkg.ErrFmtJobDelete, job.Id)
+				}
+				if s.removeFiles {
+					s.removeJobFiles(job)
+				}
+				purged++
+			}
+			if cursor == "" {
+				break
+			}
+			filter.Cursor = cursor
+		}
+	}
+	return purged, nil
+}
+
+// removeJobFiles removes the archive and viewable files of the input file of the job and of the output files of
+// its pipeline, of each of its pipeline runs and of each of its pipeline stages.
+// Errors are logged rather than returned since the job has already been deleted.
+func (s *Sweeper) removeJobFiles(job types.Job) {
+	files := []string{job.InputFile.ArchiveName, job.InputFile.Viewable}
+	pipelines := append([]types.PipelineInfo{job.PipelineDetails}, job.PipelineRuns...)
+	pipelines = append(pipelines, job.PipelineStages...)
+	for _, pipeline := range pipelines {
+		for _, outputFile := range pipeline.OutputFiles {
+			files = append(files, outputFile.ArchiveName, outputFile.Viewable)
+		}
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		err := os.Remove(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.lc.Errorf("failed to remove file %s of purged job %s: %s", file, job.Id, err.Error())
+		}
+	}
+}
+
+// closeExport closes the export file and removes it if no jobs were exported
+func (s *Sweeper) closeExport(exportFile *os.File) {
+	info, err := exportFile.Stat()
+	_ = exportFile.Close()
+	if err == nil && info.Size() == 0 {
+		_ = os.Remove(exportFile.Name())
+	}
+}
+
+// isTerminal checks if the job status is one of the TerminalStatuses
+func isTerminal(status string) bool {
+	for _, terminal := range TerminalStatuses {
+		if status == terminal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fileHostname = "gateway"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Ages        map[string]time.Duration
+		ExpectedErr bool
+	}{
+		{"happy path", map[string]time.Duration{pkg.StatusComplete: time.Hour, pkg.StatusFileError: time.Minute}, false},
+		{"happy path - no ages", map[string]time.Duration{}, false},
+		{"non terminal status", map[string]time.Duration{pkg.StatusIncomplete: time.Hour}, true},
+		{"non positive age", map[string]time.Duration{pkg.StatusComplete: 0}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := New(logger.MockLogger{}, &persistMocks.Persistence{}, test.Ages, "", false)
+			if test.ExpectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	ages := map[string]time.Duration{pkg.StatusComplete: time.Hour, pkg.StatusPipelineError: 24 * time.Hour}
+	completeFilter := types.JobFilter{Status: pkg.StatusComplete, Until: now.Add(-time.Hour).UnixNano(), Limit: persist.MaxQueryLimit}
+	completeNextFilter := completeFilter
+	completeNextFilter.Cursor = "next"
+	errorFilter := types.JobFilter{Status: pkg.StatusPipelineError, Until: now.Add(-24 * time.Hour).UnixNano(), Limit: persist.MaxQueryLimit}
+
+	first := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	second := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	second.Id = "2"
+	third := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	third.Id = "3"
+
+	tests := []struct {
+		Name           string
+		Export         bool
+		RemoveFiles    bool
+		QueryErr       error
+		DeleteErr      error
+		ExpectedPurged int
+		ExpectedErr    bool
+	}{
+		{"happy path - delete", false, false, nil, nil, 3, false},
+		{"happy path - export", true, false, nil, nil, 3, false},
+		{"happy path - remove files", false, true, nil, nil, 3, false},
+		{"query error", false, false, errors.New("query failed"), nil, 0, true},
+		{"delete error", false, false, nil, errors.New("delete failed"), 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			jobs := []types.Job{first, second, third}
+			var files []string
+			if test.RemoveFiles {
+				outputFile := func(name string) types.OutputFile {
+					return types.OutputFile{ArchiveName: filepath.Join(tempDir, name+"-archive.tiff"), Viewable: filepath.Join(tempDir, name+"-viewable.jpeg")}
+				}
+				for i := range jobs {
+					jobs[i].InputFile.ArchiveName = filepath.Join(tempDir, jobs[i].Id+"-archive.tiff")
+					jobs[i].InputFile.Viewable = filepath.Join(tempDir, jobs[i].Id+"-viewable.jpeg")
+					files = append(files, jobs[i].InputFile.ArchiveName, jobs[i].InputFile.Viewable)
+				}
+				// the output files of the pipeline of a job, of the runs of a fanned out job and of the stages of a chained job
+				jobs[0].PipelineDetails.OutputFiles = []types.OutputFile{outputFile("1-output")}
+				jobs[1].PipelineRuns = []types.PipelineInfo{{TaskId: "1", OutputFiles: []types.OutputFile{outputFile("2-run-1")}},
+					{TaskId: "2", OutputFiles: []types.OutputFile{outputFile("2-run-2")}}}
+				jobs[2].PipelineStages = []types.PipelineInfo{{TaskId: "1", OutputFiles: []types.OutputFile{outputFile("3-stage-1")}}}
+				for _, name := range []string{"1-output", "2-run-1", "2-run-2", "3-stage-1"} {
+					files = append(files, outputFile(name).ArchiveName, outputFile(name).Viewable)
+				}
+				for _, file := range files {
+					require.NoError(t, os.WriteFile(file, []byte("file"), pkg.FilePermissions))
+				}
+			}
+			exportFolder := ""
+			if test.Export {
+				exportFolder = filepath.Join(tempDir, "export")
+			}
+
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("Query", completeFilter).Return(jobs[:2], "next", test.QueryErr)
+			persistMock.On("Query", completeNextFilter).Return(jobs[2:], "", nil)
+			persistMock.On("Query", errorFilter).Return([]types.Job{}, "", nil)
+			persistMock.On("Delete", first.Id).Return(test.DeleteErr)
+			persistMock.On("Delete", second.Id).Return(nil)
+			persistMock.On("Delete", third.Id).Return(nil)
+
+			sweeper, err := New(logger.MockLogger{}, &persistMock, ages, exportFolder, test.RemoveFiles)
+			require.NoError(t, err)
+			purged, err := sweeper.Sweep(now)
+			assert.Equal(t, test.ExpectedPurged, purged)
+			if test.ExpectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			persistMock.AssertExpectations(t)
+
+			for _, file := range files {
+				exists, err := helpers.Exists(file)
+				require.NoError(t, err)
+				assert.False(t, exists, file)
+			}
+			if test.Export {
+				exportFiles, err := os.ReadDir(exportFolder)
+				require.NoError(t, err)
+				require.Len(t, exportFiles, 1)
+				exportFile, err := os.Open(filepath.Join(exportFolder, exportFiles[0].Name()))
+				require.NoError(t, err)
+				defer exportFile.Close()
+				var exported []string
+				scanner := bufio.NewScanner(exportFile)
+				for scanner.Scan() {
+					var job types.Job
+					require.NoError(t, json.Unmarshal(scanner.Bytes(), &job))
+					exported = append(exported, job.Id)
+				}
+				assert.Equal(t, []string{first.Id, second.Id, third.Id}, exported)
+			}
+		})
+	}
+}
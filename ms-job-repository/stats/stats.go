@@ -0,0 +1,110 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"aicsd/ms-job-repository/persist"
+	"aicsd/pkg"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+)
+
+// Collect queries the jobs last updated within the window ending at until and computes their statistics
+func Collect(persistence persist.Persistence, until time.Time, window time.Duration) (types.JobStats, error) {
+	since := until.Add(-window)
+	filter := types.JobFilter{
+		Since: since.UnixNano(),
+		Until: until.UnixNano(),
+		Limit: persist.MaxQueryLimit,
+	}
+	var jobs []types.Job
+	for {
+		page, cursor, err := persistence.Query(filter)
+		if err != nil {
+			return types.JobStats{}, werrors.WrapMsg(err, "failed to query jobs for statistics")
+		}
+		jobs = append(jobs, page...)
+		if cursor == "" {
+			break
+		}
+		filter.Cursor = cursor
+	}
+	return Compute(jobs, since, until), nil
+}
+
+// Compute returns the statistics of the jobs within the window from since to until.
+// The stage durations and throughput are derived from the job transitions, so jobs created before the
+// transitions were recorded are only counted by status and owner.
+func Compute(jobs []types.Job, since time.Time, until time.Time) types.JobStats {
+	stats := types.JobStats{
+		Since:          since.UnixNano(),
+		Until:          until.UnixNano(),
+		Total:          len(jobs),
+		StatusCounts:   make(map[string]int),
+		OwnerCounts:    make(map[string]int),
+		StageDurations: make(map[string]types.StageDuration),
+	}
+
+	// one bucket for each hour overlapping the window
+	firstHour := since.Truncate(time.Hour)
+	hours := int(until.Sub(firstHour)/time.Hour) + 1
+	stats.Throughput = make([]types.HourlyThroughput, hours)
+	for i := range stats.Throughput {
+		stats.Throughput[i].Hour = firstHour.Add(time.Duration(i) * time.Hour).UnixNano()
+	}
+
+	durations := make(map[string][]int64)
+	for _, job := range jobs {
+		stats.StatusCounts[job.Status]++
+		stats.OwnerCounts[job.Owner]++
+
+		stageStart := 0
+		for i, transition := range job.Transitions {
+			if transition.Status == pkg.StatusComplete && (i == 0 || job.Transitions[i-1].Status != pkg.StatusComplete) &&
+				inWindow(transition.Timestamp, stats) {
+				hour := int(time.Duration(transition.Timestamp-stats.Throughput[0].Hour) / time.Hour)
+				stats.Throughput[hour].Completed++
+			}
+			if i == 0 || transition.Owner == job.Transitions[stageStart].Owner {
+				continue
+			}
+			// the owner changed so the previous stage ended
+			if inWindow(transition.Timestamp, stats) {
+				owner := job.Transitions[stageStart].Owner
+				durations[owner] = append(durations[owner], transition.Timestamp-job.Transitions[stageStart].Timestamp)
+			}
+			stageStart = i
+		}
+	}
+
+	for owner, ownerDurations := range durations {
+		sort.Slice(ownerDurations, func(i, j int) bool { return ownerDurations[i] < ownerDurations[j] })
+		stats.StageDurations[owner] = types.StageDuration{
+			Count: len(ownerDurations),
+			P50:   percentile(ownerDurations, 0.5),
+			P95:   percentile(ownerDurations, 0.95),
+		}
+	}
+	return stats
+}
+
+// inWindow checks if the timestamp is within the window of the stats
+func inWindow(timestamp int64, stats types.JobStats) bool {
+	return timestamp >= stats.Since && timestamp <= stats.Until
+}
+
+// percentile returns the nearest rank percentile of the sorted values
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,118 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package stats
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"aicsd/ms-job-repository/persist"
+	persistMocks "aicsd/ms-job-repository/persist/mocks"
+	"aicsd/pkg"
+	"aicsd/pkg/helpers"
+	"aicsd/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fileHostname = "gateway"
+
+// createStatsJob creates a completed job that spent the given durations with the data organizer, the task launcher
+// and the file sender starting at the start time
+func createStatsJob(id string, start time.Time, dataOrg, taskLauncher, fileSender time.Duration) types.Job {
+	job := helpers.CreateTestJob(pkg.OwnerNone, fileHostname)
+	job.Id = id
+	job.Status = pkg.StatusComplete
+	taskLauncherStart := start.Add(dataOrg)
+	fileSenderStart := taskLauncherStart.Add(taskLauncher)
+	end := fileSenderStart.Add(fileSender)
+	job.Transitions = []types.Transition{
+		{Owner: pkg.OwnerDataOrg, Status: pkg.StatusIncomplete, Timestamp: start.UnixNano()},
+		{Owner: pkg.OwnerTaskLauncher, Status: pkg.StatusIncomplete, Timestamp: taskLauncherStart.UnixNano()},
+		// a status change within a stage does not end the stage
+		{Owner: pkg.OwnerTaskLauncher, Status: pkg.StatusPipelineError, Timestamp: taskLauncherStart.Add(taskLauncher / 2).UnixNano()},
+		{Owner: pkg.OwnerFileSenderGateway, Status: pkg.StatusIncomplete, Timestamp: fileSenderStart.UnixNano()},
+		{Owner: pkg.OwnerNone, Status: pkg.StatusComplete, Timestamp: end.UnixNano()},
+	}
+	job.LastUpdated = end.UnixNano()
+	return job
+}
+
+func TestCompute(t *testing.T) {
+	until := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)
+	since := until.Add(-2 * time.Hour)
+
+	var jobs []types.Job
+	for i := 1; i <= 20; i++ {
+		start := since.Add(time.Duration(i) * time.Minute)
+		jobs = append(jobs, createStatsJob(strconv.Itoa(i), start, time.Duration(i)*time.Second, time.Minute, time.Second))
+	}
+	// a job completed within the last hour
+	jobs = append(jobs, createStatsJob("21", until.Add(-10*time.Minute), time.Second, 2*time.Minute, time.Second))
+	// a job whose stages ended before the window is only counted by status and owner
+	jobs = append(jobs, createStatsJob("22", since.Add(-time.Hour), time.Second, time.Second, time.Second))
+	// a job without transitions
+	inProgress := helpers.CreateTestJob(pkg.OwnerTaskLauncher, fileHostname)
+	inProgress.Status = pkg.StatusIncomplete
+	jobs = append(jobs, inProgress)
+
+	stats := Compute(jobs, since, until)
+	assert.Equal(t, since.UnixNano(), stats.Since)
+	assert.Equal(t, until.UnixNano(), stats.Until)
+	assert.Equal(t, 23, stats.Total)
+	assert.Equal(t, map[string]int{pkg.StatusComplete: 22, pkg.StatusIncomplete: 1}, stats.StatusCounts)
+	assert.Equal(t, map[string]int{pkg.OwnerNone: 22, pkg.OwnerTaskLauncher: 1}, stats.OwnerCounts)
+	assert.Equal(t, []types.HourlyThroughput{
+		{Hour: time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC).UnixNano(), Completed: 20},
+		{Hour: time.Date(2023, 6, 1, 11, 0, 0, 0, time.UTC).UnixNano(), Completed: 0},
+		{Hour: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano(), Completed: 1},
+	}, stats.Throughput)
+	assert.Equal(t, map[string]types.StageDuration{
+		pkg.OwnerDataOrg:           {Count: 21, P50: int64(10 * time.Second), P95: int64(19 * time.Second)},
+		pkg.OwnerTaskLauncher:      {Count: 21, P50: int64(time.Minute), P95: int64(time.Minute)},
+		pkg.OwnerFileSenderGateway: {Count: 21, P50: int64(time.Second), P95: int64(time.Second)},
+	}, stats.StageDurations)
+}
+
+func TestCollect(t *testing.T) {
+	until := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	filter := types.JobFilter{Since: until.Add(-time.Hour).UnixNano(), Until: until.UnixNano(), Limit: persist.MaxQueryLimit}
+	nextFilter := filter
+	nextFilter.Cursor = "next"
+	first := createStatsJob("1", until.Add(-30*time.Minute), time.Second, time.Second, time.Second)
+	second := createStatsJob("2", until.Add(-20*time.Minute), time.Second, time.Second, time.Second)
+
+	tests := []struct {
+		Name          string
+		QueryErr      error
+		ExpectedTotal int
+		ExpectedErr   bool
+	}{
+		{"happy path", nil, 2, false},
+		{"query error", errors.New("query failed"), 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			persistMock := persistMocks.Persistence{}
+			persistMock.On("Query", filter).Return([]types.Job{first}, "next", test.QueryErr)
+			persistMock.On("Query", nextFilter).Return([]types.Job{second}, "", nil)
+
+			stats, err := Collect(&persistMock, until, time.Hour)
+			if test.ExpectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedTotal, stats.Total)
+			assert.Equal(t, 2, stats.StatusCounts[pkg.StatusComplete])
+			persistMock.AssertExpectations(t)
+		})
+	}
+}
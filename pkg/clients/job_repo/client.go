@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"aicsd/pkg"
@@ -213,6 +215,17 @@ func (c *RepoClient) RetrieveById(id string) (types.Job, error) {
 // Update is used to put updated values for a job.
 // It returns an error if the http request fails.
 func (c *RepoClient) Update(id string, jobFields map[string]interface{}) (types.Job, error) {
+	return c.update(id, jobFields, 0)
+}
+
+// UpdateVersion is used to put updated values for a job only if the job is still at the given version.
+// It returns pkg.ErrJobVersionMismatch if the job has changed since, or an error if the http request fails.
+func (c *RepoClient) UpdateVersion(id string, jobFields map[string]interface{}, version int64) (types.Job, error) {
+	return c.update(id, jobFields, version)
+}
+
+// update puts the updated values for a job, with the version in the If-Match header unless it is 0
+func (c *RepoClient) update(id string, jobFields map[string]interface{}, version int64) (types.Job, error) {
 	var job types.Job
 	jobIdUrl := fmt.Sprintf("%s/%s", c.jobUrl, id)
 
@@ -224,6 +237,11 @@ func (c *RepoClient) Update(id string, jobFields map[string]interface{}) (types.
 	if err != nil {
 		return job, fmt.Errorf("job repo update job new request error: %s", err.Error())
 	}
+	// identify the calling service for the job history
+	req.Header.Set(pkg.HeaderCaller, filepath.Base(os.Args[0]))
+	if version != 0 {
+		req.Header.Set(pkg.HeaderIfMatch, fmt.Sprintf(`"%d"`, version))
+	}
 
 	err = c.jwtInfo.AddAuthHeader(req)
 	if err != nil {
@@ -237,6 +255,9 @@ func (c *RepoClient) Update(id string, jobFields map[string]interface{}) (types.
 		return job, fmt.Errorf("job repo update job do request error: %s", err.Error())
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return job, pkg.ErrJobVersionMismatch
+	}
 	if resp.StatusCode != http.StatusOK {
 		return job, fmt.Errorf("job repo update job status not OK for id %s: %s", id, resp.Status)
 	}
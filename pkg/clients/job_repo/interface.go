@@ -15,5 +15,6 @@ type Client interface {
 	RetrieveAllByOwner(owner string) ([]types.Job, error)
 	RetrieveById(id string) (types.Job, error)
 	Update(id string, jobFields map[string]interface{}) (types.Job, error)
+	UpdateVersion(id string, jobFields map[string]interface{}, version int64) (types.Job, error)
 	Delete(id string) error
 }
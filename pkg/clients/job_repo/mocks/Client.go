@@ -158,6 +158,30 @@ func (_m *Client) Update(id string, jobFields map[string]interface{}) (types.Job
 	return r0, r1
 }
 
+// UpdateVersion provides a mock function with given fields: id, jobFields, version
+func (_m *Client) UpdateVersion(id string, jobFields map[string]interface{}, version int64) (types.Job, error) {
+	ret := _m.Called(id, jobFields, version)
+
+	var r0 types.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}, int64) (types.Job, error)); ok {
+		return rf(id, jobFields, version)
+	}
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}, int64) types.Job); ok {
+		r0 = rf(id, jobFields, version)
+	} else {
+		r0 = ret.Get(0).(types.Job)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, map[string]interface{}, int64) error); ok {
+		r1 = rf(id, jobFields, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewClient interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,71 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package pipeline
+
+import (
+	"aicsd/pkg"
+	"aicsd/pkg/auth"
+	"aicsd/pkg/types"
+	"aicsd/pkg/werrors"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type PipelineClient struct {
+	pipelinesUrl string
+	httpTimeout  time.Duration
+	jwtInfo      *auth.JWTInfo
+}
+
+// NewClient builds a new client of the pipeline service at the passed in base url
+func NewClient(baseUrl string, httpTimeout time.Duration, info *auth.JWTInfo) Client {
+	client := PipelineClient{
+		pipelinesUrl: fmt.Sprintf("%s%s", baseUrl, pkg.EndpointGetPipelines),
+		httpTimeout:  httpTimeout,
+		jwtInfo:      info,
+	}
+	return &client
+}
+
+// GetPipelines retrieves the pipelines advertised by the pipeline service.
+// It returns an error if the http request fails.
+func (c *PipelineClient) GetPipelines() ([]types.Pipeline, error) {
+	req, err := http.NewRequest(http.MethodGet, c.pipelinesUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request to get pipelines: %s", err.Error())
+	}
+	err = c.jwtInfo.AddAuthHeader(req)
+	if err != nil {
+		return nil, werrors.WrapErr(err, pkg.ErrAuthHeader)
+	}
+	client := &http.Client{
+		Timeout: c.httpTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get pipelines %s request error: %s", c.pipelinesUrl, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get pipelines %s status not OK: %s", c.pipelinesUrl, resp.Status)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("get pipelines %s read error: %s", c.pipelinesUrl, err.Error())
+	}
+
+	var pipelines []types.Pipeline
+	err = json.Unmarshal(respBody, &pipelines)
+	if err != nil {
+		return nil, fmt.Errorf("get pipelines %s unmarshal error: %s", c.pipelinesUrl, err.Error())
+	}
+	return pipelines, nil
+}
@@ -0,0 +1,12 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package pipeline
+
+import "aicsd/pkg/types"
+
+type Client interface {
+	GetPipelines() ([]types.Pipeline, error)
+}
@@ -19,6 +19,16 @@ const (
 	HDEL    = "HDEL"
 	EXEC    = "EXEC"
 	WATCH   = "WATCH"
+	EXISTS  = "EXISTS"
+	HMGET   = "HMGET"
+	ZADD    = "ZADD"
+	ZREM    = "ZREM"
+	RPUSH   = "RPUSH"
+	LRANGE  = "LRANGE"
+
+	ZRANGEBYSCORE = "ZRANGEBYSCORE"
+	WITHSCORES    = "WITHSCORES"
+	LIMIT         = "LIMIT"
 )
 
 const (
@@ -27,5 +37,12 @@ const (
 	KeyInputFile   = "job|input_file"
 	KeyLock        = "lock"
 	KeyOwner       = "job|owner"
+	KeyStatus      = "job|status"
+	KeyTaskId      = "job|task"
+	KeyAttribute   = "job|attribute"
+	KeyLastUpdated = "job|last_updated"
+	KeyHistory     = "job|history"
 	KeyTask        = "task"
+	KeyTaskVersion = "task|version"
+	KeyDispatch    = "task|dispatch"
 )
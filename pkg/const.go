@@ -13,6 +13,18 @@ const (
 	FileIdKey   = "fileid"
 	FilenameKey = "filename"
 	OwnerKey    = "owner"
+	VersionKey  = "version"
+	// REST query keys
+	QueryStatusKey     = "status"
+	QueryOwnerKey      = "owner"
+	QueryTaskIdKey     = "taskId"
+	QuerySinceKey      = "since"
+	QueryUntilKey      = "until"
+	QueryLimitKey      = "limit"
+	QueryCursorKey     = "cursor"
+	QueryAttributesKey = "attr."
+	QueryWindowKey     = "window"
+	QueryFormatKey     = "format"
 	// MQTT key
 	PublishTopicKey = "publish-topic"
 	CustomTopicKey  = "custom-topic"
@@ -25,14 +37,27 @@ const (
 	EndpointJobId        = "/api/v1/job/{" + JobIdKey + "}"
 	EndpointJobOwner     = "/api/v1/job/owner/{" + OwnerKey + "}"
 	EndpointJobPipeline  = "/api/v1/job/pipeline/{" + JobIdKey + "}/{" + TaskIdKey + "}"
+	EndpointJobHistory   = "/api/v1/job/{" + JobIdKey + "}/history"
+	EndpointJobStats     = "/api/v1/job/stats"
+	EndpointJobReview    = "/api/v1/job/review"
+	EndpointJobAccept    = "/api/v1/job/{" + JobIdKey + "}/accept"
+	EndpointJobReject    = "/api/v1/job/{" + JobIdKey + "}/reject"
+	// EndpointJobEvents and EndpointJobExport are served on the job repository EventsPort rather than the service port
+	EndpointJobExport = "/api/v1/job/export"
+	EndpointJobEvents = "/api/v1/job/events"
 
 	// TODO: implement update for job results as PUT
 	// EndpointJobResults = "/api/v1/job/results/{" + JobIdKey + "}"
 	EndpointMatchTask         = "/api/v1/matchTask"
+	EndpointMatchTaskExplain  = "/api/v1/matchTask/explain"
 	EndpointNotifyNewFile     = "/api/v1/notifyNewFile"
 	EndpointPipelineStatus    = "/api/v1/pipelineStatus/{" + JobIdKey + "}/{" + TaskIdKey + "}"
 	EndpointTask              = "/api/v1/task"
 	EndpointTaskId            = "/api/v1/task/{" + TaskIdKey + "}"
+	EndpointTaskVersions      = "/api/v1/task/{" + TaskIdKey + "}/versions"
+	EndpointTaskRollback      = "/api/v1/task/{" + TaskIdKey + "}/rollback/{" + VersionKey + "}"
+	EndpointQueue             = "/api/v1/queue"
+	EndpointWatchedFolders    = "/api/v1/watchedFolders"
 	EndpointTransmitJob       = "/api/v1/transmitJob"
 	EndpointTransmitFile      = "/api/v1/transmitFile"
 	EndpointTransmitFileJobId = "/api/v1/transmitFile/{" + JobIdKey + "}/{" + FileIdKey + "}"
@@ -55,6 +80,13 @@ const (
 	StatusFileError          = "FileErrored"        // An output file error occurred
 )
 
+// Job Verification
+const (
+	VerificationPending  = 0
+	VerificationAccepted = 1
+	VerificationRejected = 2
+)
+
 // File Status
 const (
 	FileStatusComplete           = "FileComplete"
@@ -84,6 +116,9 @@ const (
 	TaskStatusProcessing   = "PipelineProcessing"
 	TaskStatusFailed       = "PipelineFailed"
 	TaskStatusFileNotFound = "FileNotFound"
+	// TaskStatusTimedOut is set by the task launcher when the pipeline did not report the status of the job
+	// after the job was published the maximum number of times
+	TaskStatusTimedOut = "PipelineTimedOut"
 )
 
 // File specifications for writing a file
@@ -99,6 +134,12 @@ const (
 	DatabasePath    = "redisdb"
 )
 
+// Persistence backends selected with the PersistenceType app setting
+const (
+	PersistenceTypeRedis = "redis"
+	PersistenceTypeBolt  = "bolt"
+)
+
 // Loop up to 5 output files for the pipeline sim and integration tests
 const (
 	LoopForMultiOutputFiles = 5
@@ -108,4 +149,13 @@ const (
 const (
 	AcceptLanguage  = "Accept-Language"
 	LanguageChinese = "zh"
+	HeaderCaller    = "X-Caller"
+	HeaderIfMatch   = "If-Match"
+)
+
+// Response headers related
+const (
+	HeaderNextCursor = "X-Next-Cursor"
+	HeaderETag       = "ETag"
+	HeaderWarning    = "Warning"
 )
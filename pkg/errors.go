@@ -42,6 +42,8 @@ var (
 	ErrFmtRetrieving       = "failed to retrieve job(s) %s from job repo"
 	ErrFmtJobDelete        = "failed to delete job for id %s"
 	ErrFmtRedisWatchFailed = "failed to watch redis for job id %s"
+	ErrJobVersionMismatch  = fmt.Errorf("job version does not match the expected version")
+	ErrJobUpdateConflict   = fmt.Errorf("job was modified concurrently, retry the update")
 
 	// file related errors
 	ErrFileTransmitting      = fmt.Errorf("failed to transmit file")
@@ -64,6 +66,10 @@ var (
 	ErrFmtJobDetails     = "(%s): %s"
 	ErrJobNoMatchingTask = fmt.Errorf("no tasks could be matched to the input file name")
 	ErrPipelineFailed    = fmt.Errorf("an error occurred in the processing pipeline")
+	ErrNextTaskInvalid   = fmt.Errorf("the follow-up task of the pipeline could not be launched")
+	ErrPipelineTimedOut  = fmt.Errorf("the processing pipeline did not report the job status in time")
+	ErrPipelineLaunch    = fmt.Errorf("the processing pipeline could not be launched")
+	ErrTaskTemplate      = fmt.Errorf("the templates of the task could not be rendered for the job")
 
 	// miscellaneous errors
 	ErrTranslating = fmt.Errorf("error translating field")
@@ -123,6 +129,10 @@ var translationErrorMap = map[string]string{
 
 	"ErrJobNoMatchingTask": ErrJobNoMatchingTask.Error(),
 	"ErrPipelineFailed":    ErrPipelineFailed.Error(),
+	"ErrNextTaskInvalid":   ErrNextTaskInvalid.Error(),
+	"ErrPipelineTimedOut":  ErrPipelineTimedOut.Error(),
+	"ErrPipelineLaunch":    ErrPipelineLaunch.Error(),
+	"ErrTaskTemplate":      ErrTaskTemplate.Error(),
 
 	// translation errors
 	"ErrTranslating": ErrTranslating.Error(),
@@ -18,7 +18,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -76,6 +75,31 @@ func GetUrlFromAppSetting(service interfaces.ApplicationService, key string, pro
 	return keyUrl, nil
 }
 
+// GetPersistenceFromAppSetting parses the PersistenceType and PersistenceFile Application Settings.
+// An empty PersistenceType selects redis, and a PersistenceFile is required for the bolt persistence.
+func GetPersistenceFromAppSetting(service interfaces.ApplicationService) (string, string, error) {
+	persistenceType, err := GetAppSetting(service, "PersistenceType", true)
+	if err != nil {
+		return "", "", err
+	}
+	persistenceType = strings.ToLower(strings.TrimSpace(persistenceType))
+	switch persistenceType {
+	case "":
+		return pkg.PersistenceTypeRedis, "", nil
+	case pkg.PersistenceTypeRedis:
+		return persistenceType, "", nil
+	case pkg.PersistenceTypeBolt:
+		persistenceFile, err := GetAppSetting(service, "PersistenceFile", false)
+		if err != nil {
+			return "", "", err
+		}
+		return persistenceType, persistenceFile, nil
+	default:
+		return "", "", fmt.Errorf(pkg.ErrFmtInvalidInput, persistenceType,
+			fmt.Sprintf("persistence type of %s or %s", pkg.PersistenceTypeRedis, pkg.PersistenceTypeBolt))
+	}
+}
+
 // UnmarshalTask reads the httprequest, and tries to unmarshal the request into a task.
 // It returns the task, the http status and the error if one occurred
 func UnmarshalTask(request *http.Request) (types.Task, int, error) {
@@ -189,6 +213,19 @@ func IsNetworkError(err error) bool {
 
 // UpdateJobFields is a job specific helper to update the job repository with job changes
 func UpdateJobFields(jobRepoClient job_repo.Client, jobId, owner, status, pipelineStatus string, errDetails *pkg.UserFacingError, inputArchiveName string, inputViewableName string, outputFiles []types.OutputFile) (types.Job, error) {
+	//TODO: add retry logic here?
+	return jobRepoClient.Update(jobId, jobFieldsOf(owner, status, pipelineStatus, errDetails, inputArchiveName, inputViewableName, outputFiles))
+}
+
+// UpdateJobFieldsVersion is UpdateJobFields for changes made to a copy of the job, such as its output files, that are
+// only applied if the job is still at the version of the copy. It returns pkg.ErrJobVersionMismatch if the job has
+// changed since.
+func UpdateJobFieldsVersion(jobRepoClient job_repo.Client, jobId string, version int64, owner, status, pipelineStatus string, errDetails *pkg.UserFacingError, inputArchiveName string, inputViewableName string, outputFiles []types.OutputFile) (types.Job, error) {
+	return jobRepoClient.UpdateVersion(jobId, jobFieldsOf(owner, status, pipelineStatus, errDetails, inputArchiveName, inputViewableName, outputFiles), version)
+}
+
+// jobFieldsOf returns the job fields of UpdateJobFields for the values that are set
+func jobFieldsOf(owner, status, pipelineStatus string, errDetails *pkg.UserFacingError, inputArchiveName string, inputViewableName string, outputFiles []types.OutputFile) map[string]interface{} {
 	jobFields := make(map[string]interface{})
 	if owner != "" {
 		jobFields[types.JobOwner] = owner
@@ -212,14 +249,20 @@ func UpdateJobFields(jobRepoClient job_repo.Client, jobId, owner, status, pipeli
 	if outputFiles != nil {
 		jobFields[types.JobPipelineOutputFiles] = outputFiles
 	}
-
-	//TODO: add retry logic here?
-	return jobRepoClient.Update(jobId, jobFields)
+	return jobFields
 }
 
 // PublishEventForPipeline publishes an event containing the job information to the EdgeX Message Bus.
 // It uses the information in the task passed in to determine the publish topic and fill in the callback urls.
+// The error returned when the templates of the task can not be rendered for the job has the context pkg.ErrTaskTemplate.
 func PublishEventForPipeline(publisher interfaces.BackgroundPublisher, service interfaces.ApplicationService, lc logger.LoggingClient, job types.Job, matchedTask *types.Task, jobUpdateBaseUrl string, pipelineStatusBaseUrl string, deviceProfileName string, deviceName string, resourceName string) error {
+	// the result folder and model parameters of the task may be templates of the job
+	renderedTask, err := matchedTask.RenderTemplates(job, time.Now().UTC())
+	if err != nil {
+		return werrors.WrapErr(werrors.WrapMsgf(err, "task %s, job id %s", matchedTask.Id, job.Id), pkg.ErrTaskTemplate)
+	}
+	matchedTask = &renderedTask
+
 	var resultFolder string
 	// create struct to add PipelineParams: JobId, TaskId, InputFileLocation, OutputFileFolder, ModelParameters
 	if matchedTask.ResultFileFolder == "" {
@@ -227,14 +270,15 @@ func PublishEventForPipeline(publisher interfaces.BackgroundPublisher, service i
 	} else {
 		resultFolder = matchedTask.ResultFileFolder
 	}
-	pipelineParams := struct {
-		InputFileLocation string
-		OutputFileFolder  string
-		ModelParams       map[string]string
-		JobUpdateUrl      string
-		PipelineStatusUrl string
-	}{
-		InputFileLocation: filepath.Join(job.InputFile.DirName, job.InputFile.Name),
+	// a follow-up task of a chain processes the output files of the previous stage instead of the input file
+	inputFiles := job.PipelineInputFiles()
+	var inputFileLocation string
+	if len(inputFiles) > 0 {
+		inputFileLocation = inputFiles[0]
+	}
+	pipelineParams := types.PipelineParameters{
+		InputFileLocation: inputFileLocation,
+		InputFiles:        inputFiles,
 		OutputFileFolder:  resultFolder,
 		ModelParams:       matchedTask.ModelParameters,
 		JobUpdateUrl:      fmt.Sprintf("%s%s", jobUpdateBaseUrl, pkg.EndpointJobPipeline),
@@ -0,0 +1,31 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+// Dispatch is a job launched on the pipeline of a task whose number of jobs in flight is limited,
+// either waiting in the dispatch queue for a free slot of the pipeline or in flight on the pipeline
+type Dispatch struct {
+	// JobId is the id of the job
+	JobId string
+	// TaskId is the id of the task whose pipeline the job is launched on
+	TaskId string
+	// PipelineId is the pipeline the job is launched on
+	PipelineId string
+	// Queued is the time the job was queued in ns from UTC, the queued jobs of a pipeline are released in this order
+	Queued int64
+	// InFlight is true once the job was published to the pipeline
+	InFlight bool
+}
+
+// Key returns the key a dispatch is stored under, a job is dispatched once for each task
+func (d *Dispatch) Key() string {
+	return DispatchKey(d.JobId, d.TaskId)
+}
+
+// DispatchKey returns the key of the dispatch of a job to the pipeline of a task
+func DispatchKey(jobId string, taskId string) string {
+	return jobId + "/" + taskId
+}
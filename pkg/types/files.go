@@ -69,6 +69,20 @@ func (j *Job) FullInputFileLocation() string {
 	return fmt.Sprintf("%s:%s", j.InputFile.Hostname, filepath.Join(j.InputFile.DirName, j.InputFile.Name))
 }
 
+// PipelineInputFiles returns the paths of the files processed by the pipeline of the current stage of the job,
+// the input file of the job or, for a follow-up task, the output files of the previous stage.
+func (j *Job) PipelineInputFiles() []string {
+	if len(j.PipelineStages) == 0 {
+		return []string{filepath.Join(j.InputFile.DirName, j.InputFile.Name)}
+	}
+	previous := j.PipelineStages[len(j.PipelineStages)-1]
+	inputFiles := make([]string, len(previous.OutputFiles))
+	for k, file := range previous.OutputFiles {
+		inputFiles[k] = filepath.Join(file.DirName, file.Name)
+	}
+	return inputFiles
+}
+
 // FullOutputFileLocation is a function that will return a string containing a list of all the files listed in the
 // job.PipelineDetails.OutputFiles field.
 func (j *Job) FullOutputFileLocation() string {
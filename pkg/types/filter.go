@@ -0,0 +1,56 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+// JobFilter holds the criteria used to query a subset of the jobs.
+// Empty string values, zero times and a nil attribute map are not applied.
+// Since and Until are compared against Job.LastUpdated and are inclusive.
+type JobFilter struct {
+	Status     string
+	Owner      string
+	TaskId     string
+	Since      int64
+	Until      int64
+	Attributes map[string]string
+	Limit      int
+	Cursor     string
+}
+
+// Matches checks the job against every criteria set in the filter, ignoring the paging values Limit and Cursor
+func (f JobFilter) Matches(job Job) bool {
+	if f.Status != "" && job.Status != f.Status {
+		return false
+	}
+	if f.Owner != "" && job.Owner != f.Owner {
+		return false
+	}
+	if f.TaskId != "" && !containsString(job.TaskIds(), f.TaskId) {
+		return false
+	}
+	if f.Since != 0 && job.LastUpdated < f.Since {
+		return false
+	}
+	if f.Until != 0 && job.LastUpdated > f.Until {
+		return false
+	}
+	for name, value := range f.Attributes {
+		actual, ok := job.InputFile.Attributes[name]
+		if !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString reports whether the value is in the list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
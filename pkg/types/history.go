@@ -0,0 +1,22 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+// JobHistoryEntry records a single update made to a job
+type JobHistoryEntry struct {
+	Timestamp int64
+	Caller    string
+	OldOwner  string
+	NewOwner  string
+	Changes   []FieldChange
+}
+
+// FieldChange records the value of a job field path, e.g. PipelineDetails.Status, before and after an update
+type FieldChange struct {
+	Path     string
+	OldValue interface{}
+	NewValue interface{}
+}
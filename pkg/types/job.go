@@ -22,6 +22,8 @@ const (
 	JobInputArchiveName     = "InputFile.ArchiveName"
 	JobInputViewableName    = "InputFile.Viewable"
 	JobPipelineTaskId       = "PipelineDetails.TaskId"
+	JobPipelineTaskVersion  = "PipelineDetails.TaskVersion"
+	JobPipelineAttempts     = "PipelineDetails.Attempts"
 	JobPipelineStatus       = "PipelineDetails.Status"
 	JobPipelineQCFlags      = "PipelineDetails.QCFlags"
 	JobPipelineOutputHost   = "PipelineDetails.OutputFileHost"
@@ -29,6 +31,11 @@ const (
 	JobPipelineResults      = "PipelineDetails.Results"
 	JobErrorDetailsOwner    = "ErrorDetails.Owner"
 	JobErrorDetailsErrorMsg = "ErrorDetails.Error"
+	JobVerification         = "Verification"
+	JobReview               = "Review"
+	JobPipelineRuns         = "PipelineRuns"
+	JobPipelineDetails      = "PipelineDetails"
+	JobPipelineStages       = "PipelineStages"
 )
 
 // TODO: add json marshalling attributes
@@ -41,6 +48,12 @@ type Job struct {
 	InputFile FileInfo
 	// PipelineDetails contains the information pertaining to the task run for this job
 	PipelineDetails PipelineInfo
+	// PipelineRuns contains the information pertaining to each task run for this job when it is fanned out
+	// to all the matching tasks, PipelineDetails then summarizes the runs once they all reported their status
+	PipelineRuns []PipelineInfo
+	// PipelineStages contains the information pertaining to the completed stages of a chain of tasks, oldest first,
+	// PipelineDetails then holds the stage being run by the follow-up task
+	PipelineStages []PipelineInfo
 	// LastUpdated is the update time in ns from UTC
 	LastUpdated int64
 	// Status is the current status of job
@@ -50,11 +63,39 @@ type Job struct {
 	// Verification contains the state of manual review in the form of an enum
 	// 0 = Pending; 1 = Accepted; 2 = Rejected
 	Verification int
+	// Review is the latest manual review of the job, nil while the verification is pending
+	Review *Review
+	// Version is set to 1 on creation and incremented on every update
+	Version int64
+	// Transitions records when the job entered each owner and status, oldest first
+	Transitions []Transition
+}
+
+// Transition is a change of the job owner or status
+type Transition struct {
+	// Owner is the owner of the job from the time of the transition
+	Owner string
+	// Status is the status of the job from the time of the transition
+	Status string
+	// Timestamp is the time of the transition in ns from UTC, the LastUpdated value of the change
+	Timestamp int64
+}
+
+// Review is the sign-off record of a manual review of the job results
+type Review struct {
+	// Reviewer is the name of the person that reviewed the job
+	Reviewer string
+	// Reason is the explanation of the verification, required for a rejection
+	Reason string
+	// Timestamp is the time of the review in ns from UTC
+	Timestamp int64
 }
 
 type PipelineInfo struct {
 	// TaskId is the unique identifier for the task that matched resulting in the pipeline being launched
 	TaskId string
+	// TaskVersion is the version of the task the pipeline was launched with
+	TaskVersion int64
 	// Status is the status of the pipeline running the task
 	Status string
 	// QCFlags are the flags set by the pipeline
@@ -65,12 +106,71 @@ type PipelineInfo struct {
 	OutputFiles []OutputFile
 	// Results is the string of the output from the model with or without an image file
 	Results string
+	// Attempts is the number of times the job was published again to the pipeline after it got stuck processing
+	Attempts int
 }
 
 func (j *Job) SetLastUpdated() {
 	j.LastUpdated = time.Now().UTC().UnixNano()
 }
 
+// RecordTransition appends a transition at the LastUpdated time if the owner or status
+// differs from the last recorded transition
+func (j *Job) RecordTransition() {
+	if len(j.Transitions) > 0 {
+		last := j.Transitions[len(j.Transitions)-1]
+		if last.Owner == j.Owner && last.Status == j.Status {
+			return
+		}
+	}
+	j.Transitions = append(j.Transitions, Transition{Owner: j.Owner, Status: j.Status, Timestamp: j.LastUpdated})
+}
+
+// TaskIds returns the ids of the tasks run for the job, including those of a fanned out job
+// and of the earlier stages of a chain of tasks
+func (j *Job) TaskIds() []string {
+	var taskIds []string
+	if j.PipelineDetails.TaskId != "" {
+		taskIds = append(taskIds, j.PipelineDetails.TaskId)
+	}
+	for _, run := range j.PipelineRuns {
+		taskIds = append(taskIds, run.TaskId)
+	}
+	for _, stage := range j.PipelineStages {
+		taskIds = append(taskIds, stage.TaskId)
+	}
+	return taskIds
+}
+
+// PipelineRunIndex returns the index of the pipeline run of the fanned out job for the task id, or -1 if there is none
+func (j *Job) PipelineRunIndex(taskId string) int {
+	for k, run := range j.PipelineRuns {
+		if run.TaskId == taskId {
+			return k
+		}
+	}
+	return -1
+}
+
+// TaskVersion returns the version of the task recorded when the job was launched on it, from the pipeline details,
+// the pipeline runs or the pipeline stages, or 0 if there is none
+func (j *Job) TaskVersion(taskId string) int64 {
+	if j.PipelineDetails.TaskId == taskId {
+		return j.PipelineDetails.TaskVersion
+	}
+	for _, run := range j.PipelineRuns {
+		if run.TaskId == taskId {
+			return run.TaskVersion
+		}
+	}
+	for _, stage := range j.PipelineStages {
+		if stage.TaskId == taskId {
+			return stage.TaskVersion
+		}
+	}
+	return 0
+}
+
 // ValidateHost checks that the job hostname is valid,
 // and updates ErrorDetails if otherwise.
 func (j *Job) ValidateHost(thisHostname string) error {
@@ -118,6 +218,26 @@ func (j *Job) Translate(bundle *i18n.Bundle, accept string) error {
 		j.PipelineDetails.Status = pipelineStatus
 	}
 
+	for k := range j.PipelineRuns {
+		if j.PipelineRuns[k].Status != "" {
+			runStatus, err := translation.TranslateField(loc, j.PipelineRuns[k].Status)
+			if err != nil {
+				return fmt.Errorf("error translating job.PipelineRuns[%d].Status field with value: %s", k, j.PipelineRuns[k].Status)
+			}
+			j.PipelineRuns[k].Status = runStatus
+		}
+	}
+
+	for k := range j.PipelineStages {
+		if j.PipelineStages[k].Status != "" {
+			stageStatus, err := translation.TranslateField(loc, j.PipelineStages[k].Status)
+			if err != nil {
+				return fmt.Errorf("error translating job.PipelineStages[%d].Status field with value: %s", k, j.PipelineStages[k].Status)
+			}
+			j.PipelineStages[k].Status = stageStatus
+		}
+	}
+
 	for k := range j.PipelineDetails.OutputFiles {
 		if j.PipelineDetails.OutputFiles[k].Status != "" {
 			outputFileStatus, err := translation.TranslateField(loc, j.PipelineDetails.OutputFiles[k].Status)
@@ -0,0 +1,20 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+// Pipeline is a pipeline advertised by a pipeline service
+type Pipeline struct {
+	// Id is the unique identifier of the pipeline
+	Id string `json:"id"`
+	// Name is the display name of the pipeline
+	Name string `json:"name"`
+	// Description describes what the pipeline does
+	Description string `json:"description"`
+	// SubscriptionTopic is the topic the pipeline subscribes to, the PipelineId of the tasks launching it
+	SubscriptionTopic string `json:"subscriptionTopic"`
+	// Status is the current status of the pipeline
+	Status string `json:"status"`
+}
@@ -9,6 +9,8 @@ package types
 type PipelineParameters struct {
 	// InputFileLocation is the path to the unprocessed file
 	InputFileLocation string
+	// InputFiles are the paths to all the files to process, the output files of the previous stage for a follow-up task
+	InputFiles []string
 	// OutputFileFolder is the path to inference result file (CSV or Image files)
 	OutputFileFolder string
 	// ModelParams are parameters specific to a pipeline and applied as data is launched in pipeline execution
@@ -0,0 +1,98 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ResultsEnvelopeVersion is the version of the ResultsEnvelope format, incremented on every change that is not
+// backwards compatible so that the consumers can tell the formats apart
+const ResultsEnvelopeVersion = 1
+
+// ResultsEnvelope is the message the task launcher publishes with the results of the pipeline of a task for a job
+type ResultsEnvelope struct {
+	// Version is the ResultsEnvelopeVersion of the message
+	Version int
+	// JobId is the id of the job
+	JobId string
+	// TaskId is the id of the task whose pipeline reported the results
+	TaskId string
+	// TaskVersion is the version of the task the pipeline was launched with
+	TaskVersion int64
+	// PipelineId is the pipeline of the task
+	PipelineId string
+	// InputFile is the input file of the job, including its attributes
+	InputFile FileInfo
+	// Status is the status reported by the pipeline
+	Status string
+	// QCFlags are the flags set by the pipeline
+	QCFlags string
+	// Results is the results string reported by the pipeline
+	Results string
+	// ParsedResults are the results decoded from the results string, see ParseResults
+	ParsedResults interface{} `json:",omitempty"`
+	// OutputFileHost is the hostname associated with the output files
+	OutputFileHost string
+	// OutputFiles are the names of the output files of the pipeline
+	OutputFiles []string
+	// Timestamp is the time the results were published in ns from UTC
+	Timestamp int64
+}
+
+// NewResultsEnvelope creates the message with the results reported in the pipeline details of the job,
+// for the task with the given pipeline
+func NewResultsEnvelope(job Job, pipelineId string, timestamp int64) ResultsEnvelope {
+	outputFiles := make([]string, len(job.PipelineDetails.OutputFiles))
+	for k, file := range job.PipelineDetails.OutputFiles {
+		outputFiles[k] = file.Name
+	}
+	return ResultsEnvelope{
+		Version:        ResultsEnvelopeVersion,
+		JobId:          job.Id,
+		TaskId:         job.PipelineDetails.TaskId,
+		TaskVersion:    job.PipelineDetails.TaskVersion,
+		PipelineId:     pipelineId,
+		InputFile:      job.InputFile,
+		Status:         job.PipelineDetails.Status,
+		QCFlags:        job.PipelineDetails.QCFlags,
+		Results:        job.PipelineDetails.Results,
+		ParsedResults:  ParseResults(job.PipelineDetails.Results),
+		OutputFileHost: job.PipelineDetails.OutputFileHost,
+		OutputFiles:    outputFiles,
+		Timestamp:      timestamp,
+	}
+}
+
+// ParseResults decodes the results string reported by a pipeline. Results that are a JSON object or array are
+// decoded as is, while results made of name and value pairs such as "CellCount,25" or "count,3;area,12.5",
+// separated by semicolons or new lines, are decoded into a map of the values by name.
+// It returns nil for the results in any other format.
+func ParseResults(results string) interface{} {
+	results = strings.TrimSpace(results)
+	if results == "" {
+		return nil
+	}
+	if strings.HasPrefix(results, "{") || strings.HasPrefix(results, "[") {
+		var parsed interface{}
+		if json.Unmarshal([]byte(results), &parsed) == nil {
+			return parsed
+		}
+		return nil
+	}
+
+	parsed := make(map[string]string)
+	for _, pair := range strings.FieldsFunc(results, func(r rune) bool { return r == ';' || r == '\n' }) {
+		name, value, found := strings.Cut(pair, ",")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil
+		}
+		parsed[name] = strings.TrimSpace(value)
+	}
+	return parsed
+}
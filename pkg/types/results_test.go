@@ -0,0 +1,66 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Results  string
+		Expected interface{}
+	}{
+		{"empty", "", nil},
+		{"single pair", "CellCount, 598", map[string]string{"CellCount": "598"}},
+		{"pairs", "count,3;area,12.5\nlabel,cell,round", map[string]string{"count": "3", "area": "12.5", "label": "cell,round"}},
+		{"json object", `{"count": 3, "labels": ["a", "b"]}`, map[string]interface{}{"count": float64(3), "labels": []interface{}{"a", "b"}}},
+		{"json array", `[1, 2]`, []interface{}{float64(1), float64(2)}},
+		{"invalid json", `{"count": 3`, nil},
+		{"free text", "three cells found", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, ParseResults(test.Results))
+		})
+	}
+}
+
+func TestNewResultsEnvelope(t *testing.T) {
+	job := Job{
+		Id:        "1",
+		InputFile: FileInfo{Hostname: "gateway", DirName: "/tmp/files/input", Name: "plate12.tiff", Attributes: map[string]string{"LabName": "lab-a"}},
+		PipelineDetails: PipelineInfo{
+			TaskId:         "count",
+			TaskVersion:    2,
+			Status:         "PipelineComplete",
+			QCFlags:        "None",
+			OutputFileHost: "gateway",
+			OutputFiles:    []OutputFile{{DirName: "/tmp/files/output", Name: "plate12-out.tiff"}},
+			Results:        "CellCount,25",
+		},
+	}
+
+	assert.Equal(t, ResultsEnvelope{
+		Version:        ResultsEnvelopeVersion,
+		JobId:          "1",
+		TaskId:         "count",
+		TaskVersion:    2,
+		PipelineId:     "only-file",
+		InputFile:      job.InputFile,
+		Status:         "PipelineComplete",
+		QCFlags:        "None",
+		Results:        "CellCount,25",
+		ParsedResults:  map[string]string{"CellCount": "25"},
+		OutputFileHost: "gateway",
+		OutputFiles:    []string{"plate12-out.tiff"},
+		Timestamp:      10,
+	}, NewResultsEnvelope(job, "only-file", 10))
+}
@@ -0,0 +1,41 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+// JobStats summarizes the jobs last updated within the time window from Since to Until
+type JobStats struct {
+	// Since is the start of the window in ns from UTC
+	Since int64
+	// Until is the end of the window in ns from UTC
+	Until int64
+	// Total is the number of jobs updated within the window
+	Total int
+	// StatusCounts is the number of jobs by current status
+	StatusCounts map[string]int
+	// OwnerCounts is the number of jobs by current owner
+	OwnerCounts map[string]int
+	// Throughput is the number of jobs completed in each hour of the window, oldest first
+	Throughput []HourlyThroughput
+	// StageDurations are the durations of the owner stages that ended within the window, by owner
+	StageDurations map[string]StageDuration
+}
+
+// HourlyThroughput is the number of jobs that reached the Complete status within an hour
+type HourlyThroughput struct {
+	// Hour is the start of the hour in ns from UTC
+	Hour      int64
+	Completed int
+}
+
+// StageDuration is the distribution of the time jobs spent with an owner
+type StageDuration struct {
+	// Count is the number of stages measured
+	Count int
+	// P50 is the median time spent in ns
+	P50 int64
+	// P95 is the 95th percentile of the time spent in ns
+	P95 int64
+}
@@ -5,7 +5,10 @@
 
 package types
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // Task Object Attributes
 type Task struct {
@@ -17,15 +20,54 @@ type Task struct {
 	JobSelector string
 	// PipelineId is the unique identifier for each pipeline
 	PipelineId string
-	// ResultFileFolder is the path to inference result file (CSV or Image files)
+	// ResultFileFolder is the path to inference result file (CSV or Image files).
+	// It may be a template rendered for each job, see RenderTemplates.
 	ResultFileFolder string
-	// ModelParameters are parameters specific to a pipeline and applied as data is launched in pipeline execution
+	// ModelParameters are parameters specific to a pipeline and applied as data is launched in pipeline execution.
+	// The values may be templates rendered for each job, see RenderTemplates.
 	ModelParameters map[string]string
+	// NextTaskId is the id of an optional follow-up task that is launched with the output files of the pipeline of
+	// this task as its input, chaining the tasks into a multi-stage workflow
+	NextTaskId string
+	// PipelineTimeout is how long the pipeline may process a job, e.g. "30m", before the job is published again.
+	// The default timeout of the task launcher is used when it is not set.
+	PipelineTimeout string
+	// ResultsTopic is the topic the results of the pipeline are published to on the EdgeX Message Bus.
+	// The default topic of the task launcher is used when it is not set.
+	ResultsTopic string
+	// Priority orders the matching of the tasks, tasks with a higher priority are matched first
+	Priority int
+	// Enabled can be set to false to stop matching jobs to the task, the task is enabled when it is not set
+	Enabled *bool
+	// Created is the creation time in ns from UTC, tasks with the same priority are matched in creation order
+	Created int64
+	// Version is the version of the task, starting at 1 when the task is created and incremented by every update
+	// or rollback. Each version is kept in the task history.
+	Version int64
 	// LastUpdated is the update time in ns from UTC
 	LastUpdated int64
+	// ClearFields lists the optional fields an update resets to their default, e.g. NextTaskId to unchain the task,
+	// as the empty fields of an update leave the task unchanged. It is only used by updates and is not stored.
+	ClearFields []string `json:",omitempty"`
 }
 
-// ReplaceTask will replace the values from the existing task object from redisdb with the incoming task object
+// ClearableTaskFields are the optional fields of a task that an update can list in its ClearFields
+var ClearableTaskFields = []string{
+	"ResultFileFolder", "ModelParameters", "NextTaskId", "PipelineTimeout", "ResultsTopic", "Priority", "Enabled",
+}
+
+// IsClearableTaskField reports whether an update can clear the task field
+func IsClearableTaskField(field string) bool {
+	for _, clearable := range ClearableTaskFields {
+		if field == clearable {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceTask will replace the values from the existing task object from redisdb with the non-empty values of the
+// incoming task object, and then reset the fields listed in its ClearFields
 func (t *Task) ReplaceTask(task Task) {
 	if task.Id != "" {
 		t.Id = task.Id
@@ -42,14 +84,83 @@ func (t *Task) ReplaceTask(task Task) {
 	if task.ResultFileFolder != "" {
 		t.ResultFileFolder = task.ResultFileFolder
 	}
+	if task.NextTaskId != "" {
+		t.NextTaskId = task.NextTaskId
+	}
+	if task.PipelineTimeout != "" {
+		t.PipelineTimeout = task.PipelineTimeout
+	}
+	if task.ResultsTopic != "" {
+		t.ResultsTopic = task.ResultsTopic
+	}
+	if task.Priority != 0 {
+		t.Priority = task.Priority
+	}
+	if task.Enabled != nil {
+		t.Enabled = task.Enabled
+	}
 	if len(task.ModelParameters) != 0 {
+		// the parameters are copied so that the previous version of the task keeps its own
+		parameters := make(map[string]string, len(t.ModelParameters)+len(task.ModelParameters))
+		for id, val := range t.ModelParameters {
+			parameters[id] = val
+		}
 		for id, val := range task.ModelParameters {
-			t.ModelParameters[id] = val
+			parameters[id] = val
 		}
+		t.ModelParameters = parameters
 	}
+	for _, field := range task.ClearFields {
+		switch field {
+		case "ResultFileFolder":
+			t.ResultFileFolder = ""
+		case "ModelParameters":
+			t.ModelParameters = nil
+		case "NextTaskId":
+			t.NextTaskId = ""
+		case "PipelineTimeout":
+			t.PipelineTimeout = ""
+		case "ResultsTopic":
+			t.ResultsTopic = ""
+		case "Priority":
+			t.Priority = 0
+		case "Enabled":
+			t.Enabled = nil
+		}
+	}
+	t.ClearFields = nil
+	t.SetLastUpdated()
+}
+
+// RestoreVersion replaces the definition of the task with that of a previous version of the task,
+// keeping its id and creation time, and makes it the next version of the task
+func (t *Task) RestoreVersion(previous Task) {
+	previous.Id = t.Id
+	previous.Created = t.Created
+	previous.Version = t.Version + 1
+	*t = previous
 	t.SetLastUpdated()
 }
 
 func (t *Task) SetLastUpdated() {
 	t.LastUpdated = time.Now().UTC().UnixNano()
 }
+
+// IsEnabled reports whether jobs can be matched to the task
+func (t *Task) IsEnabled() bool {
+	return t.Enabled == nil || *t.Enabled
+}
+
+// SortTasks orders the tasks for matching, by descending priority, then by creation time and then by id
+// so that the order does not depend on how the tasks are stored
+func SortTasks(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+		if tasks[i].Created != tasks[j].Created {
+			return tasks[i].Created < tasks[j].Created
+		}
+		return tasks[i].Id < tasks[j].Id
+	})
+}
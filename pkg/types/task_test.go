@@ -0,0 +1,82 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortTasks(t *testing.T) {
+	tasks := []Task{
+		{Id: "b", Created: 2},
+		{Id: "low", Priority: -1, Created: 1},
+		{Id: "a", Created: 2},
+		{Id: "first", Created: 1},
+		{Id: "high", Priority: 10, Created: 3},
+	}
+
+	SortTasks(tasks)
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	assert.Equal(t, []string{"high", "first", "a", "b", "low"}, ids)
+}
+
+func TestTask_IsEnabled(t *testing.T) {
+	enabled := true
+	disabled := false
+	assert.True(t, (&Task{}).IsEnabled())
+	assert.True(t, (&Task{Enabled: &enabled}).IsEnabled())
+	assert.False(t, (&Task{Enabled: &disabled}).IsEnabled())
+}
+
+func TestTask_ReplaceTask(t *testing.T) {
+	task := Task{Id: "1", Description: "Count Cells", PipelineId: "100", ModelParameters: map[string]string{"Brightness": "0"}}
+	previous := task
+
+	task.ReplaceTask(Task{PipelineId: "200", ModelParameters: map[string]string{"Gamma": "255"}})
+
+	assert.Equal(t, "Count Cells", task.Description)
+	assert.Equal(t, "200", task.PipelineId)
+	assert.Equal(t, map[string]string{"Brightness": "0", "Gamma": "255"}, task.ModelParameters)
+	// the previous version is not changed by the update
+	assert.Equal(t, map[string]string{"Brightness": "0"}, previous.ModelParameters)
+}
+
+func TestTask_ReplaceTaskClearFields(t *testing.T) {
+	disabled := false
+	task := Task{Id: "1", Description: "Count Cells", PipelineId: "100", NextTaskId: "2", PipelineTimeout: "30m",
+		ResultsTopic: "lab-a/results", Priority: 5, Enabled: &disabled}
+
+	// unchain the task, the description is changed in the same update
+	task.ReplaceTask(Task{Description: "Count Nuclei", ClearFields: []string{"NextTaskId", "PipelineTimeout", "ResultsTopic", "Priority"}})
+
+	assert.Equal(t, "Count Nuclei", task.Description)
+	assert.Equal(t, "100", task.PipelineId)
+	assert.Empty(t, task.NextTaskId)
+	assert.Empty(t, task.PipelineTimeout)
+	assert.Empty(t, task.ResultsTopic)
+	assert.Zero(t, task.Priority)
+	assert.False(t, task.IsEnabled(), "field not cleared is kept")
+	assert.Nil(t, task.ClearFields, "clear fields are not stored")
+}
+
+func TestTask_RestoreVersion(t *testing.T) {
+	task := Task{Id: "1", Description: "Count Nuclei", PipelineId: "200", Created: 5, LastUpdated: 10, Version: 3}
+
+	task.RestoreVersion(Task{Id: "1", Description: "Count Cells", PipelineId: "100", Created: 5, LastUpdated: 5, Version: 1})
+
+	assert.Equal(t, "1", task.Id)
+	assert.Equal(t, "Count Cells", task.Description)
+	assert.Equal(t, "100", task.PipelineId)
+	assert.Equal(t, int64(5), task.Created)
+	assert.Equal(t, int64(4), task.Version)
+	assert.Greater(t, task.LastUpdated, int64(10))
+}
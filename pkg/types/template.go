@@ -0,0 +1,93 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the functions the templates of a task may call besides the fields of the job,
+// with the time the job is launched at as the current time
+func templateFuncs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		// now returns the current time, e.g. {{now.Year}}
+		"now": func() time.Time { return now },
+		// date formats the current time with a Go time layout, e.g. {{date "2006-01-02"}}
+		"date": func(layout string) string { return now.Format(layout) },
+	}
+}
+
+// RenderTemplates returns a copy of the task with its ResultFileFolder and ModelParameters values rendered for the job
+// at the given time. They are Go text templates of the job, such as {{.InputFile.Attributes.LabName}},
+// {{.InputFile.Name}} or {{date "2006-01-02"}}. It returns an error when a template references an attribute
+// the input file of the job does not have, or when the rendered ResultFileFolder is outside of its folder.
+func (t *Task) RenderTemplates(job Job, now time.Time) (Task, error) {
+	rendered := *t
+	var err error
+	rendered.ResultFileFolder, err = renderTemplate("ResultFileFolder", t.ResultFileFolder, job, now, "error")
+	if err != nil {
+		return rendered, err
+	}
+	err = validateResultFolder(t.ResultFileFolder, rendered.ResultFileFolder)
+	if err != nil {
+		return rendered, err
+	}
+	if t.ModelParameters != nil {
+		rendered.ModelParameters = make(map[string]string, len(t.ModelParameters))
+		for key, value := range t.ModelParameters {
+			rendered.ModelParameters[key], err = renderTemplate("ModelParameters."+key, value, job, now, "error")
+			if err != nil {
+				return rendered, err
+			}
+		}
+	}
+	return rendered, nil
+}
+
+// ValidateTemplate checks that the value is a template that can be rendered for the sample job.
+// The attributes of the input file are specific to each job, so a missing attribute is not an error.
+func ValidateTemplate(value string, sample Job) error {
+	_, err := renderTemplate("", value, sample, time.Now().UTC(), "zero")
+	return err
+}
+
+// validateResultFolder checks that the rendered result folder stays in the folder written before the first action
+// of the template, so the values of a job can not make the pipeline write its results elsewhere. A template starting
+// with an action must render to a relative folder.
+func validateResultFolder(value string, rendered string) error {
+	actionStart := strings.Index(value, "{{")
+	if actionStart < 0 {
+		return nil
+	}
+	base := filepath.Dir(value[:actionStart])
+	relative, err := filepath.Rel(base, filepath.Clean(rendered))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("the rendered ResultFileFolder %s is outside of the folder %s", rendered, base)
+	}
+	return nil
+}
+
+// renderTemplate renders the value as a template of the job, the missingKey option sets how the missing attributes
+// of the input file are handled. A value without an action is returned unchanged.
+func renderTemplate(name string, value string, job Job, now time.Time, missingKey string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs(now)).Option("missingkey=" + missingKey).Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %s", err.Error())
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, job)
+	if err != nil {
+		return "", fmt.Errorf("could not render template: %s", err.Error())
+	}
+	return rendered.String(), nil
+}
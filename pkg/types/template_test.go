@@ -0,0 +1,75 @@
+/*********************************************************************
+ * Copyright (c) Intel Corporation 2023
+ * SPDX-License-Identifier: BSD-3-Clause
+ **********************************************************************/
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTask_RenderTemplates(t *testing.T) {
+	now := time.Date(2023, time.March, 7, 10, 0, 0, 0, time.UTC)
+	job := Job{
+		Id: "1",
+		InputFile: FileInfo{
+			Name:       "plate12.tiff",
+			Attributes: map[string]string{"LabName": "lab-a", "Threshold": "0.8", "Escape": "../../etc", "Root": "/etc"},
+		},
+	}
+
+	tests := []struct {
+		Name             string
+		ResultFileFolder string
+		ModelParameters  map[string]string
+		ExpectedFolder   string
+		ExpectedParams   map[string]string
+		ExpectedErrorMsg string
+	}{
+		{"no templates", "/tmp/files/output", map[string]string{"Brightness": "0"}, "/tmp/files/output", map[string]string{"Brightness": "0"}, ""},
+		{"attributes and name", "/tmp/files/{{.InputFile.Attributes.LabName}}/{{.InputFile.Name}}",
+			map[string]string{"Threshold": "{{.InputFile.Attributes.Threshold}}"},
+			"/tmp/files/lab-a/plate12.tiff", map[string]string{"Threshold": "0.8"}, ""},
+		{"date", `/tmp/files/{{date "2006-01-02"}}/{{now.Year}}`, nil, "/tmp/files/2023-03-07/2023", nil, ""},
+		{"missing attribute", "/tmp/files/{{.InputFile.Attributes.Plate}}", nil, "", nil, `map has no entry for key "Plate"`},
+		{"missing attribute in parameter", "", map[string]string{"Plate": "{{.InputFile.Attributes.Plate}}"}, "", nil, "ModelParameters.Plate"},
+		{"invalid template", "/tmp/files/{{.InputFile.Name", nil, "", nil, "invalid template"},
+		{"absolute attribute in the folder", "/tmp/files/output/{{.InputFile.Attributes.Root}}", nil, "/tmp/files/output//etc", nil, ""},
+		{"relative folder", "{{.InputFile.Attributes.LabName}}/output", nil, "lab-a/output", nil, ""},
+		{"folder escaped", "/tmp/files/output/{{.InputFile.Attributes.Escape}}", nil, "", nil, "outside of the folder /tmp/files/output"},
+		{"relative folder escaped", "{{.InputFile.Attributes.LabName}}/{{.InputFile.Attributes.Escape}}", nil, "", nil, "outside of the folder ."},
+		{"absolute folder rendered", "{{.InputFile.Attributes.Root}}/output", nil, "", nil, "outside of the folder ."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			task := Task{Id: "task", ResultFileFolder: test.ResultFileFolder, ModelParameters: test.ModelParameters}
+			rendered, err := task.RenderTemplates(job, now)
+			if test.ExpectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.ExpectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedFolder, rendered.ResultFileFolder)
+			assert.Equal(t, test.ExpectedParams, rendered.ModelParameters)
+			// the task itself keeps its templates
+			assert.Equal(t, test.ResultFileFolder, task.ResultFileFolder)
+			assert.Equal(t, test.ModelParameters, task.ModelParameters)
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	sample := Job{InputFile: FileInfo{Name: "sample.tiff", Attributes: map[string]string{}}}
+	assert.NoError(t, ValidateTemplate("/tmp/files/output", sample))
+	assert.NoError(t, ValidateTemplate("/tmp/files/{{.InputFile.Attributes.LabName}}", sample))
+	assert.Error(t, ValidateTemplate("/tmp/files/{{.InputFile.Nmae}}", sample))
+	assert.Error(t, ValidateTemplate("/tmp/files/{{date}}", sample))
+	assert.Error(t, ValidateTemplate("/tmp/files/{{.InputFile.Name", sample))
+}
@@ -23,6 +23,17 @@ const (
 	ServiceRedis        Service = "edgex-redis:6379"
 )
 
+// Without returns the services other than the given service
+func (s Services) Without(service Service) Services {
+	services := Services{}
+	for _, dependency := range s {
+		if dependency != service {
+			services = append(services, dependency)
+		}
+	}
+	return services
+}
+
 // ServiceMaxTimeout is a timer for the service dependency waiting logic
 const (
 	ServiceMaxTimeout = 1 * time.Minute